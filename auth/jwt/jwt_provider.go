@@ -1,9 +1,11 @@
 package jwt
 
 import (
+	"errors"
 	"fmt"
 	"github.com/Lumicrate/gompose/auth"
 	"github.com/Lumicrate/gompose/db"
+	gerrors "github.com/Lumicrate/gompose/errors"
 	"github.com/Lumicrate/gompose/http"
 	"github.com/Lumicrate/gompose/utils"
 	"reflect"
@@ -68,20 +70,21 @@ func (j *JWTAuthProvider) registerHandler(ctx http.Context) {
 	newUser := reflect.New(t).Interface()
 
 	if err := ctx.Bind(newUser); err != nil {
-		ctx.JSON(400, map[string]string{"error": "invalid input: " + err.Error()})
+		gerrors.Write(ctx, gerrors.BadRequest("invalid input: "+err.Error()))
 		return
 	}
 
 	authUser, ok := newUser.(auth.AuthUser)
 	if !ok {
-		ctx.JSON(500, map[string]string{"error": "user model must implement AuthUser"})
+		gerrors.Write(ctx, errors.New("user model must implement AuthUser"))
 		return
 	}
 
 	password := authUser.GetHashedPassword()
 	hashed, err := utils.GenerateFromPassword(password)
 	if err != nil {
-		ctx.JSON(400, map[string]string{"error": "invalid input: " + err.Error()})
+		gerrors.Write(ctx, gerrors.BadRequest("invalid input: "+err.Error()))
+		return
 	}
 
 	reflect.ValueOf(newUser).Elem().FieldByName("Password").SetString(hashed)
@@ -93,8 +96,8 @@ func (j *JWTAuthProvider) registerHandler(ctx http.Context) {
 		}
 	}
 
-	if err := j.DB.Create(newUser); err != nil {
-		ctx.JSON(500, map[string]string{"error": "failed to create user: " + err.Error()})
+	if err := j.DB.WithContext(ctx.Context()).Create(newUser); err != nil {
+		gerrors.Write(ctx, fmt.Errorf("failed to create user: %w", err))
 		return
 	}
 
@@ -108,7 +111,7 @@ func (j *JWTAuthProvider) loginHandler(ctx http.Context) {
 	}{}
 
 	if err := ctx.BindJSON(&payload); err != nil {
-		ctx.JSON(400, map[string]string{"error": "invalid input: " + err.Error()})
+		gerrors.Write(ctx, gerrors.BadRequest("invalid input: "+err.Error()))
 		return
 	}
 
@@ -118,18 +121,18 @@ func (j *JWTAuthProvider) loginHandler(ctx http.Context) {
 	}
 	user := reflect.New(t).Interface()
 
-	foundUsers, err := j.DB.FindAll(user, map[string]any{
-		"email": payload.Email,
+	foundUsers, err := j.DB.WithContext(ctx.Context()).FindAll(user, []db.Filter{
+		db.Eq("email", payload.Email),
 	}, db.Pagination{Limit: 1}, nil)
 
 	if err != nil {
-		ctx.JSON(500, map[string]string{"error": "failed to query user"})
+		gerrors.Write(ctx, fmt.Errorf("failed to query user: %w", err))
 		return
 	}
 
 	usersVal := reflect.ValueOf(foundUsers)
 	if usersVal.Len() == 0 {
-		ctx.JSON(401, map[string]string{"error": "invalid username or password"})
+		gerrors.Write(ctx, gerrors.Unauthorized("invalid username or password"))
 		return
 	}
 
@@ -144,18 +147,19 @@ func (j *JWTAuthProvider) loginHandler(ctx http.Context) {
 	}
 
 	if !ok {
-		ctx.JSON(500, map[string]string{"error": "user model must implement AuthUser"})
+		gerrors.Write(ctx, errors.New("user model must implement AuthUser"))
 		return
 	}
 
 	if err := utils.CompareHashAndPassword(authUser.GetHashedPassword(), payload.Password); err != nil {
-		ctx.JSON(401, map[string]string{"error": "invalid username or password"})
+		gerrors.Write(ctx, gerrors.Unauthorized("invalid username or password"))
 		return
 	}
 
 	token, err := utils.GenerateJWT(authUser.GetID(), j.SecretKey, j.TokenTTL)
 	if err != nil {
-		ctx.JSON(500, map[string]string{"error": "failed to generate token: " + err.Error()})
+		gerrors.Write(ctx, err)
+		return
 	}
 
 	ctx.JSON(200, map[string]string{"token": token})
@@ -166,14 +170,14 @@ func (j *JWTAuthProvider) Middleware() http.MiddlewareFunc {
 		return func(ctx http.Context) {
 			tokenStr, err := utils.ExtractBearerToken(ctx.Header("Authorization"))
 			if err != nil {
-				ctx.JSON(401, map[string]string{"error": err.Error()})
+				gerrors.Write(ctx, gerrors.Unauthorized(err.Error()))
 				ctx.Abort()
 				return
 			}
 
 			claims, err := utils.ValidateJWT(tokenStr, j.SecretKey)
 			if err != nil {
-				ctx.JSON(401, map[string]string{"error": err.Error()})
+				gerrors.Write(ctx, gerrors.Unauthorized(err.Error()))
 				ctx.Abort()
 				return
 			}
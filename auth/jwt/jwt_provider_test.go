@@ -1,6 +1,7 @@
 package jwt
 
 import (
+	"context"
 	"testing"
 
 	Net "net/http"
@@ -18,18 +19,35 @@ type MockDB struct {
 	MigrateErr error
 }
 
-func (m *MockDB) Init() error                        { return nil }
-func (m *MockDB) Migrate(entities []any) error       { return m.MigrateErr }
-func (m *MockDB) Create(entity any) error            { m.Created = append(m.Created, entity); return nil }
-func (m *MockDB) Update(entity any) error            { return nil }
-func (m *MockDB) Delete(id string, entity any) error { return nil }
-func (m *MockDB) FindAll(entity any, filters map[string]any, pagination db.Pagination, sort []db.Sort) (any, error) {
+func (m *MockDB) Init() error                                          { return nil }
+func (m *MockDB) Migrate(entities []any) error                         { return m.MigrateErr }
+func (m *MockDB) Ping() error                                          { return nil }
+func (m *MockDB) Close() error                                         { return nil }
+func (m *MockDB) WithContext(ctx context.Context) db.DBAdapter         { return m }
+func (m *MockDB) WithTransaction(fn func(tx db.DBAdapter) error) error { return fn(m) }
+func (m *MockDB) Create(entity any) error                              { m.Created = append(m.Created, entity); return nil }
+func (m *MockDB) Update(entity any) error                              { return nil }
+func (m *MockDB) IncludeDeleted() db.DBAdapter                         { return m }
+func (m *MockDB) Preload(relations ...string) db.DBAdapter             { return m }
+func (m *MockDB) Select(fields ...string) db.DBAdapter                 { return m }
+func (m *MockDB) Search(query string, fields ...string) db.DBAdapter   { return m }
+func (m *MockDB) Delete(id string, entity any) error                   { return nil }
+func (m *MockDB) Restore(id string, entity any) error                  { return nil }
+func (m *MockDB) Upsert(entity any) (bool, error)                      { return false, nil }
+func (m *MockDB) CreateMany(entities []any) error                      { return nil }
+func (m *MockDB) UpdateMany(entities []any) error                      { return nil }
+func (m *MockDB) DeleteMany(ids []string, entity any) error            { return nil }
+func (m *MockDB) FindAll(entity any, filters []db.Filter, pagination db.Pagination, sort []db.Sort) (any, error) {
 	if m.FindErr != nil {
 		return nil, m.FindErr
 	}
 	return m.FindRes, nil
 }
-func (m *MockDB) FindByID(id string, entity any) (any, error) { return entity, nil }
+func (m *MockDB) FindByID(id string, entity any) (any, error)          { return entity, nil }
+func (m *MockDB) Count(entity any, filters []db.Filter) (int64, error) { return 0, nil }
+func (m *MockDB) Aggregate(entity any, filters []db.Filter, groupBy []string, metrics []db.Metric) ([]db.AggregateRow, error) {
+	return nil, nil
+}
 
 // Mock Context
 type MockContext struct {
@@ -65,6 +83,7 @@ func (m *MockContext) Body(content string)              {}
 func (m *MockContext) Request() *Net.Request            { return nil }
 func (m *MockContext) QueryParams() map[string][]string { return nil }
 func (m *MockContext) BindJSON(obj any) error           { return nil }
+func (m *MockContext) Context() context.Context         { return context.Background() }
 
 // Test AuthUser Model
 type TestUser struct {
@@ -14,18 +14,24 @@ var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Generate a gompose.yaml config file",
 	Run: func(cmd *cobra.Command, args []string) {
+		if !cmd.Flags().Changed("dsn") {
+			if dsn, ok := defaultDSNs[dbFlag]; ok {
+				dsnFlag = dsn
+			}
+		}
+
 		config := fmt.Sprintf(`database:
   driver: %s
   dsn: "%s"
   name: %s
-
+%s
 http:
   engine: %s
   port: %d
 
 auth:
   secret: "%s"
-`, dbFlag, dsnFlag, dbNameFlag, httpFlag, portFlag, secretFlag)
+`, dbFlag, dsnFlag, dbNameFlag, driverOptions[dbFlag], httpFlag, portFlag, secretFlag)
 
 		if err := os.WriteFile("gompose.yaml", []byte(config), 0644); err != nil {
 			fmt.Println("Error creating gompose.yaml:", err)
@@ -36,10 +42,46 @@ auth:
 	},
 }
 
+// defaultDSNs is used when --dsn is not given.
+var defaultDSNs = map[string]string{
+	"postgres": "host=localhost user=username password=password dbname=mydb port=5432 sslmode=disable",
+	"mongodb":  "mongodb://localhost:27017",
+	"sqlite":   "gompose.db",
+	"mysql":    "username:password@tcp(localhost:3306)/mydb?charset=utf8mb4&parseTime=True&loc=Local",
+}
+
+// driverOptions are the tuning settings written for each driver; zero values keep the
+// driver defaults.
+var driverOptions = map[string]string{
+	"postgres": poolOptions + `  statement_timeout: 0s
+  replicas:
+    dsns: []
+    policy: round_robin
+    health_check_interval: 10s
+`,
+	"mysql": poolOptions,
+	"mongodb": `  mongodb:
+    max_pool_size: 0
+    min_pool_size: 0
+    max_conn_idle_time: 0s
+    connect_timeout: 0s
+    server_selection_timeout: 0s
+    read_preference: primary
+`,
+}
+
+const poolOptions = `  pool:
+    max_open_conns: 0
+    max_idle_conns: 0
+    conn_max_lifetime: 0s
+    conn_max_idle_time: 0s
+    log_level: warn
+`
+
 func init() {
-	configCmd.Flags().StringVar(&dbFlag, "db", "postgres", "Database driver (postgres|mongodb)")
+	configCmd.Flags().StringVar(&dbFlag, "db", "postgres", "Database driver (postgres|mongodb|sqlite|mysql)")
 	configCmd.Flags().StringVar(&httpFlag, "http", "gin", "HTTP engine (gin)")
-	configCmd.Flags().StringVar(&dsnFlag, "dsn", "host=localhost user=username password=password dbname=mydb port=5432 sslmode=disable", "Database DSN/URI")
+	configCmd.Flags().StringVar(&dsnFlag, "dsn", defaultDSNs["postgres"], "Database DSN/URI")
 	configCmd.Flags().StringVar(&dbNameFlag, "dbname", "mydb", "Database name (used by MongoDB)")
 	configCmd.Flags().IntVar(&portFlag, "port", 8080, "HTTP port")
 	configCmd.Flags().StringVar(&secretFlag, "secret", "SecretKEY", "Auth secret")
@@ -1,10 +1,16 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"text/template"
+	"time"
 
+	"github.com/Lumicrate/gompose/db/gormadapter"
+	"github.com/Lumicrate/gompose/db/mongodb"
+	"github.com/Lumicrate/gompose/db/postgres"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 )
@@ -14,6 +20,12 @@ type Config struct {
 		Driver string `yaml:"driver"`
 		DSN    string `yaml:"dsn"`
 		Name   string `yaml:"name"`
+
+		// Pool tunes the postgres and mysql connection pools.
+		Pool             gormadapter.Options     `yaml:"pool"`
+		StatementTimeout time.Duration           `yaml:"statement_timeout"`
+		Replicas         postgres.ReplicaOptions `yaml:"replicas"`
+		MongoDB          mongodb.Options         `yaml:"mongodb"`
 	} `yaml:"database"`
 	HTTP struct {
 		Engine string `yaml:"engine"`
@@ -31,15 +43,9 @@ var initCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		projectName := args[0]
 
-		data, err := os.ReadFile("gompose.yaml")
+		cfg, err := readConfig()
 		if err != nil {
-			fmt.Println("gompose.yaml not found. Run 'gompose config' first.")
-			return
-		}
-
-		var cfg Config
-		if err := yaml.Unmarshal(data, &cfg); err != nil {
-			fmt.Println("Failed to parse gompose.yaml:", err)
+			fmt.Println(err)
 			return
 		}
 
@@ -49,30 +55,86 @@ var initCmd = &cobra.Command{
 		}
 
 		// Pick template based on db + http
-		var mainTemplate string
-		if cfg.Database.Driver == "postgres" && cfg.HTTP.Engine == "gin" {
-			mainTemplate = postgresGinTemplate
-		} else if cfg.Database.Driver == "mongodb" && cfg.HTTP.Engine == "gin" {
-			mainTemplate = mongoGinTemplate
-		} else {
+		mainTemplate, ok := templateFor(cfg.Database.Driver, cfg.HTTP.Engine)
+		if !ok {
 			fmt.Println("Unsupported db/http combination")
 			return
 		}
 
-		tmpl, _ := template.New("main").Parse(mainTemplate)
 		f, _ := os.Create(fmt.Sprintf("%s/main.go", projectName))
 		defer f.Close()
-		tmpl.Execute(f, cfg)
+		if err := renderMain(f, mainTemplate, cfg); err != nil {
+			fmt.Println("Error rendering main.go:", err)
+			return
+		}
 
 		fmt.Printf("Project %s initialized with gompose.yaml configs!\n", projectName)
 	},
 }
 
+// readConfig loads gompose.yaml from the working directory.
+func readConfig() (*Config, error) {
+	data, err := os.ReadFile("gompose.yaml")
+	if err != nil {
+		return nil, errors.New("gompose.yaml not found. Run 'gompose config' first.")
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("Failed to parse gompose.yaml: %w", err)
+	}
+	return &cfg, nil
+}
+
+// renderMain executes a main.go template with the configuration.
+func renderMain(w io.Writer, mainTemplate string, cfg *Config) error {
+	tmpl, err := template.New("main").Funcs(template.FuncMap{"duration": goDuration}).Parse(mainTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, cfg)
+}
+
+// goDuration renders d as a Go expression, such as 30 * time.Second.
+func goDuration(d time.Duration) string {
+	for _, unit := range []struct {
+		d    time.Duration
+		name string
+	}{{time.Hour, "Hour"}, {time.Minute, "Minute"}, {time.Second, "Second"}, {time.Millisecond, "Millisecond"}} {
+		if d != 0 && d%unit.d == 0 {
+			return fmt.Sprintf("%d * time.%s", d/unit.d, unit.name)
+		}
+	}
+	return fmt.Sprintf("time.Duration(%d)", d)
+}
+
+// templateFor returns the main.go template for a database driver and HTTP engine.
+func templateFor(driver, engine string) (string, bool) {
+	if engine != "gin" {
+		return "", false
+	}
+
+	switch driver {
+	case "postgres":
+		return postgresGinTemplate, true
+	case "mongodb":
+		return mongoGinTemplate, true
+	case "sqlite":
+		return sqliteGinTemplate, true
+	case "mysql":
+		return mysqlGinTemplate, true
+	}
+	return "", false
+}
+
 // Templates
 const postgresGinTemplate = `package main
 
 import (
+    "time"
+
     "github.com/Lumicrate/gompose/core"
+    "github.com/Lumicrate/gompose/db/gormadapter"
     "github.com/Lumicrate/gompose/db/postgres"
     "github.com/Lumicrate/gompose/http/gin"
     "github.com/Lumicrate/gompose/auth/jwt"
@@ -88,13 +150,105 @@ type User struct {
 
 func main() {
     dsn := "{{.Database.DSN}}"
-    dbAdapter := postgres.New(dsn)
+    dbAdapter := postgres.NewWithOptions(dsn, postgres.Options{
+        Options: gormadapter.Options{
+            MaxOpenConns:    {{.Database.Pool.MaxOpenConns}},
+            MaxIdleConns:    {{.Database.Pool.MaxIdleConns}},
+            ConnMaxLifetime: {{duration .Database.Pool.ConnMaxLifetime}},
+            ConnMaxIdleTime: {{duration .Database.Pool.ConnMaxIdleTime}},
+            LogLevel:        "{{.Database.Pool.LogLevel}}",
+        },
+        StatementTimeout: {{duration .Database.StatementTimeout}},
+        Replicas: postgres.ReplicaOptions{
+            DSNs:                []string{ {{- range $i, $dsn := .Database.Replicas.DSNs}}{{if $i}}, {{end}}"{{$dsn}}"{{end -}} },
+            Policy:              "{{.Database.Replicas.Policy}}",
+            HealthCheckInterval: {{duration .Database.Replicas.HealthCheckInterval}},
+        },
+    })
+    httpEngine := ginadapter.New({{.HTTP.Port}})
+    authProvider := jwt.NewJWTAuthProvider("{{.Auth.Secret}}", dbAdapter)
+
+    app := core.NewApp().
+        AddEntity(User{}, crud.Protect("POST", "PUT", "DELETE")).
+        UseDB(dbAdapter).
+        AutoMigrate().
+        UseHTTP(httpEngine).
+        UseAuth(authProvider)
+
+    app.Run()
+}
+`
+
+const sqliteGinTemplate = `package main
+
+import (
+    "github.com/Lumicrate/gompose/core"
+    "github.com/Lumicrate/gompose/db/sqlite"
+    "github.com/Lumicrate/gompose/http/gin"
+    "github.com/Lumicrate/gompose/auth/jwt"
+    "github.com/Lumicrate/gompose/crud"
+)
+
+// sample entity
+type User struct {
+    ID    int    ` + "`json:\"id\" gorm:\"primaryKey;autoIncrement\"`" + `
+    Name  string ` + "`json:\"name\"`" + `
+    Email string ` + "`json:\"email\"`" + `
+}
+
+func main() {
+    dsn := "{{.Database.DSN}}"
+    dbAdapter := sqlite.New(dsn)
+    httpEngine := ginadapter.New({{.HTTP.Port}})
+    authProvider := jwt.NewJWTAuthProvider("{{.Auth.Secret}}", dbAdapter)
+
+    app := core.NewApp().
+        AddEntity(User{}, crud.Protect("POST", "PUT", "DELETE")).
+        UseDB(dbAdapter).
+        AutoMigrate().
+        UseHTTP(httpEngine).
+        UseAuth(authProvider)
+
+    app.Run()
+}
+`
+
+const mysqlGinTemplate = `package main
+
+import (
+    "time"
+
+    "github.com/Lumicrate/gompose/core"
+    "github.com/Lumicrate/gompose/db/gormadapter"
+    "github.com/Lumicrate/gompose/db/mysql"
+    "github.com/Lumicrate/gompose/http/gin"
+    "github.com/Lumicrate/gompose/auth/jwt"
+    "github.com/Lumicrate/gompose/crud"
+)
+
+// sample entity
+type User struct {
+    ID    int    ` + "`json:\"id\" gorm:\"primaryKey;autoIncrement\"`" + `
+    Name  string ` + "`json:\"name\"`" + `
+    Email string ` + "`json:\"email\"`" + `
+}
+
+func main() {
+    dsn := "{{.Database.DSN}}"
+    dbAdapter := mysql.NewWithOptions(dsn, gormadapter.Options{
+        MaxOpenConns:    {{.Database.Pool.MaxOpenConns}},
+        MaxIdleConns:    {{.Database.Pool.MaxIdleConns}},
+        ConnMaxLifetime: {{duration .Database.Pool.ConnMaxLifetime}},
+        ConnMaxIdleTime: {{duration .Database.Pool.ConnMaxIdleTime}},
+        LogLevel:        "{{.Database.Pool.LogLevel}}",
+    })
     httpEngine := ginadapter.New({{.HTTP.Port}})
     authProvider := jwt.NewJWTAuthProvider("{{.Auth.Secret}}", dbAdapter)
 
     app := core.NewApp().
         AddEntity(User{}, crud.Protect("POST", "PUT", "DELETE")).
         UseDB(dbAdapter).
+        AutoMigrate().
         UseHTTP(httpEngine).
         UseAuth(authProvider)
 
@@ -105,6 +259,8 @@ func main() {
 const mongoGinTemplate = `package main
 
 import (
+    "time"
+
     "github.com/Lumicrate/gompose/core"
     "github.com/Lumicrate/gompose/db/mongodb"
 	"github.com/Lumicrate/gompose/auth/jwt"
@@ -121,7 +277,14 @@ type User struct {
 func main() {
     mongoURI := "{{.Database.DSN}}"
     dbName := "{{.Database.Name}}"
-    dbAdapter := mongodb.New(mongoURI, dbName)
+    dbAdapter := mongodb.NewWithOptions(mongoURI, dbName, mongodb.Options{
+        MaxPoolSize:            {{.Database.MongoDB.MaxPoolSize}},
+        MinPoolSize:            {{.Database.MongoDB.MinPoolSize}},
+        MaxConnIdleTime:        {{duration .Database.MongoDB.MaxConnIdleTime}},
+        ConnectTimeout:         {{duration .Database.MongoDB.ConnectTimeout}},
+        ServerSelectionTimeout: {{duration .Database.MongoDB.ServerSelectionTimeout}},
+        ReadPreference:         "{{.Database.MongoDB.ReadPreference}}",
+    })
     authProvider := jwt.NewJWTAuthProvider("{{.Auth.Secret}}", dbAdapter)
 
     httpEngine := ginadapter.New({{.HTTP.Port}})
@@ -129,6 +292,7 @@ func main() {
     app := core.NewApp().
         AddEntity(User{}, crud.Protect("POST", "PUT", "DELETE")).
         UseDB(dbAdapter).
+        AutoMigrate().
         UseHTTP(httpEngine).
 		UseAuth(authProvider)
 
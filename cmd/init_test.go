@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplates_RenderValidGo(t *testing.T) {
+	for _, driver := range []string{"postgres", "mongodb", "sqlite", "mysql"} {
+		t.Run(driver, func(t *testing.T) {
+			mainTemplate, ok := templateFor(driver, "gin")
+			require.True(t, ok)
+
+			var cfg Config
+			cfg.Database.Driver = driver
+			cfg.Database.DSN = defaultDSNs[driver]
+			cfg.Database.Name = "mydb"
+			cfg.HTTP.Port = 8080
+			cfg.Auth.Secret = "secret"
+			cfg.Database.Pool.ConnMaxLifetime = 5 * time.Minute
+			cfg.Database.MongoDB.ReadPreference = "secondaryPreferred"
+			cfg.Database.Replicas.DSNs = []string{"host=replica1", "host=replica2"}
+
+			var out bytes.Buffer
+			require.NoError(t, renderMain(&out, mainTemplate, &cfg))
+			require.True(t, strings.Contains(out.String(), "github.com/Lumicrate/gompose/db/"+driver))
+
+			_, err := parser.ParseFile(token.NewFileSet(), "main.go", out.Bytes(), 0)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestTemplateFor_Unsupported(t *testing.T) {
+	_, ok := templateFor("oracle", "gin")
+	require.False(t, ok)
+
+	_, ok = templateFor("postgres", "echo")
+	require.False(t, ok)
+}
+
+func TestDriverOptions_Parse(t *testing.T) {
+	for _, driver := range []string{"postgres", "mongodb", "mysql"} {
+		t.Run(driver, func(t *testing.T) {
+			var cfg Config
+			data := "database:\n  driver: " + driver + "\n" + driverOptions[driver]
+			require.NoError(t, yaml.Unmarshal([]byte(data), &cfg))
+		})
+	}
+
+	var cfg Config
+	data := `database:
+  pool:
+    max_open_conns: 20
+    conn_max_lifetime: 5m
+    log_level: info
+  statement_timeout: 30s
+  replicas:
+    dsns: ["host=replica1", "host=replica2"]
+    policy: random
+  mongodb:
+    max_pool_size: 50
+    read_preference: nearest
+`
+	require.NoError(t, yaml.Unmarshal([]byte(data), &cfg))
+	require.Equal(t, 20, cfg.Database.Pool.MaxOpenConns)
+	require.Equal(t, 5*time.Minute, cfg.Database.Pool.ConnMaxLifetime)
+	require.Equal(t, "info", cfg.Database.Pool.LogLevel)
+	require.Equal(t, 30*time.Second, cfg.Database.StatementTimeout)
+	require.Equal(t, []string{"host=replica1", "host=replica2"}, cfg.Database.Replicas.DSNs)
+	require.Equal(t, "random", cfg.Database.Replicas.Policy)
+	require.Equal(t, uint64(50), cfg.Database.MongoDB.MaxPoolSize)
+	require.Equal(t, "nearest", cfg.Database.MongoDB.ReadPreference)
+}
+
+func TestGoDuration(t *testing.T) {
+	require.Equal(t, "time.Duration(0)", goDuration(0))
+	require.Equal(t, "90 * time.Second", goDuration(90*time.Second))
+	require.Equal(t, "2 * time.Hour", goDuration(2*time.Hour))
+	require.Equal(t, "1500 * time.Millisecond", goDuration(1500*time.Millisecond))
+	require.Equal(t, "time.Duration(7)", goDuration(7))
+}
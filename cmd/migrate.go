@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Lumicrate/gompose/db"
+	"github.com/Lumicrate/gompose/db/mongodb"
+	"github.com/Lumicrate/gompose/db/mysql"
+	"github.com/Lumicrate/gompose/db/postgres"
+	"github.com/Lumicrate/gompose/db/sqlite"
+	"github.com/Lumicrate/gompose/migrate"
+	"github.com/spf13/cobra"
+)
+
+var migrateDirFlag string
+var migrateStepsFlag int
+var migrateGoFlag bool
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply, revert and create versioned schema migrations",
+	Long: `Runs the SQL migrations in the migrations directory against the database in gompose.yaml.
+Go migrations are compiled into your app and applied with App.UseMigrations(migrate.Registered()...).`,
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply all pending migrations",
+	Run: func(cmd *cobra.Command, args []string) {
+		runMigrator(func(m *migrate.Migrator) error {
+			applied, err := m.Up(context.Background())
+			for _, migration := range applied {
+				fmt.Printf("Applied %d_%s\n", migration.Version, migration.Name)
+			}
+			if err == nil && len(applied) == 0 {
+				fmt.Println("No pending migrations.")
+			}
+			return err
+		})
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Revert the last applied migrations",
+	Run: func(cmd *cobra.Command, args []string) {
+		runMigrator(func(m *migrate.Migrator) error {
+			reverted, err := m.Down(context.Background(), migrateStepsFlag)
+			for _, migration := range reverted {
+				fmt.Printf("Reverted %d_%s\n", migration.Version, migration.Name)
+			}
+			return err
+		})
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List migrations and whether they are applied",
+	Run: func(cmd *cobra.Command, args []string) {
+		runMigrator(func(m *migrate.Migrator) error {
+			statuses, err := m.Status(context.Background())
+			if err != nil {
+				return err
+			}
+			for _, s := range statuses {
+				state := "pending"
+				if s.Applied {
+					state = "applied " + s.AppliedAt.Format(time.RFC3339)
+				}
+				fmt.Printf("%d_%s\t%s\n", s.Version, s.Name, state)
+			}
+			return nil
+		})
+	},
+}
+
+var migrateCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Create a new migration (SQL up/down files, or a Go file with --go)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		files, err := createMigration(migrateDirFlag, args[0], time.Now(), migrateGoFlag)
+		if err != nil {
+			fmt.Println("Error creating migration:", err)
+			return
+		}
+		for _, file := range files {
+			fmt.Println("Created", file)
+		}
+	},
+}
+
+func runMigrator(fn func(m *migrate.Migrator) error) {
+	cfg, err := readConfig()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	adapter, err := adapterFor(cfg)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := adapter.Init(); err != nil {
+		fmt.Println("DB Init failed:", err)
+		return
+	}
+	defer adapter.Close()
+
+	migrations, err := migrate.LoadSQL(os.DirFS(migrateDirFlag))
+	if err != nil {
+		fmt.Println("Failed to load migrations:", err)
+		return
+	}
+
+	if err := fn(migrate.New(adapter, migrations...)); err != nil {
+		fmt.Println("Migration failed:", err)
+	}
+}
+
+// adapterFor builds the database adapter configured in gompose.yaml.
+func adapterFor(cfg *Config) (db.DBAdapter, error) {
+	switch cfg.Database.Driver {
+	case "postgres":
+		return postgres.NewWithOptions(cfg.Database.DSN, postgres.Options{
+			Options:          cfg.Database.Pool,
+			StatementTimeout: cfg.Database.StatementTimeout,
+			Replicas:         cfg.Database.Replicas,
+		}), nil
+	case "mongodb":
+		return mongodb.NewWithOptions(cfg.Database.DSN, cfg.Database.Name, cfg.Database.MongoDB), nil
+	case "sqlite":
+		return sqlite.New(cfg.Database.DSN), nil
+	case "mysql":
+		return mysql.NewWithOptions(cfg.Database.DSN, cfg.Database.Pool), nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", cfg.Database.Driver)
+}
+
+var nonIdentifier = regexp.MustCompile(`[^a-z0-9]+`)
+
+// createMigration writes the files of a new migration versioned by now and returns their paths.
+func createMigration(dir, name string, now time.Time, goFile bool) ([]string, error) {
+	name = strings.Trim(nonIdentifier.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, errors.New("migration name must contain letters or digits")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	version, _ := strconv.ParseInt(now.UTC().Format("20060102150405"), 10, 64)
+	base := filepath.Join(dir, fmt.Sprintf("%d_%s", version, name))
+
+	if goFile {
+		pkg := filepath.Base(dir)
+		if !token.IsIdentifier(pkg) {
+			pkg = "migrations"
+		}
+
+		var out strings.Builder
+		data := map[string]any{"Package": pkg, "Version": version, "Name": name}
+		if err := template.Must(template.New("migration").Parse(goMigrationTemplate)).Execute(&out, data); err != nil {
+			return nil, err
+		}
+		if err := writeNewFile(base+".go", out.String()); err != nil {
+			return nil, err
+		}
+		return []string{base + ".go"}, nil
+	}
+
+	files := []string{base + ".up.sql", base + ".down.sql"}
+	for _, file := range files {
+		if err := writeNewFile(file, "-- "+filepath.Base(file)+"\n"); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func writeNewFile(path, content string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(content)
+	return err
+}
+
+const goMigrationTemplate = `package {{.Package}}
+
+import (
+	"context"
+
+	"github.com/Lumicrate/gompose/db"
+	"github.com/Lumicrate/gompose/migrate"
+)
+
+func init() {
+	migrate.Register({{.Version}}, "{{.Name}}", up{{.Version}}, down{{.Version}})
+}
+
+func up{{.Version}}(ctx context.Context, adapter db.DBAdapter) error {
+	return nil
+}
+
+func down{{.Version}}(ctx context.Context, adapter db.DBAdapter) error {
+	return nil
+}
+`
+
+func init() {
+	migrateCmd.PersistentFlags().StringVar(&migrateDirFlag, "dir", "migrations", "Migrations directory")
+	migrateDownCmd.Flags().IntVar(&migrateStepsFlag, "steps", 1, "Number of migrations to revert")
+	migrateCreateCmd.Flags().BoolVar(&migrateGoFlag, "go", false, "Create a Go migration instead of SQL files")
+
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd, migrateCreateCmd)
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Lumicrate/gompose/migrate"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateMigration_SQL(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+	now := time.Date(2026, 10, 18, 12, 30, 0, 0, time.UTC)
+
+	files, err := createMigration(dir, "Add Users!", now, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "20261018123000_add_users.up.sql"),
+		filepath.Join(dir, "20261018123000_add_users.down.sql"),
+	}, files)
+
+	migrations, err := migrate.LoadSQL(os.DirFS(dir))
+	require.NoError(t, err)
+	require.Len(t, migrations, 1)
+	require.Equal(t, int64(20261018123000), migrations[0].Version)
+
+	_, err = createMigration(dir, "add users", now, false)
+	require.Error(t, err, "existing migrations are never overwritten")
+}
+
+func TestCreateMigration_Go(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+
+	files, err := createMigration(dir, "seed", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), true)
+	require.NoError(t, err)
+
+	file, err := parser.ParseFile(token.NewFileSet(), files[0], nil, 0)
+	require.NoError(t, err)
+	require.Equal(t, "migrations", file.Name.Name)
+}
@@ -28,4 +28,5 @@ func init() {
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(generateCmd)
+	rootCmd.AddCommand(migrateCmd)
 }
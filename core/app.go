@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"log"
 
 	"github.com/Lumicrate/gompose/auth"
@@ -9,6 +10,7 @@ import (
 	"github.com/Lumicrate/gompose/docs/swagger"
 	"github.com/Lumicrate/gompose/http"
 	"github.com/Lumicrate/gompose/i18n"
+	"github.com/Lumicrate/gompose/migrate"
 )
 
 type App struct {
@@ -19,6 +21,8 @@ type App struct {
 	authProvider    auth.AuthProvider
 	swaggerProvider *swagger.SwaggerProvider
 	localization    *i18n.Translator
+	migrations      []migrate.Migration
+	autoMigrate     bool
 }
 
 type registeredEntity struct {
@@ -55,6 +59,19 @@ func (a *App) UseDB(adapter db.DBAdapter) *App {
 	return a
 }
 
+// UseMigrations applies the pending migrations when the app starts.
+func (a *App) UseMigrations(migrations ...migrate.Migration) *App {
+	a.migrations = append(a.migrations, migrations...)
+	return a
+}
+
+// AutoMigrate lets the adapter create and alter the schema of the registered entities
+// on start. It never drops or renames anything, so it is meant for development.
+func (a *App) AutoMigrate() *App {
+	a.autoMigrate = true
+	return a
+}
+
 func (a *App) UseHTTP(engine http.HTTPEngine) *App {
 	a.httpEngine = engine
 	return a
@@ -100,9 +117,30 @@ func (a *App) Run() {
 		if err := a.dbAdapter.Init(); err != nil {
 			log.Fatalf("DB Init failed: %v", err)
 		}
+		defer func() {
+			if err := a.dbAdapter.Close(); err != nil {
+				log.Printf("DB Close failed: %v", err)
+			}
+		}()
+
+		if len(a.migrations) > 0 {
+			applied, err := migrate.New(a.dbAdapter, a.migrations...).Up(context.Background())
+			for _, m := range applied {
+				log.Printf("Applied migration %d_%s", m.Version, m.Name)
+			}
+			if err != nil {
+				log.Fatalf("DB Migration failed: %v", err)
+			}
+		}
 
-		if err := a.dbAdapter.Migrate(a.Entities()); err != nil {
-			log.Fatalf("DB Migration failed: %v", err)
+		if a.autoMigrate {
+			if err := a.dbAdapter.Migrate(a.Entities()); err != nil {
+				log.Fatalf("DB Migration failed: %v", err)
+			}
+		} else if ensurer, ok := a.dbAdapter.(db.IndexEnsurer); ok {
+			if err := ensurer.EnsureIndexes(a.Entities()); err != nil {
+				log.Fatalf("DB Indexes failed: %v", err)
+			}
 		}
 	}
 
@@ -0,0 +1,229 @@
+package crud
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lumicrate/gompose/db"
+	gerrors "github.com/Lumicrate/gompose/errors"
+	"github.com/Lumicrate/gompose/http"
+)
+
+// handleAggregate serves GET /entities/aggregate: one object per group holding the
+// group-by fields and the metrics, e.g. {"status": "paid", "count": 3, "sum(total)": 120}.
+// The list filters apply; metrics default to count. Groups are ordered by the group-by
+// fields unless sorted by their keys with ?sort=, and limit and offset page through them.
+func handleAggregate(ctx http.Context, dbAdapter db.DBAdapter, entity any, config *Config) {
+	var filters []db.Filter
+	var groupBy []string
+	var metrics []db.Metric
+	groupKeys := []string{}
+	metricKeys := []string{}
+	includeDeleted := false
+	search := ""
+	sortParam := ""
+	limit, offset := 0, 0
+
+	for key, vals := range ctx.QueryParams() {
+		val := vals[0]
+		switch key {
+		case "group_by":
+			for _, name := range strings.Split(val, ",") {
+				field, err := resolveQueryField(entity, strings.TrimSpace(name), config.Aggregatable)
+				if err != nil {
+					gerrors.Write(ctx, gerrors.BadRequest("invalid group_by: "+err.Error()))
+					return
+				}
+				groupBy = append(groupBy, field)
+				groupKeys = append(groupKeys, jsonName(entity, field))
+			}
+		case "metrics":
+			for _, expr := range strings.Split(val, ",") {
+				metric, err := parseMetric(entity, expr, config.Aggregatable)
+				if err != nil {
+					gerrors.Write(ctx, gerrors.BadRequest("invalid metrics: "+err.Error()))
+					return
+				}
+				metrics = append(metrics, metric)
+				metricKeys = append(metricKeys, metricKey(entity, metric))
+			}
+		case "include_deleted":
+			includeDeleted, _ = strconv.ParseBool(val)
+		case "sort":
+			sortParam = val
+		case "limit":
+			if l, err := strconv.Atoi(val); err == nil {
+				limit = l
+			}
+		case "offset":
+			if o, err := strconv.Atoi(val); err == nil {
+				offset = o
+			}
+		case "q":
+			if len(config.Searchable) > 0 {
+				search = val
+				continue
+			}
+			fallthrough
+		default:
+			filter, err := parseQueryFilter(entity, key, val, config)
+			if err != nil {
+				gerrors.Write(ctx, err)
+				return
+			}
+			filters = append(filters, filter)
+		}
+	}
+	if len(metrics) == 0 {
+		metrics = []db.Metric{{Func: db.AggCount}}
+		metricKeys = []string{string(db.AggCount)}
+	}
+	sorts, err := parseAggregateSort(sortParam, append(append([]string{}, groupKeys...), metricKeys...))
+	if err != nil {
+		gerrors.Write(ctx, gerrors.BadRequest("invalid sort: "+err.Error()))
+		return
+	}
+
+	dbAdapter, err = withDeleted(ctx, dbAdapter, entity, includeDeleted, config)
+	if err != nil {
+		gerrors.Write(ctx, err)
+		return
+	}
+	if strings.TrimSpace(search) != "" {
+		if dbAdapter, err = withSearch(dbAdapter, entity, search, config.Searchable); err != nil {
+			gerrors.Write(ctx, err)
+			return
+		}
+	}
+
+	rows, err := dbAdapter.Aggregate(entity, filters, groupBy, metrics)
+	if err != nil {
+		gerrors.Write(ctx, err)
+		return
+	}
+
+	result := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		result[i] = make(map[string]any, len(groupKeys)+len(metricKeys))
+		for j, key := range groupKeys {
+			result[i][key] = row.Group[j]
+		}
+		for j, key := range metricKeys {
+			result[i][key] = row.Metrics[j]
+		}
+	}
+
+	if len(sorts) > 0 {
+		slices.SortStableFunc(result, func(a, b map[string]any) int {
+			for _, s := range sorts {
+				if c := compareAggregateValues(a[s.Field], b[s.Field]); c != 0 {
+					if s.Direction == "desc" {
+						return -c
+					}
+					return c
+				}
+			}
+			return 0
+		})
+	}
+	result = result[min(max(offset, 0), len(result)):]
+	if limit > 0 && limit < len(result) {
+		result = result[:limit]
+	}
+	ctx.JSON(200, result)
+}
+
+// parseAggregateSort parses ?sort= of the aggregate route, whose keys are those of the
+// result objects: the group-by fields and the metrics, such as sort=-count,status.
+func parseAggregateSort(param string, keys []string) ([]db.Sort, error) {
+	if param == "" {
+		return nil, nil
+	}
+	var sorts []db.Sort
+	for _, key := range strings.Split(param, ",") {
+		direction := "asc"
+		if strings.HasPrefix(key, "-") {
+			direction = "desc"
+			key = strings.TrimPrefix(key, "-")
+		}
+		if !slices.Contains(keys, key) {
+			return nil, fmt.Errorf("%q is not a group-by field or metric of the request", key)
+		}
+		sorts = append(sorts, db.Sort{Field: key, Direction: direction})
+	}
+	return sorts, nil
+}
+
+// compareAggregateValues orders the values of a group-by field or metric, nulls first.
+func compareAggregateValues(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case isNumber(av) && isNumber(bv):
+		return cmp.Compare(toFloat(av), toFloat(bv))
+	case av.Kind() == reflect.String && bv.Kind() == reflect.String:
+		return strings.Compare(av.String(), bv.String())
+	}
+	if at, ok := a.(time.Time); ok {
+		if bt, ok := b.(time.Time); ok {
+			return at.Compare(bt)
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func isNumber(v reflect.Value) bool {
+	return v.CanInt() || v.CanUint() || v.CanFloat()
+}
+
+func toFloat(v reflect.Value) float64 {
+	switch {
+	case v.CanInt():
+		return float64(v.Int())
+	case v.CanUint():
+		return float64(v.Uint())
+	}
+	return v.Float()
+}
+
+// parseMetric parses count or fn(field) and resolves the field among the allowed ones.
+// Only numeric fields can be aggregated.
+func parseMetric(entity any, expr string, allowed []string) (db.Metric, error) {
+	metric, err := db.ParseMetric(expr)
+	if err != nil || metric.Func == db.AggCount {
+		return metric, err
+	}
+
+	if metric.Field, err = resolveQueryField(entity, metric.Field, allowed); err != nil {
+		return db.Metric{}, err
+	}
+	t := reflect.TypeOf(entity)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if f, _ := t.FieldByName(metric.Field); !db.IsNumeric(f.Type) {
+		return db.Metric{}, fmt.Errorf("cannot compute %s of non-numeric field %q", metric.Func, jsonName(entity, metric.Field))
+	}
+	return metric, nil
+}
+
+// metricKey names a metric in responses, with the json name of its field: count, sum(total).
+func metricKey(entity any, m db.Metric) string {
+	if m.Func == db.AggCount {
+		return string(m.Func)
+	}
+	return fmt.Sprintf("%s(%s)", m.Func, jsonName(entity, m.Field))
+}
@@ -0,0 +1,103 @@
+package crud
+
+import (
+	"testing"
+
+	"github.com/Lumicrate/gompose/db/memory"
+	"github.com/stretchr/testify/require"
+)
+
+type Sale struct {
+	ID     string  `json:"id"`
+	Status string  `json:"status"`
+	Total  float64 `json:"total"`
+	Note   string  `json:"note"`
+}
+
+func salesAdapter(t *testing.T) *memory.MemoryAdapter {
+	adapter := memory.New()
+	require.NoError(t, adapter.CreateMany([]any{
+		&Sale{Status: "paid", Total: 10},
+		&Sale{Status: "pending", Total: 5},
+		&Sale{Status: "paid", Total: 30},
+		&Sale{Status: "refunded", Total: 100},
+	}))
+	return adapter
+}
+
+func TestHandleAggregate(t *testing.T) {
+	ctx := new(MockContext)
+	ctx.On("QueryParams").Return(map[string][]string{
+		"group_by":  {"status"},
+		"metrics":   {"count,sum(total),avg(total),max(total)"},
+		"total[lt]": {"100"},
+	})
+	config := DefaultConfig()
+	Aggregatable("status", "total")(config)
+
+	handleAggregate(ctx, salesAdapter(t), Sale{}, config)
+
+	require.Equal(t, 200, ctx.Status())
+	require.Equal(t, []map[string]any{
+		{"status": "paid", "count": int64(2), "sum(total)": 40.0, "avg(total)": 20.0, "max(total)": 30.0},
+		{"status": "pending", "count": int64(1), "sum(total)": 5.0, "avg(total)": 5.0, "max(total)": 5.0},
+	}, ctx.Resp)
+}
+
+func TestHandleAggregate_DefaultsToCount(t *testing.T) {
+	ctx := new(MockContext)
+	ctx.On("QueryParams").Return(map[string][]string{})
+	config := DefaultConfig()
+	Aggregatable("status")(config)
+
+	handleAggregate(ctx, salesAdapter(t), Sale{}, config)
+
+	require.Equal(t, 200, ctx.Status())
+	require.Equal(t, []map[string]any{{"count": int64(4)}}, ctx.Resp)
+}
+
+func TestHandleAggregate_SortAndPaginate(t *testing.T) {
+	ctx := new(MockContext)
+	ctx.On("QueryParams").Return(map[string][]string{
+		"group_by": {"status"},
+		"metrics":  {"count,sum(total)"},
+		"sort":     {"-count,-sum(total)"},
+		"limit":    {"2"},
+		"offset":   {"1"},
+	})
+	config := DefaultConfig()
+	Aggregatable("status", "total")(config)
+
+	handleAggregate(ctx, salesAdapter(t), Sale{}, config)
+
+	require.Equal(t, 200, ctx.Status())
+	require.Equal(t, []map[string]any{
+		{"status": "refunded", "count": int64(1), "sum(total)": 100.0},
+		{"status": "pending", "count": int64(1), "sum(total)": 5.0},
+	}, ctx.Resp)
+}
+
+func TestHandleAggregate_RejectsFields(t *testing.T) {
+	config := DefaultConfig()
+	Aggregatable("status", "total")(config)
+
+	for _, query := range []map[string][]string{
+		{"group_by": {"note"}},
+		{"group_by": {"status,"}},
+		{"metrics": {"sum(note)"}},
+		{"metrics": {"sum(status)"}},
+		{"metrics": {"median(total)"}},
+		{"metrics": {"count(total"}},
+		{"group_by": {"status"}, "sort": {"total"}},
+		{"metrics": {"sum(total)"}, "sort": {"count"}},
+	} {
+		ctx := new(MockContext)
+		ctx.On("QueryParams").Return(query)
+		mockDB := new(MockDB)
+
+		handleAggregate(ctx, mockDB, Sale{}, config)
+
+		require.Equal(t, 400, ctx.Status(), query)
+		mockDB.AssertNotCalled(t, "Aggregate")
+	}
+}
@@ -0,0 +1,449 @@
+package crud
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Lumicrate/gompose/db"
+	gerrors "github.com/Lumicrate/gompose/errors"
+	"github.com/Lumicrate/gompose/hooks"
+	"github.com/Lumicrate/gompose/http"
+)
+
+// maxBulkItems bounds the number of items in one bulk request.
+const maxBulkItems = 1000
+
+// statusFailedDependency marks the items of an all-or-nothing bulk request that were
+// valid but not written because another item failed.
+const statusFailedDependency = 424
+
+// BulkResult is the outcome of one item of a bulk request. Index is the position of
+// the item in the request body, or in ?ids= for deletes.
+type BulkResult struct {
+	Index  int              `json:"index"`
+	Status int              `json:"status"`
+	Data   any              `json:"data,omitempty"`
+	Error  *gerrors.Problem `json:"error,omitempty"`
+}
+
+// BulkResponse is the body of the bulk routes, with one result per item in request order.
+type BulkResponse struct {
+	Results   []BulkResult `json:"results"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+}
+
+// bulkPrepare builds the entity of item i: it decodes, loads and validates it without writing.
+type bulkPrepare func(dbAdapter db.DBAdapter, i int) (any, error)
+
+// bulkWrite writes prepared entities and runs their hooks. indexes holds the request
+// index of every entity; errors about one entity are *db.BatchError with its position in entities.
+type bulkWrite func(tx db.DBAdapter, indexes []int, entities []any) error
+
+// handleBulkCreate serves POST /entities/bulk with an array of entities.
+func handleBulkCreate(ctx http.Context, dbAdapter db.DBAdapter, entity any, config *Config) {
+	items, ok := bindBulkItems(ctx)
+	if !ok {
+		return
+	}
+
+	prepare := func(_ db.DBAdapter, i int) (any, error) {
+		newEntity := newEntityOf(entity)
+		if err := json.Unmarshal(items[i], newEntity); err != nil {
+			return nil, gerrors.BadRequest("invalid input: " + err.Error())
+		}
+		return newEntity, validationError(newEntity)
+	}
+
+	write := func(tx db.DBAdapter, _ []int, entities []any) error {
+		return writeBatch(tx, entities, func(e any) error {
+			if hook, ok := e.(hooks.BeforeCreate); ok {
+				if err := hook.BeforeCreate(); err != nil {
+					return &hookError{hook: "beforeSave", err: err}
+				}
+			}
+			return nil
+		}, func() error {
+			return tx.CreateMany(entities)
+		}, func(e any) error {
+			if hook, ok := e.(hooks.AfterCreate); ok {
+				if err := hook.AfterCreate(); err != nil {
+					return &hookError{hook: "afterSave", err: err}
+				}
+			}
+			return nil
+		})
+	}
+
+	runBulk(ctx, dbAdapter, config, len(items), 201, prepare, write)
+}
+
+// handleBulkPatch serves PATCH /entities/bulk with an array of partial entities, each
+// carrying its id. The version in an item plays the role of If-Match for that item.
+func handleBulkPatch(ctx http.Context, dbAdapter db.DBAdapter, entity any, config *Config) {
+	items, ok := bindBulkItems(ctx)
+	if !ok {
+		return
+	}
+	idKey, versionKey := jsonName(entity, "ID"), jsonName(entity, db.VersionField)
+
+	prepare := func(dbAdapter db.DBAdapter, i int) (any, error) {
+		patchData := map[string]any{}
+		decoder := json.NewDecoder(bytes.NewReader(items[i]))
+		decoder.UseNumber()
+		if err := decoder.Decode(&patchData); err != nil {
+			return nil, gerrors.BadRequest("invalid patch data: " + err.Error())
+		}
+		id, ok := patchData[idKey]
+		if !ok || id == nil || id == "" {
+			return nil, gerrors.BadRequest(fmt.Sprintf("%s is required", idKey))
+		}
+
+		found, err := dbAdapter.FindByID(fmt.Sprint(id), newEntityOf(entity))
+		if err != nil {
+			return nil, err
+		}
+		if config.RequireIfMatch && db.IsVersioned(found) {
+			if _, ok := patchData[versionKey]; !ok {
+				return nil, gerrors.PreconditionRequired(versionKey + " is required")
+			}
+		}
+
+		patchBytes, _ := json.Marshal(patchData)
+		if err := json.Unmarshal(patchBytes, &found); err != nil {
+			return nil, gerrors.BadRequest("invalid patch data: " + err.Error())
+		}
+		return found, validationError(found)
+	}
+
+	write := func(tx db.DBAdapter, _ []int, entities []any) error {
+		return writeBatch(tx, entities, func(e any) error {
+			if hook, ok := e.(hooks.BeforePatch); ok {
+				if err := hook.BeforePatch(); err != nil {
+					return &hookError{hook: "beforePatch", err: err}
+				}
+			}
+			return nil
+		}, func() error {
+			return tx.UpdateMany(entities)
+		}, func(e any) error {
+			if hook, ok := e.(hooks.AfterPatch); ok {
+				if err := hook.AfterPatch(); err != nil {
+					return &hookError{hook: "afterPatch", err: err}
+				}
+			}
+			return nil
+		})
+	}
+
+	runBulk(ctx, dbAdapter, config, len(items), 200, prepare, write)
+}
+
+// handleBulkDelete serves DELETE /entities/bulk?ids=1,2,3.
+func handleBulkDelete(ctx http.Context, dbAdapter db.DBAdapter, entity any, config *Config) {
+	var ids []string
+	seen := map[string]bool{}
+	for _, id := range strings.Split(ctx.Query("ids"), ",") {
+		if id = strings.TrimSpace(id); id == "" {
+			continue
+		}
+		if seen[id] {
+			gerrors.Write(ctx, gerrors.BadRequest(fmt.Sprintf("duplicate id %q", id)))
+			return
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if !checkBulkSize(ctx, len(ids), "ids") {
+		return
+	}
+
+	// Deletes carry no version, so they cannot satisfy RequireIfMatch.
+	if config.RequireIfMatch && db.IsVersioned(entity) {
+		gerrors.Write(ctx, gerrors.PreconditionRequired("bulk deletes are not available when If-Match is required"))
+		return
+	}
+
+	prepare := func(_ db.DBAdapter, _ int) (any, error) {
+		return newEntityOf(entity), nil
+	}
+
+	write := func(tx db.DBAdapter, indexes []int, entities []any) error {
+		batch := make([]string, len(indexes))
+		for k, i := range indexes {
+			batch[k] = ids[i]
+		}
+		return writeBatch(tx, entities, func(e any) error {
+			if hook, ok := e.(hooks.BeforeDelete); ok {
+				if err := hook.BeforeDelete(); err != nil {
+					return &hookError{hook: "beforeDelete", err: err}
+				}
+			}
+			return nil
+		}, func() error {
+			return tx.DeleteMany(batch, newEntityOf(entity))
+		}, func(e any) error {
+			if hook, ok := e.(hooks.AfterDelete); ok {
+				if err := hook.AfterDelete(); err != nil {
+					return &hookError{hook: "afterDelete", err: err}
+				}
+			}
+			return nil
+		})
+	}
+
+	results, atomic := runBulkItems(ctx, dbAdapter, config, len(ids), 204, prepare, write)
+	for i := range results {
+		results[i].Data = nil
+	}
+	writeBulkResponse(ctx, results, atomic, 200)
+}
+
+// runBulk prepares and writes n items and responds with their results.
+func runBulk(ctx http.Context, dbAdapter db.DBAdapter, config *Config, n, okStatus int, prepare bulkPrepare, write bulkWrite) {
+	results, atomic := runBulkItems(ctx, dbAdapter, config, n, okStatus, prepare, write)
+	writeBulkResponse(ctx, results, atomic, okStatus)
+}
+
+// runBulkItems prepares every item and writes the valid ones in one transaction. It returns
+// the results and whether the request was all-or-nothing.
+//
+// In all-or-nothing mode nothing is written unless every item succeeds, and the items that were
+// not at fault are reported as 424. Otherwise, when the batch fails, every item is retried in a
+// transaction of its own so each gets its real outcome; items are re-prepared for the retry,
+// so hooks never see an entity already changed by the failed attempt.
+//
+// Adapters without transactions cannot roll a failed batch back, so neither applies to them:
+// the results report what was written and no item is written twice.
+func runBulkItems(ctx http.Context, dbAdapter db.DBAdapter, config *Config, n, okStatus int, prepare bulkPrepare, write bulkWrite) ([]BulkResult, bool) {
+	atomic := bulkAtomic(ctx, config)
+	results := make([]BulkResult, n)
+
+	var indexes []int
+	var entities []any
+	for i := 0; i < n; i++ {
+		e, err := prepare(dbAdapter, i)
+		if err != nil {
+			results[i] = bulkFailure(i, err)
+			continue
+		}
+		indexes = append(indexes, i)
+		entities = append(entities, e)
+	}
+
+	if atomic && len(indexes) < n {
+		skipBulkItems(results, indexes)
+		return results, atomic
+	}
+	if len(entities) == 0 {
+		return results, atomic
+	}
+
+	transactional := db.SupportsTransactions(dbAdapter)
+	err := dbAdapter.WithTransaction(func(tx db.DBAdapter) error {
+		return write(tx, indexes, entities)
+	})
+	var partial *db.PartialBatchError
+	var batchErr *db.BatchError
+	switch {
+	case err == nil:
+		for k, i := range indexes {
+			results[i] = BulkResult{Index: i, Status: okStatus, Data: entities[k]}
+		}
+	case !transactional && errors.As(err, &partial):
+		// Part of the batch was written and stays written, whatever the mode.
+		for k, i := range indexes {
+			if err := partial.Failed(k); err != nil {
+				results[i] = bulkFailure(i, err)
+			} else {
+				results[i] = BulkResult{Index: i, Status: okStatus, Data: entities[k]}
+			}
+		}
+		return results, false
+	case !transactional && !errors.As(err, &batchErr):
+		// What was written is unknown, so nothing is retried. A *db.BatchError means nothing
+		// was written yet (see writeBatch), and is handled like a rolled back batch below.
+		for _, i := range indexes {
+			results[i] = bulkFailure(i, err)
+		}
+	case atomic:
+		if errors.As(err, &batchErr) && batchErr.Index >= 0 && batchErr.Index < len(indexes) {
+			failed := indexes[batchErr.Index]
+			results[failed] = bulkFailure(failed, batchErr.Err)
+			skipBulkItems(results, append(indexes[:batchErr.Index:batchErr.Index], indexes[batchErr.Index+1:]...))
+			break
+		}
+		for _, i := range indexes {
+			results[i] = bulkFailure(i, err)
+		}
+	default:
+		for _, i := range indexes {
+			e, err := prepare(dbAdapter, i)
+			if err == nil {
+				err = dbAdapter.WithTransaction(func(tx db.DBAdapter) error {
+					return write(tx, []int{i}, []any{e})
+				})
+			}
+			if errors.As(err, &partial) {
+				err = partial.Failed(0)
+			} else if errors.As(err, &batchErr) {
+				err = batchErr.Err
+			}
+			if err != nil {
+				results[i] = bulkFailure(i, err)
+			} else {
+				results[i] = BulkResult{Index: i, Status: okStatus, Data: e}
+			}
+		}
+	}
+	return results, atomic
+}
+
+// writeBatch runs before on every entity, then write, then after on the entities written.
+// Errors about one entity are *db.BatchError. Without transactions nothing undoes the write
+// once it happened, so a failing after hook is reported with the items the database rejected
+// in a *db.PartialBatchError.
+func writeBatch(tx db.DBAdapter, entities []any, before func(e any) error, write func() error, after func(e any) error) error {
+	for k, e := range entities {
+		if err := before(e); err != nil {
+			return &db.BatchError{Index: k, Err: err}
+		}
+	}
+
+	err := write()
+	var partial *db.PartialBatchError
+	if err != nil && !errors.As(err, &partial) {
+		return err
+	}
+	transactional := db.SupportsTransactions(tx)
+	for k, e := range entities {
+		if partial.Failed(k) != nil {
+			continue
+		}
+		if err := after(e); err != nil {
+			if transactional {
+				return &db.BatchError{Index: k, Err: err}
+			}
+			if partial == nil {
+				partial = &db.PartialBatchError{}
+			}
+			partial.Errors = append(partial.Errors, &db.BatchError{Index: k, Err: err})
+		}
+	}
+	if partial != nil {
+		sort.Slice(partial.Errors, func(i, j int) bool { return partial.Errors[i].Index < partial.Errors[j].Index })
+		return partial
+	}
+	return nil
+}
+
+// writeBulkResponse responds okStatus when every item succeeded. Otherwise an all-or-nothing
+// request takes the status of its first failing item and a partial one answers 207 Multi-Status.
+func writeBulkResponse(ctx http.Context, results []BulkResult, atomic bool, okStatus int) {
+	response := BulkResponse{Results: results}
+	status := okStatus
+	for _, r := range results {
+		if r.Error == nil {
+			response.Succeeded++
+			continue
+		}
+		if response.Failed == 0 || status == statusFailedDependency {
+			status = r.Status
+		}
+		response.Failed++
+	}
+	if response.Failed > 0 && !atomic {
+		status = 207
+	}
+	ctx.JSON(status, response)
+}
+
+// bulkAtomic reports whether a bulk request is all-or-nothing: always with BulkAtomic,
+// otherwise when the client asks for it with ?atomic=true.
+func bulkAtomic(ctx http.Context, config *Config) bool {
+	if config.BulkAtomic {
+		return true
+	}
+	atomic, _ := strconv.ParseBool(ctx.Query("atomic"))
+	return atomic
+}
+
+func bindBulkItems(ctx http.Context) ([]json.RawMessage, bool) {
+	var items []json.RawMessage
+	if err := ctx.BindJSON(&items); err != nil {
+		gerrors.Write(ctx, gerrors.BadRequest("invalid input: expected an array of items: "+err.Error()))
+		return nil, false
+	}
+	return items, checkBulkSize(ctx, len(items), "items")
+}
+
+func checkBulkSize(ctx http.Context, n int, what string) bool {
+	switch {
+	case n == 0:
+		gerrors.Write(ctx, gerrors.BadRequest("no "+what+" given"))
+		return false
+	case n > maxBulkItems:
+		gerrors.Write(ctx, gerrors.Newf(413, "at most %d %s are allowed per request", maxBulkItems, what))
+		return false
+	}
+	return true
+}
+
+func bulkFailure(i int, err error) BulkResult {
+	problem := gerrors.ToProblem(txError(err))
+	if problem.Status >= 500 {
+		log.Printf("gompose: bulk item %d: %d %s: %v", i, problem.Status, problem.Title, err)
+	}
+	return BulkResult{Index: i, Status: problem.Status, Error: &problem}
+}
+
+// skipBulkItems marks the given items as not written because of another item.
+func skipBulkItems(results []BulkResult, indexes []int) {
+	for _, i := range indexes {
+		problem := gerrors.ToProblem(gerrors.New(statusFailedDependency, "not written because another item failed"))
+		results[i] = BulkResult{Index: i, Status: statusFailedDependency, Error: &problem}
+	}
+}
+
+// validationError runs the `validate` tags of entity, like validateRequest, but returns the failure.
+func validationError(entity any) error {
+	fields, err := validateEntity(entity)
+	if err != nil {
+		return err
+	}
+	if len(fields) > 0 {
+		return gerrors.Validation(fields)
+	}
+	return nil
+}
+
+func newEntityOf(entity any) any {
+	t := reflect.TypeOf(entity)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return reflect.New(t).Interface()
+}
+
+// jsonName returns the name clients use for the Go field name of entity.
+func jsonName(entity any, field string) string {
+	t := reflect.TypeOf(entity)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	f, ok := t.FieldByName(field)
+	if !ok {
+		return field
+	}
+	if name := strings.Split(f.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+		return name
+	}
+	return field
+}
@@ -0,0 +1,272 @@
+package crud
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Lumicrate/gompose/db"
+	"github.com/Lumicrate/gompose/db/memory"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// bulkContext returns a context whose body is the JSON array body and whose query holds query.
+func bulkContext(t *testing.T, body string, query map[string]string) *MockContext {
+	var items []json.RawMessage
+	if body != "" {
+		require.NoError(t, json.Unmarshal([]byte(body), &items))
+	}
+
+	ctx := new(MockContext)
+	ctx.On("BindJSON", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*[]json.RawMessage) = items
+	}).Return(nil)
+	for key, value := range query {
+		ctx.On("Query", key).Return(value)
+	}
+	ctx.On("Query", mock.Anything).Return("").Maybe()
+	return ctx
+}
+
+func bulkStatuses(t *testing.T, ctx *MockContext) []int {
+	response, ok := ctx.Resp.(BulkResponse)
+	require.True(t, ok, "expected a BulkResponse, got %T", ctx.Resp)
+
+	statuses := make([]int, len(response.Results))
+	for i, r := range response.Results {
+		require.Equal(t, i, r.Index)
+		statuses[i] = r.Status
+	}
+	return statuses
+}
+
+func countOf(t *testing.T, adapter db.DBAdapter, entity any) int64 {
+	count, err := adapter.Count(entity, nil)
+	require.NoError(t, err)
+	return count
+}
+
+func TestHandleBulkCreate_Success(t *testing.T) {
+	adapter := memory.New()
+	ctx := bulkContext(t, `[{"Name": "a"}, {"Name": "b"}, {"Name": "c"}]`, nil)
+
+	handleBulkCreate(ctx, adapter, TestEntity{}, DefaultConfig())
+
+	require.Equal(t, 201, ctx.Status())
+	require.Equal(t, []int{201, 201, 201}, bulkStatuses(t, ctx))
+	response := ctx.Resp.(BulkResponse)
+	require.Equal(t, 3, response.Succeeded)
+	require.NotEmpty(t, response.Results[0].Data.(*TestEntity).ID)
+	require.Equal(t, int64(3), countOf(t, adapter, TestEntity{}))
+}
+
+func TestHandleBulkCreate_PartialFailure(t *testing.T) {
+	adapter := memory.New()
+	ctx := bulkContext(t, `[
+		{"email": "a@example.com", "name": "Alice"},
+		{"email": "nope", "name": "Bob"},
+		{"email": "c@example.com", "name": "Carol"}
+	]`, nil)
+
+	handleBulkCreate(ctx, adapter, ValidatedEntity{}, DefaultConfig())
+
+	require.Equal(t, 207, ctx.Status())
+	require.Equal(t, []int{201, 422, 201}, bulkStatuses(t, ctx))
+	response := ctx.Resp.(BulkResponse)
+	require.Equal(t, 2, response.Succeeded)
+	require.Equal(t, 1, response.Failed)
+	require.Equal(t, "email", response.Results[1].Error.Errors[0].Field)
+	require.Equal(t, int64(2), countOf(t, adapter, ValidatedEntity{}))
+}
+
+func TestHandleBulkCreate_DatabaseErrorRetriesItems(t *testing.T) {
+	adapter := memory.New()
+	ctx := bulkContext(t, `[{"ID": "a"}, {"ID": "a"}, {"ID": "b"}]`, nil)
+
+	handleBulkCreate(ctx, adapter, TestEntity{}, DefaultConfig())
+
+	require.Equal(t, 207, ctx.Status())
+	require.Equal(t, []int{201, 409, 201}, bulkStatuses(t, ctx))
+	require.Equal(t, int64(2), countOf(t, adapter, TestEntity{}))
+}
+
+func TestHandleBulkCreate_Atomic(t *testing.T) {
+	adapter := memory.New()
+	ctx := bulkContext(t, `[{"ID": "a"}, {"ID": "a"}, {"ID": "b"}]`, map[string]string{"atomic": "true"})
+
+	handleBulkCreate(ctx, adapter, TestEntity{}, DefaultConfig())
+
+	require.Equal(t, 409, ctx.Status())
+	require.Equal(t, []int{424, 409, 424}, bulkStatuses(t, ctx))
+	require.Equal(t, int64(0), countOf(t, adapter, TestEntity{}))
+}
+
+// standaloneDB behaves like MongoDB on a standalone server: WithTransaction cannot roll back,
+// and CreateMany writes every item it can and reports the others in a *db.PartialBatchError.
+type standaloneDB struct {
+	*memory.MemoryAdapter
+	inserts int
+}
+
+func (s *standaloneDB) SupportsTransactions() bool { return false }
+
+func (s *standaloneDB) WithTransaction(fn func(tx db.DBAdapter) error) error { return fn(s) }
+
+func (s *standaloneDB) CreateMany(entities []any) error {
+	partial := &db.PartialBatchError{}
+	for i, e := range entities {
+		s.inserts++
+		if err := s.Create(e); err != nil {
+			partial.Errors = append(partial.Errors, &db.BatchError{Index: i, Err: err})
+		}
+	}
+	if len(partial.Errors) > 0 {
+		return partial
+	}
+	return nil
+}
+
+func TestHandleBulkCreate_WithoutTransactions(t *testing.T) {
+	for _, atomic := range []string{"false", "true"} {
+		t.Run("atomic="+atomic, func(t *testing.T) {
+			adapter := &standaloneDB{MemoryAdapter: memory.New()}
+			ctx := bulkContext(t, `[{"ID": "a"}, {"ID": "a"}, {"ID": "b"}]`, map[string]string{"atomic": atomic})
+
+			handleBulkCreate(ctx, adapter, TestEntity{}, DefaultConfig())
+
+			// The written items are reported as such and never inserted again.
+			require.Equal(t, 207, ctx.Status())
+			require.Equal(t, []int{201, 409, 201}, bulkStatuses(t, ctx))
+			require.Equal(t, 3, adapter.inserts)
+			require.Equal(t, int64(2), countOf(t, adapter, TestEntity{}))
+		})
+	}
+}
+
+func TestHandleBulkCreate_AtomicValidation(t *testing.T) {
+	mockDB := new(MockDB)
+	config := DefaultConfig()
+	BulkAtomic()(config)
+	ctx := bulkContext(t, `[{"email": "a@example.com", "name": "Alice"}, {"email": "nope", "name": "Bob"}]`, nil)
+
+	handleBulkCreate(ctx, mockDB, ValidatedEntity{}, config)
+
+	require.Equal(t, 422, ctx.Status())
+	require.Equal(t, []int{424, 422}, bulkStatuses(t, ctx))
+	mockDB.AssertNotCalled(t, "CreateMany", mock.Anything)
+}
+
+func TestHandleBulkCreate_HookErrorRollsBack(t *testing.T) {
+	mockDB := new(MockDB)
+	mockDB.On("CreateMany", mock.Anything).Return(nil)
+	config := DefaultConfig()
+	BulkAtomic()(config)
+	ctx := bulkContext(t, `[{"Name": "a"}, {"Name": "b"}]`, nil)
+
+	handleBulkCreate(ctx, mockDB, HookOnNameEntity{}, config)
+
+	require.Equal(t, 400, ctx.Status())
+	require.Equal(t, []int{424, 400}, bulkStatuses(t, ctx))
+	require.Equal(t, "beforeSave failed: b is reserved", ctx.Resp.(BulkResponse).Results[1].Error.Detail)
+	require.True(t, mockDB.RolledBack)
+}
+
+// HookOnNameEntity fails BeforeCreate for entities named "b".
+type HookOnNameEntity struct {
+	ID   string
+	Name string
+}
+
+func (h *HookOnNameEntity) BeforeCreate() error {
+	if h.Name == "b" {
+		return errors.New("b is reserved")
+	}
+	return nil
+}
+
+func TestHandleBulkCreate_InvalidBody(t *testing.T) {
+	ctx := new(MockContext)
+	ctx.On("BindJSON", mock.Anything).Return(errors.New("cannot unmarshal object"))
+
+	handleBulkCreate(ctx, new(MockDB), TestEntity{}, DefaultConfig())
+	require.Equal(t, 400, ctx.Status())
+
+	empty := bulkContext(t, `[]`, nil)
+	handleBulkCreate(empty, new(MockDB), TestEntity{}, DefaultConfig())
+	require.Equal(t, 400, empty.Status())
+
+	large := bulkContext(t, "["+strings.Repeat(`{},`, maxBulkItems)+"{}]", nil)
+	handleBulkCreate(large, new(MockDB), TestEntity{}, DefaultConfig())
+	require.Equal(t, 413, large.Status())
+}
+
+func TestHandleBulkPatch(t *testing.T) {
+	adapter := memory.New()
+	require.NoError(t, adapter.CreateMany([]any{
+		&VersionedEntity{ID: "a", Name: "Alice"},
+		&VersionedEntity{ID: "b", Name: "Bob"},
+	}))
+
+	ctx := bulkContext(t, `[
+		{"ID": "a", "Name": "Alicia"},
+		{"ID": "b", "Name": "Robert", "Version": 7},
+		{"ID": "missing", "Name": "Nobody"},
+		{"Name": "no id"}
+	]`, nil)
+
+	handleBulkPatch(ctx, adapter, VersionedEntity{}, DefaultConfig())
+
+	require.Equal(t, 207, ctx.Status())
+	require.Equal(t, []int{200, 412, 404, 400}, bulkStatuses(t, ctx))
+	require.Equal(t, 2, ctx.Resp.(BulkResponse).Results[0].Data.(*VersionedEntity).Version)
+
+	found, err := adapter.FindByID("b", &VersionedEntity{})
+	require.NoError(t, err)
+	require.Equal(t, "Bob", found.(*VersionedEntity).Name)
+}
+
+func TestHandleBulkPatch_RequireIfMatch(t *testing.T) {
+	adapter := memory.New()
+	require.NoError(t, adapter.Create(&VersionedEntity{ID: "a", Name: "Alice"}))
+	config := DefaultConfig()
+	RequireIfMatch()(config)
+
+	ctx := bulkContext(t, `[{"ID": "a", "Name": "Alicia"}, {"ID": "a", "Name": "Alicia", "Version": 1}]`, nil)
+	handleBulkPatch(ctx, adapter, VersionedEntity{}, config)
+
+	require.Equal(t, []int{428, 200}, bulkStatuses(t, ctx))
+}
+
+func TestHandleBulkDelete(t *testing.T) {
+	adapter := memory.New()
+	require.NoError(t, adapter.CreateMany([]any{&TestEntity{ID: "a"}, &TestEntity{ID: "b"}, &TestEntity{ID: "c"}}))
+
+	ctx := new(MockContext)
+	ctx.On("Query", "ids").Return("a, missing,b")
+	ctx.On("Query", "atomic").Return("")
+	handleBulkDelete(ctx, adapter, TestEntity{}, DefaultConfig())
+
+	require.Equal(t, 207, ctx.Status())
+	require.Equal(t, []int{204, 404, 204}, bulkStatuses(t, ctx))
+	require.Equal(t, int64(1), countOf(t, adapter, TestEntity{}))
+
+	atomic := new(MockContext)
+	atomic.On("Query", "ids").Return("c,missing")
+	atomic.On("Query", "atomic").Return("true")
+	handleBulkDelete(atomic, adapter, TestEntity{}, DefaultConfig())
+
+	require.Equal(t, 404, atomic.Status())
+	require.Equal(t, []int{424, 404}, bulkStatuses(t, atomic))
+	require.Equal(t, int64(1), countOf(t, adapter, TestEntity{}))
+}
+
+func TestHandleBulkDelete_InvalidIDs(t *testing.T) {
+	for _, ids := range []string{"", " , ", "a,b,a"} {
+		ctx := new(MockContext)
+		ctx.On("Query", "ids").Return(ids)
+		handleBulkDelete(ctx, new(MockDB), TestEntity{}, DefaultConfig())
+		require.Equal(t, 400, ctx.Status(), ids)
+	}
+}
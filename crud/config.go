@@ -1,7 +1,22 @@
 package crud
 
+import "time"
+
 type Config struct {
-	ProtectedMethods map[string]bool
+	ProtectedMethods    map[string]bool
+	Timeout             time.Duration
+	Envelope            bool
+	TotalCount          bool
+	Cursor              bool
+	Filterable          []string
+	Sortable            []string
+	Searchable          []string
+	Aggregatable        []string
+	RequireIfMatch      bool
+	PutCreates          bool
+	Bulk                bool
+	BulkAtomic          bool
+	AllowIncludeDeleted bool
 }
 
 type Option func(*Config)
@@ -21,3 +36,110 @@ func Protect(methods ...string) Option {
 func ProtectAll() Option {
 	return Protect("GET", "POST", "PUT", "PATCH", "DELETE")
 }
+
+// Timeout bounds every database call made by the entity's routes.
+// The deadline is derived from the request context, so client disconnects still cancel earlier.
+func Timeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.Timeout = d
+	}
+}
+
+// Envelope wraps list responses in a ListResponse carrying the total count and
+// links to the neighbouring pages instead of returning a bare array.
+func Envelope() Option {
+	return func(c *Config) {
+		c.Envelope = true
+	}
+}
+
+// TotalCount adds X-Total-Count and RFC 8288 Link headers to list responses.
+func TotalCount() Option {
+	return func(c *Config) {
+		c.TotalCount = true
+	}
+}
+
+// CursorPagination switches list routes from limit/offset to keyset pagination:
+// responses are CursorResponse bodies whose next_cursor is passed back as ?cursor=.
+func CursorPagination() Option {
+	return func(c *Config) {
+		c.Cursor = true
+	}
+}
+
+// Filterable restricts list filters to the given fields (json names).
+// Without it every field exposed in JSON can be filtered on.
+func Filterable(fields ...string) Option {
+	return func(c *Config) {
+		c.Filterable = append(c.Filterable, fields...)
+	}
+}
+
+// Sortable restricts the sort query parameter to the given fields (json names).
+// Without it every field exposed in JSON can be sorted on.
+func Sortable(fields ...string) Option {
+	return func(c *Config) {
+		c.Sortable = append(c.Sortable, fields...)
+	}
+}
+
+// Searchable exposes ?q= on the list route: it keeps the entities where every word of q
+// occurs in one of the given text fields (json names), best matches first unless ?sort= is given.
+func Searchable(fields ...string) Option {
+	return func(c *Config) {
+		c.Searchable = append(c.Searchable, fields...)
+	}
+}
+
+// Aggregatable adds GET /entities/aggregate?group_by=status&metrics=count,sum(total),
+// which groups the entities matching the list filters and computes metrics per group.
+// Only the given fields (json names) can be grouped by or aggregated.
+func Aggregatable(fields ...string) Option {
+	return func(c *Config) {
+		c.Aggregatable = append(c.Aggregatable, fields...)
+	}
+}
+
+// RequireIfMatch rejects PUT, PATCH and DELETE requests on versioned entities that do not
+// send an If-Match header with 428 Precondition Required. Without it the header is optional.
+func RequireIfMatch() Option {
+	return func(c *Config) {
+		c.RequireIfMatch = true
+	}
+}
+
+// PutCreates makes PUT /entities/:id create the entity when the ID does not exist yet,
+// answering 201 instead of 404. The ID is taken from the URL, as for updates.
+func PutCreates() Option {
+	return func(c *Config) {
+		c.PutCreates = true
+	}
+}
+
+// Bulk adds POST, PATCH and DELETE /entities/bulk, which create, patch and delete many
+// entities in one request and report a BulkResult per item. Items are written together;
+// when some fail the others are still written unless the request is all-or-nothing (?atomic=true).
+// The routes are protected like their single-entity counterparts.
+func Bulk() Option {
+	return func(c *Config) {
+		c.Bulk = true
+	}
+}
+
+// BulkAtomic enables the bulk routes and makes every bulk request all-or-nothing:
+// if one item fails, none is written.
+func BulkAtomic() Option {
+	return func(c *Config) {
+		c.Bulk = true
+		c.BulkAtomic = true
+	}
+}
+
+// AllowIncludeDeleted honours ?include_deleted=true for every caller. Without it deleted
+// records are only shown to authenticated callers, so routes that are not protected answer 403.
+func AllowIncludeDeleted() Option {
+	return func(c *Config) {
+		c.AllowIncludeDeleted = true
+	}
+}
@@ -2,6 +2,7 @@ package crud
 
 import (
 	"testing"
+	"time"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -34,3 +35,35 @@ func TestProtectAll(t *testing.T) {
 		}
 	}
 }
+
+func TestTimeout(t *testing.T) {
+	cfg := DefaultConfig()
+	Timeout(5 * time.Second)(cfg)
+
+	if cfg.Timeout != 5*time.Second {
+		t.Errorf("expected timeout 5s, got %v", cfg.Timeout)
+	}
+}
+
+func TestEnvelopeAndTotalCount(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Envelope || cfg.TotalCount {
+		t.Fatal("expected pagination metadata to be opt-in")
+	}
+
+	Envelope()(cfg)
+	TotalCount()(cfg)
+	if !cfg.Envelope || !cfg.TotalCount {
+		t.Errorf("expected Envelope and TotalCount to be enabled, got %+v", cfg)
+	}
+}
+
+func TestFilterableAndSortable(t *testing.T) {
+	cfg := DefaultConfig()
+	Filterable("name", "email")(cfg)
+	Sortable("created_at")(cfg)
+
+	if len(cfg.Filterable) != 2 || cfg.Sortable[0] != "created_at" {
+		t.Errorf("unexpected whitelists: %v %v", cfg.Filterable, cfg.Sortable)
+	}
+}
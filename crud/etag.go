@@ -0,0 +1,62 @@
+package crud
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Lumicrate/gompose/db"
+	gerrors "github.com/Lumicrate/gompose/errors"
+	"github.com/Lumicrate/gompose/http"
+)
+
+// setETag exposes the version of a versioned entity as a strong ETag.
+func setETag(ctx http.Context, entity any) {
+	if db.IsVersioned(entity) {
+		ctx.SetHeader("ETag", formatETag(db.EntityVersion(entity)))
+	}
+}
+
+func formatETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+func parseETag(tag string) (int64, error) {
+	tag = strings.TrimPrefix(tag, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, fmt.Errorf("malformed entity tag %q", tag)
+	}
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("unknown entity tag %q", tag)
+	}
+	return version, nil
+}
+
+// applyIfMatch copies the version named by If-Match onto a versioned entity, so the
+// adapter only writes if the stored version still matches. "*" makes the write
+// unconditional; without the header the version in the entity itself is used.
+func applyIfMatch(ctx http.Context, entity any, config *Config) error {
+	if !db.IsVersioned(entity) {
+		return nil
+	}
+
+	header := strings.TrimSpace(ctx.Header("If-Match"))
+	switch header {
+	case "":
+		if config.RequireIfMatch {
+			return gerrors.PreconditionRequired("If-Match header is required")
+		}
+		return nil
+	case "*":
+		db.SetEntityVersion(entity, 0)
+		return nil
+	}
+
+	version, err := parseETag(header)
+	if err != nil {
+		return gerrors.BadRequest("invalid If-Match: " + err.Error())
+	}
+	db.SetEntityVersion(entity, version)
+	return nil
+}
@@ -0,0 +1,142 @@
+package crud
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Lumicrate/gompose/db"
+)
+
+// lookupField finds the struct field addressed by a query key: its json tag,
+// or its Go name compared case-insensitively and ignoring underscores (created_at → CreatedAt).
+// Fields hidden from JSON with `json:"-"` are never matched.
+func lookupField(t reflect.Type, name string) (reflect.StructField, bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	normalized := strings.ReplaceAll(strings.ToLower(name), "_", "")
+	for _, f := range reflect.VisibleFields(t) {
+		if f.Anonymous || !f.IsExported() {
+			continue
+		}
+		jsonTag := strings.Split(f.Tag.Get("json"), ",")[0]
+		if jsonTag == "-" {
+			continue
+		}
+		if jsonTag == name || strings.ToLower(f.Name) == normalized {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// resolveQueryField maps a filter or sort key from the query string to the Go name of the
+// entity field, which every adapter knows how to translate to its column or document key.
+// When allowed is non-empty the field must also be one of the allowed names.
+func resolveQueryField(entity any, name string, allowed []string) (string, error) {
+	t := reflect.TypeOf(entity)
+	field, ok := lookupField(t, name)
+	if !ok {
+		return "", fmt.Errorf("unknown field %q", name)
+	}
+
+	if len(allowed) == 0 {
+		return field.Name, nil
+	}
+	for _, a := range allowed {
+		if allowedField, ok := lookupField(t, a); ok && allowedField.Name == field.Name {
+			return field.Name, nil
+		}
+	}
+	return "", fmt.Errorf("field %q is not allowed here", name)
+}
+
+// projection is a ?fields= selection.
+type projection struct {
+	fields []string // Go names of the requested fields, as passed to DBAdapter.Select
+	keys   []string // json names kept in responses, including the included relations
+}
+
+// parseFields resolves ?fields=id,name against the json names of entity. Relations are
+// not fields: they are requested with ?include and then always kept in the response.
+// It returns nil when no fields are requested.
+func parseFields(entity any, value, include string) (*projection, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	t := reflect.TypeOf(entity)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	p := &projection{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		field, ok := jsonField(t, name)
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		if _, isRelation := db.RelationByField(t, field.Name); isRelation {
+			return nil, fmt.Errorf("%q is a relation, request it with include", name)
+		}
+		p.fields = append(p.fields, field.Name)
+		p.keys = append(p.keys, name)
+	}
+
+	for _, name := range strings.Split(include, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if field, ok := lookupField(t, name); ok {
+			p.keys = append(p.keys, jsonName(entity, field.Name))
+		}
+	}
+	return p, nil
+}
+
+// jsonField finds the field clients call name: its json tag, or its Go name when it has none.
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for _, f := range reflect.VisibleFields(t) {
+		if f.Anonymous || !f.IsExported() {
+			continue
+		}
+		jsonTag := strings.Split(f.Tag.Get("json"), ",")[0]
+		if jsonTag == "-" {
+			continue
+		}
+		if jsonTag == name || (jsonTag == "" && f.Name == name) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// apply keeps only the projected keys of an entity, or of every entity of a slice.
+func (p *projection) apply(result any) any {
+	v := reflect.Indirect(reflect.ValueOf(result))
+	if v.Kind() != reflect.Slice {
+		return p.applyOne(v)
+	}
+
+	rows := make([]map[string]any, v.Len())
+	for i := range rows {
+		rows[i] = p.applyOne(reflect.Indirect(v.Index(i)))
+	}
+	return rows
+}
+
+func (p *projection) applyOne(v reflect.Value) map[string]any {
+	row := make(map[string]any, len(p.keys))
+	for _, key := range p.keys {
+		field, ok := jsonField(v.Type(), key)
+		if !ok {
+			continue
+		}
+		if value, err := v.FieldByIndexErr(field.Index); err == nil {
+			row[key] = value.Interface()
+		}
+	}
+	return row
+}
@@ -2,7 +2,10 @@ package crud
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/Lumicrate/gompose/db"
+	gerrors "github.com/Lumicrate/gompose/errors"
 	"github.com/Lumicrate/gompose/hooks"
 	"github.com/Lumicrate/gompose/http"
 	"reflect"
@@ -10,10 +13,21 @@ import (
 	"strings"
 )
 
-func handleGetAll(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
-	filters := map[string]any{}
+func handleGetAll(ctx http.Context, dbAdapter db.DBAdapter, entity any, config *Config) {
+	handleList(ctx, dbAdapter, entity, config, nil)
+}
+
+// handleList serves a list of entity restricted to the scope filters on top of the
+// filters, pagination and sort given in the query.
+func handleList(ctx http.Context, dbAdapter db.DBAdapter, entity any, config *Config, scope []db.Filter) {
+	filters := append([]db.Filter{}, scope...)
 	pagination := db.Pagination{Limit: 10, Offset: 0} // default pagination
 	sort := []db.Sort{}
+	cursor := ""
+	includeDeleted := false
+	include := ""
+	fields := ""
+	search := ""
 
 	// parse filters, pagination and sort from query params
 	for key, vals := range ctx.QueryParams() {
@@ -27,6 +41,14 @@ func handleGetAll(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
 			if o, err := strconv.Atoi(val); err == nil {
 				pagination.Offset = o
 			}
+		case "cursor":
+			cursor = val
+		case "include_deleted":
+			includeDeleted, _ = strconv.ParseBool(val)
+		case "include":
+			include = val
+		case "fields":
+			fields = val
 		case "sort":
 			// example: sort=name,-created_at
 			fields := strings.Split(val, ",")
@@ -36,25 +58,229 @@ func handleGetAll(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
 					direction = "desc"
 					f = strings.TrimPrefix(f, "-")
 				}
-				sort = append(sort, db.Sort{Field: f, Direction: direction})
+				field, err := resolveQueryField(entity, f, config.Sortable)
+				if err != nil {
+					gerrors.Write(ctx, gerrors.BadRequest("invalid sort: "+err.Error()))
+					return
+				}
+				sort = append(sort, db.Sort{Field: field, Direction: direction})
 			}
+		case "q":
+			if len(config.Searchable) > 0 {
+				search = val
+				continue
+			}
+			// Without Searchable, q is an ordinary filter key.
+			fallthrough
 		default:
-			filters[key] = val
+			filter, err := parseQueryFilter(entity, key, val, config)
+			if err != nil {
+				gerrors.Write(ctx, err)
+				return
+			}
+			filters = append(filters, filter)
+		}
+	}
+
+	dbAdapter, err := withDeleted(ctx, dbAdapter, entity, includeDeleted, config)
+	if err != nil {
+		gerrors.Write(ctx, err)
+		return
+	}
+
+	if dbAdapter, err = withIncludes(dbAdapter, entity, include); err != nil {
+		gerrors.Write(ctx, err)
+		return
+	}
+
+	if strings.TrimSpace(search) != "" {
+		if dbAdapter, err = withSearch(dbAdapter, entity, search, config.Searchable); err != nil {
+			gerrors.Write(ctx, err)
+			return
+		}
+	}
+
+	proj, err := parseFields(entity, fields, include)
+	if err != nil {
+		gerrors.Write(ctx, gerrors.BadRequest("invalid fields: "+err.Error()))
+		return
+	}
+	if proj != nil {
+		// Sort keys are loaded too: the cursor of the next page is built from them.
+		selected := append([]string{}, proj.fields...)
+		for _, s := range sort {
+			selected = append(selected, s.Field)
 		}
+		dbAdapter = dbAdapter.Select(selected...)
+	}
+
+	if config.Cursor {
+		handleGetAllCursor(ctx, dbAdapter, entity, config, filters, pagination, sort, cursor, proj)
+		return
 	}
 
 	result, err := dbAdapter.FindAll(entity, filters, pagination, sort)
 	if err != nil {
-		ctx.JSON(500, map[string]string{"error": err.Error()})
+		gerrors.Write(ctx, err)
+		return
+	}
+	if proj != nil {
+		result = proj.apply(result)
+	}
+
+	if !config.Envelope && !config.TotalCount {
+		ctx.JSON(200, result)
+		return
+	}
+
+	total, err := dbAdapter.Count(entity, filters)
+	if err != nil {
+		gerrors.Write(ctx, err)
 		return
 	}
+	links := buildPageLinks(ctx, pagination, total)
 
-	ctx.JSON(200, result)
+	if config.TotalCount {
+		ctx.SetHeader("X-Total-Count", strconv.FormatInt(total, 10))
+		if header := links.linkHeader(); header != "" {
+			ctx.SetHeader("Link", header)
+		}
+	}
+
+	if !config.Envelope {
+		ctx.JSON(200, result)
+		return
+	}
+
+	ctx.JSON(200, ListResponse{
+		Data:   result,
+		Total:  total,
+		Limit:  pagination.Limit,
+		Offset: pagination.Offset,
+		Next:   links.next,
+		Prev:   links.prev,
+	})
+}
+
+// parseQueryFilter parses a filter query parameter such as age[gte]=18, status[in]=a,b
+// or name[like]=jo% against the filterable fields of entity.
+func parseQueryFilter(entity any, key, val string, config *Config) (db.Filter, error) {
+	filter, err := db.ParseFilter(key, val)
+	if err != nil {
+		return db.Filter{}, gerrors.BadRequest(err.Error())
+	}
+	if filter.Field, err = resolveQueryField(entity, filter.Field, config.Filterable); err != nil {
+		return db.Filter{}, gerrors.BadRequest("invalid filter: " + err.Error())
+	}
+	return filter, nil
 }
 
-func handleGetByID(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
+// handleGetAllCursor serves a keyset page: the id is appended as a tie-breaker so the
+// sort order is total, and one extra row is fetched to know whether another page exists.
+func handleGetAllCursor(ctx http.Context, dbAdapter db.DBAdapter, entity any, config *Config,
+	filters []db.Filter, pagination db.Pagination, sort []db.Sort, cursor string, proj *projection) {
+	hasID := false
+	for _, s := range sort {
+		hasID = hasID || s.Field == "ID"
+	}
+	if !hasID {
+		sort = append(sort, db.Sort{Field: "ID", Direction: "asc"})
+	}
+
+	if cursor != "" {
+		after, err := decodeCursor(cursor, len(sort))
+		if err != nil {
+			gerrors.Write(ctx, gerrors.BadRequest(err.Error()))
+			return
+		}
+		pagination.After = after
+	}
+
+	limit := pagination.Limit
+	if limit > 0 {
+		pagination.Limit = limit + 1
+	}
+
+	result, err := dbAdapter.FindAll(entity, filters, pagination, sort)
+	if err != nil {
+		gerrors.Write(ctx, err)
+		return
+	}
+
+	rows := reflect.ValueOf(result)
+	var nextCursor *string
+	if limit > 0 && rows.Len() > limit {
+		rows = rows.Slice(0, limit)
+		values, err := sortKeyValues(rows.Index(limit-1), sort)
+		if err != nil {
+			gerrors.Write(ctx, err)
+			return
+		}
+		encoded, err := encodeCursor(values)
+		if err != nil {
+			gerrors.Write(ctx, err)
+			return
+		}
+		nextCursor = &encoded
+	}
+
+	if config.TotalCount {
+		total, err := dbAdapter.Count(entity, filters)
+		if err != nil {
+			gerrors.Write(ctx, err)
+			return
+		}
+		ctx.SetHeader("X-Total-Count", strconv.FormatInt(total, 10))
+	}
+
+	data := rows.Interface()
+	if proj != nil {
+		data = proj.apply(data)
+	}
+
+	ctx.JSON(200, CursorResponse{
+		Data:       data,
+		Limit:      limit,
+		NextCursor: nextCursor,
+	})
+}
+
+func handleGetByID(ctx http.Context, dbAdapter db.DBAdapter, entity any, config *Config) {
 	id := ctx.Param("id")
 
+	if db.IsSoftDeletable(entity) {
+		includeDeleted, _ := strconv.ParseBool(ctx.Query("include_deleted"))
+		var err error
+		if dbAdapter, err = withDeleted(ctx, dbAdapter, entity, includeDeleted, config); err != nil {
+			gerrors.Write(ctx, err)
+			return
+		}
+	}
+
+	include := ""
+	if len(db.Relations(reflect.TypeOf(entity))) > 0 {
+		include = ctx.Query("include")
+		var err error
+		if dbAdapter, err = withIncludes(dbAdapter, entity, include); err != nil {
+			gerrors.Write(ctx, err)
+			return
+		}
+	}
+
+	proj, err := parseFields(entity, ctx.Query("fields"), include)
+	if err != nil {
+		gerrors.Write(ctx, gerrors.BadRequest("invalid fields: "+err.Error()))
+		return
+	}
+	if proj != nil {
+		selected := proj.fields
+		if db.IsVersioned(entity) {
+			// The version is still needed for the ETag.
+			selected = append(selected, db.VersionField)
+		}
+		dbAdapter = dbAdapter.Select(selected...)
+	}
+
 	t := reflect.TypeOf(entity)
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
@@ -63,10 +289,15 @@ func handleGetByID(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
 
 	found, err := dbAdapter.FindByID(id, newEntity)
 	if err != nil {
-		ctx.JSON(404, map[string]string{"error": "entity not found"})
+		gerrors.Write(ctx, err)
 		return
 	}
 
+	setETag(ctx, found)
+	if proj != nil {
+		ctx.JSON(200, proj.apply(found))
+		return
+	}
 	ctx.JSON(200, found)
 }
 
@@ -78,32 +309,42 @@ func handleCreate(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
 	newEntity := reflect.New(t).Interface()
 
 	if err := ctx.Bind(newEntity); err != nil {
-		ctx.JSON(400, map[string]string{"error": "invalid input" + err.Error()})
+		gerrors.Write(ctx, gerrors.BadRequest("invalid input: "+err.Error()))
 		return
 	}
 
-	if hook, ok := newEntity.(hooks.BeforeCreate); ok {
-		if err := hook.BeforeCreate(); err != nil {
-			ctx.JSON(400, map[string]string{"error": "beforeSave failed: " + err.Error()})
-			return
-		}
-	}
-
-	if err := dbAdapter.Create(newEntity); err != nil {
-		ctx.JSON(500, map[string]string{"error": err.Error()})
+	if !validateRequest(ctx, newEntity) {
 		return
 	}
 
-	if hook, ok := newEntity.(hooks.AfterCreate); ok {
-		if err := hook.AfterCreate(); err != nil {
-			ctx.JSON(400, map[string]string{"error": "afterSave failed: " + err.Error()})
+	err := dbAdapter.WithTransaction(func(tx db.DBAdapter) error {
+		if hook, ok := newEntity.(hooks.BeforeCreate); ok {
+			if err := hook.BeforeCreate(); err != nil {
+				return &hookError{hook: "beforeSave", err: err}
+			}
+		}
+
+		if err := tx.Create(newEntity); err != nil {
+			return err
+		}
+
+		if hook, ok := newEntity.(hooks.AfterCreate); ok {
+			if err := hook.AfterCreate(); err != nil {
+				return &hookError{hook: "afterSave", err: err}
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		writeTxError(ctx, err)
+		return
 	}
 
+	setETag(ctx, newEntity)
 	ctx.JSON(201, newEntity)
 }
 
-func handleUpdate(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
+func handleUpdate(ctx http.Context, dbAdapter db.DBAdapter, entity any, config *Config) {
 	id := ctx.Param("id")
 	t := reflect.TypeOf(entity)
 	if t.Kind() == reflect.Ptr {
@@ -112,36 +353,64 @@ func handleUpdate(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
 	updatedEntity := reflect.New(t).Interface()
 
 	if err := ctx.Bind(updatedEntity); err != nil {
-		ctx.JSON(400, map[string]string{"error": "invalid input"})
+		gerrors.Write(ctx, gerrors.BadRequest("invalid input: "+err.Error()))
 		return
 	}
 
 	// Set the ID field in the updated entity to the URL param id if field exists
-	setEntityID(updatedEntity, id)
+	if err := setEntityID(updatedEntity, id); err != nil {
+		gerrors.Write(ctx, err)
+		return
+	}
 
-	if hook, ok := updatedEntity.(hooks.BeforeUpdate); ok {
-		if err := hook.BeforeUpdate(); err != nil {
-			ctx.JSON(400, map[string]string{"error": "beforeUpdate failed: " + err.Error()})
-			return
-		}
+	if !validateRequest(ctx, updatedEntity) {
+		return
 	}
 
-	if err := dbAdapter.Update(updatedEntity); err != nil {
-		ctx.JSON(500, map[string]string{"error": err.Error()})
+	if err := applyIfMatch(ctx, updatedEntity, config); err != nil {
+		gerrors.Write(ctx, err)
 		return
 	}
 
-	if hook, ok := updatedEntity.(hooks.AfterUpdate); ok {
-		if err := hook.AfterUpdate(); err != nil {
-			ctx.JSON(400, map[string]string{"error": "afterUpdate failed: " + err.Error()})
-			return
+	created := false
+	err := dbAdapter.WithTransaction(func(tx db.DBAdapter) error {
+		if hook, ok := updatedEntity.(hooks.BeforeUpdate); ok {
+			if err := hook.BeforeUpdate(); err != nil {
+				return &hookError{hook: "beforeUpdate", err: err}
+			}
+		}
+
+		var err error
+		if config.PutCreates {
+			created, err = tx.Upsert(updatedEntity)
+		} else {
+			err = tx.Update(updatedEntity)
 		}
+		if err != nil {
+			return err
+		}
+
+		if hook, ok := updatedEntity.(hooks.AfterUpdate); ok {
+			if err := hook.AfterUpdate(); err != nil {
+				return &hookError{hook: "afterUpdate", err: err}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		writeTxError(ctx, err)
+		return
 	}
 
+	setETag(ctx, updatedEntity)
+	if created {
+		ctx.JSON(201, updatedEntity)
+		return
+	}
 	ctx.JSON(200, updatedEntity)
 }
 
-func handlePatch(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
+func handlePatch(ctx http.Context, dbAdapter db.DBAdapter, entity any, config *Config) {
 	id := ctx.Param("id")
 	t := reflect.TypeOf(entity)
 	if t.Kind() == reflect.Ptr {
@@ -151,45 +420,59 @@ func handlePatch(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
 
 	found, err := dbAdapter.FindByID(id, existingEntity)
 	if err != nil {
-		ctx.JSON(404, map[string]string{"error": "entity not found"})
+		gerrors.Write(ctx, err)
 		return
 	}
 
 	patchData := map[string]interface{}{}
 	if err := ctx.BindJSON(&patchData); err != nil {
-		ctx.JSON(400, map[string]string{"error": "invalid patch data"})
+		gerrors.Write(ctx, gerrors.BadRequest("invalid patch data: "+err.Error()))
 		return
 	}
 
 	patchBytes, _ := json.Marshal(patchData)
 	if err := json.Unmarshal(patchBytes, &found); err != nil {
-		ctx.JSON(500, map[string]string{"error": err.Error()})
+		gerrors.Write(ctx, gerrors.BadRequest("invalid patch data: "+err.Error()))
 		return
 	}
 
-	if hook, ok := existingEntity.(hooks.BeforePatch); ok {
-		if err := hook.BeforePatch(); err != nil {
-			ctx.JSON(400, map[string]string{"error": "beforePatch failed: " + err.Error()})
-			return
-		}
+	if !validateRequest(ctx, found) {
+		return
 	}
 
-	if err := dbAdapter.Update(found); err != nil {
-		ctx.JSON(500, map[string]string{"error": err.Error()})
+	if err := applyIfMatch(ctx, found, config); err != nil {
+		gerrors.Write(ctx, err)
 		return
 	}
 
-	if hook, ok := existingEntity.(hooks.AfterPatch); ok {
-		if err := hook.AfterPatch(); err != nil {
-			ctx.JSON(400, map[string]string{"error": "afterPatch failed: " + err.Error()})
-			return
+	err = dbAdapter.WithTransaction(func(tx db.DBAdapter) error {
+		if hook, ok := existingEntity.(hooks.BeforePatch); ok {
+			if err := hook.BeforePatch(); err != nil {
+				return &hookError{hook: "beforePatch", err: err}
+			}
+		}
+
+		if err := tx.Update(found); err != nil {
+			return err
+		}
+
+		if hook, ok := existingEntity.(hooks.AfterPatch); ok {
+			if err := hook.AfterPatch(); err != nil {
+				return &hookError{hook: "afterPatch", err: err}
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		writeTxError(ctx, err)
+		return
 	}
 
+	setETag(ctx, found)
 	ctx.JSON(200, found)
 }
 
-func handleDelete(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
+func handleDelete(ctx http.Context, dbAdapter db.DBAdapter, entity any, config *Config) {
 	id := ctx.Param("id")
 
 	t := reflect.TypeOf(entity)
@@ -198,29 +481,104 @@ func handleDelete(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
 	}
 	toDeleteEntity := reflect.New(t).Interface()
 
-	if hook, ok := toDeleteEntity.(hooks.BeforeDelete); ok {
-		if err := hook.BeforeDelete(); err != nil {
-			ctx.JSON(400, map[string]string{"error": "beforeDelete failed: " + err.Error()})
-			return
+	if err := applyIfMatch(ctx, toDeleteEntity, config); err != nil {
+		gerrors.Write(ctx, err)
+		return
+	}
+
+	err := dbAdapter.WithTransaction(func(tx db.DBAdapter) error {
+		if hook, ok := toDeleteEntity.(hooks.BeforeDelete); ok {
+			if err := hook.BeforeDelete(); err != nil {
+				return &hookError{hook: "beforeDelete", err: err}
+			}
+		}
+
+		if err := tx.Delete(id, toDeleteEntity); err != nil {
+			return err
 		}
+
+		if hook, ok := toDeleteEntity.(hooks.AfterDelete); ok {
+			if err := hook.AfterDelete(); err != nil {
+				return &hookError{hook: "afterDelete", err: err}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		writeTxError(ctx, err)
+		return
 	}
 
-	if err := dbAdapter.Delete(id, toDeleteEntity); err != nil {
-		ctx.JSON(500, map[string]string{"error": err.Error()})
+	ctx.JSON(204, nil)
+}
+
+func handleRestore(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
+	id := ctx.Param("id")
+
+	t := reflect.TypeOf(entity)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	restoredEntity := reflect.New(t).Interface()
+
+	if err := dbAdapter.Restore(id, restoredEntity); err != nil {
+		gerrors.Write(ctx, err)
 		return
 	}
 
-	if hook, ok := toDeleteEntity.(hooks.AfterDelete); ok {
-		if err := hook.AfterDelete(); err != nil {
-			ctx.JSON(400, map[string]string{"error": "afterDelete failed: " + err.Error()})
-			return
-		}
+	found, err := dbAdapter.FindByID(id, restoredEntity)
+	if err != nil {
+		gerrors.Write(ctx, err)
+		return
 	}
 
-	ctx.JSON(204, nil)
+	setETag(ctx, found)
+	ctx.JSON(200, found)
+}
+
+// withDeleted honours ?include_deleted=true for soft-deletable entities. Deleted records
+// are only shown to authenticated callers, unless the config allows them for everyone.
+func withDeleted(ctx http.Context, dbAdapter db.DBAdapter, entity any, include bool, config *Config) (db.DBAdapter, error) {
+	if !include || !db.IsSoftDeletable(entity) {
+		return dbAdapter, nil
+	}
+	if !config.AllowIncludeDeleted && ctx.Get("user_id") == nil {
+		return nil, gerrors.Forbidden("include_deleted requires an authenticated caller")
+	}
+	return dbAdapter.IncludeDeleted(), nil
+}
+
+// hookError marks a failure returned by an entity hook, as opposed to a database error.
+type hookError struct {
+	hook string
+	err  error
 }
 
-func setEntityID(entity any, id string) {
+func (e *hookError) Error() string {
+	return e.hook + " failed: " + e.err.Error()
+}
+
+func (e *hookError) Unwrap() error {
+	return e.err
+}
+
+// writeTxError reports a failed transaction.
+func writeTxError(ctx http.Context, err error) {
+	gerrors.Write(ctx, txError(err))
+}
+
+// txError makes a hook failure a client error unless the hook returned a gompose
+// error carrying its own status.
+func txError(err error) error {
+	var hErr *hookError
+	var gErr *gerrors.Error
+	if errors.As(err, &hErr) && !errors.As(err, &gErr) {
+		return gerrors.BadRequest(hErr.Error())
+	}
+	return err
+}
+
+func setEntityID(entity any, id string) error {
 	v := reflect.ValueOf(entity)
 	if v.Kind() == reflect.Ptr {
 		v = v.Elem()
@@ -228,17 +586,20 @@ func setEntityID(entity any, id string) {
 
 	field := v.FieldByName("ID")
 	if !field.IsValid() || !field.CanSet() {
-		return
+		return nil
 	}
 
 	switch field.Kind() {
 	case reflect.String:
 		field.SetString(id)
 	case reflect.Int, reflect.Int64, reflect.Int32:
-		if intVal, err := strconv.ParseInt(id, 10, 64); err == nil {
-			field.SetInt(intVal)
+		intVal, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return gerrors.BadRequest(fmt.Sprintf("invalid id %q", id))
 		}
+		field.SetInt(intVal)
 	default:
-		panic("ID type not supported")
+		return fmt.Errorf("ID type %s not supported", field.Type())
 	}
+	return nil
 }
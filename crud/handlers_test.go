@@ -1,11 +1,16 @@
 package crud
 
 import (
+	"context"
 	"errors"
 	"github.com/Lumicrate/gompose/db"
+	"github.com/Lumicrate/gompose/db/memory"
+	gerrors "github.com/Lumicrate/gompose/errors"
 	"testing"
+	"time"
 
 	"net/http"
+	"reflect"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -15,6 +20,11 @@ import (
 
 type MockDB struct {
 	mock.Mock
+	RolledBack  bool
+	WithDeleted bool
+	Preloaded   []string
+	Selected    []string
+	Searched    []string // the query followed by the fields
 }
 
 func (m *MockDB) Init() error {
@@ -27,6 +37,48 @@ func (m *MockDB) Migrate(entities []any) error {
 	return args.Error(0)
 }
 
+func (m *MockDB) Ping() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockDB) Close() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockDB) WithContext(ctx context.Context) db.DBAdapter {
+	return m
+}
+
+func (m *MockDB) WithTransaction(fn func(tx db.DBAdapter) error) error {
+	if err := fn(m); err != nil {
+		m.RolledBack = true
+		return err
+	}
+	return nil
+}
+
+func (m *MockDB) IncludeDeleted() db.DBAdapter {
+	m.WithDeleted = true
+	return m
+}
+
+func (m *MockDB) Preload(relations ...string) db.DBAdapter {
+	m.Preloaded = append(m.Preloaded, relations...)
+	return m
+}
+
+func (m *MockDB) Select(fields ...string) db.DBAdapter {
+	m.Selected = append(m.Selected, fields...)
+	return m
+}
+
+func (m *MockDB) Search(query string, fields ...string) db.DBAdapter {
+	m.Searched = append([]string{query}, fields...)
+	return m
+}
+
 func (m *MockDB) Create(entity any) error {
 	args := m.Called(entity)
 	return args.Error(0)
@@ -42,7 +94,32 @@ func (m *MockDB) Delete(id string, entity any) error {
 	return args.Error(0)
 }
 
-func (m *MockDB) FindAll(entity any, filters map[string]any, pagination db.Pagination, sort []db.Sort) (any, error) {
+func (m *MockDB) Restore(id string, entity any) error {
+	args := m.Called(id, entity)
+	return args.Error(0)
+}
+
+func (m *MockDB) Upsert(entity any) (bool, error) {
+	args := m.Called(entity)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockDB) CreateMany(entities []any) error {
+	args := m.Called(entities)
+	return args.Error(0)
+}
+
+func (m *MockDB) UpdateMany(entities []any) error {
+	args := m.Called(entities)
+	return args.Error(0)
+}
+
+func (m *MockDB) DeleteMany(ids []string, entity any) error {
+	args := m.Called(ids, entity)
+	return args.Error(0)
+}
+
+func (m *MockDB) FindAll(entity any, filters []db.Filter, pagination db.Pagination, sort []db.Sort) (any, error) {
 	args := m.Called(entity, filters, pagination, sort)
 	return args.Get(0), args.Error(1)
 }
@@ -52,12 +129,23 @@ func (m *MockDB) FindByID(id string, entity any) (any, error) {
 	return args.Get(0), args.Error(1)
 }
 
+func (m *MockDB) Count(entity any, filters []db.Filter) (int64, error) {
+	args := m.Called(entity, filters)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockDB) Aggregate(entity any, filters []db.Filter, groupBy []string, metrics []db.Metric) ([]db.AggregateRow, error) {
+	args := m.Called(entity, filters, groupBy, metrics)
+	return args.Get(0).([]db.AggregateRow), args.Error(1)
+}
+
 // Mock Context
 
 type MockContext struct {
 	mock.Mock
-	status int
-	Resp   any
+	status  int
+	Resp    any
+	Headers map[string]string
 }
 
 func (m *MockContext) JSON(code int, obj any) {
@@ -91,7 +179,10 @@ func (m *MockContext) BindJSON(obj any) error {
 }
 
 func (m *MockContext) SetHeader(key, value string) {
-	m.Called(key, value)
+	if m.Headers == nil {
+		m.Headers = map[string]string{}
+	}
+	m.Headers[key] = value
 }
 
 func (m *MockContext) Method() string {
@@ -149,11 +240,40 @@ func (m *MockContext) Request() *http.Request {
 	return args.Get(0).(*http.Request)
 }
 
+func (m *MockContext) Context() context.Context {
+	return context.Background()
+}
+
 type TestEntity struct {
 	ID   string
 	Name string
 }
 
+type SoftEntity struct {
+	ID        string
+	Name      string
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+type VersionedEntity struct {
+	ID      string
+	Name    string
+	Version int
+}
+
+type Author struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Books []Book `json:"books"`
+}
+
+type Book struct {
+	ID       string  `json:"id"`
+	Title    string  `json:"title"`
+	AuthorID string  `json:"author_id"`
+	Author   *Author `json:"author,omitempty"`
+}
+
 type HookEntity struct {
 	TestEntity
 	BeforeCreateErr error
@@ -188,7 +308,7 @@ func TestHandleGetAll_Success(t *testing.T) {
 
 	mockCtx.On("QueryParams").Return(map[string][]string{})
 
-	handleGetAll(mockCtx, mockDB, TestEntity{})
+	handleGetAll(mockCtx, mockDB, TestEntity{}, DefaultConfig())
 
 	require.Equal(t, 200, mockCtx.Status())
 	require.Equal(t, expected, mockCtx.Resp)
@@ -205,14 +325,273 @@ func TestHandleGetAll_Error(t *testing.T) {
 
 	mockCtx.On("QueryParams").Return(map[string][]string{})
 
-	handleGetAll(mockCtx, mockDB, TestEntity{})
+	handleGetAll(mockCtx, mockDB, TestEntity{}, DefaultConfig())
 
 	require.Equal(t, 500, mockCtx.Status())
-	require.Contains(t, mockCtx.Resp.(map[string]string)["error"], "db error")
+	require.Equal(t, "internal server error", mockCtx.Resp.(gerrors.Problem).Detail)
+	require.Equal(t, gerrors.ProblemContentType, mockCtx.Headers["Content-Type"])
+}
+
+func TestHandleGetAll_FilterOperators(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	expectedFilters := []db.Filter{{Field: "Name", Operator: db.OpLike, Value: "A%"}}
+	mockDB.On("FindAll", mock.Anything, expectedFilters, mock.Anything, mock.Anything).
+		Return([]TestEntity{}, nil)
+
+	mockCtx.On("QueryParams").Return(map[string][]string{"name[like]": {"A%"}})
+
+	handleGetAll(mockCtx, mockDB, TestEntity{}, DefaultConfig())
+
+	require.Equal(t, 200, mockCtx.Status())
+	mockDB.AssertExpectations(t)
+}
+
+func TestHandleGetAll_InvalidFilterOperator(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	mockCtx.On("QueryParams").Return(map[string][]string{"name[regex]": {".*"}})
+
+	handleGetAll(mockCtx, mockDB, TestEntity{}, DefaultConfig())
+
+	require.Equal(t, 400, mockCtx.Status())
+	mockDB.AssertNotCalled(t, "FindAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandleGetAll_UnknownFieldsRejected(t *testing.T) {
+	for _, query := range []map[string][]string{
+		{"name; DROP TABLE users": {"x"}},
+		{"sort": {"name desc, (SELECT 1)"}},
+		{"sort": {"-missing"}},
+	} {
+		mockDB := new(MockDB)
+		mockCtx := new(MockContext)
+		mockCtx.On("QueryParams").Return(query)
+
+		handleGetAll(mockCtx, mockDB, TestEntity{}, DefaultConfig())
+
+		require.Equal(t, 400, mockCtx.Status(), "query %v", query)
+		mockDB.AssertNotCalled(t, "FindAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	}
+}
+
+func TestHandleGetAll_Whitelist(t *testing.T) {
+	cfg := DefaultConfig()
+	Filterable("name")(cfg)
+	Sortable("name")(cfg)
+
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+	mockCtx.On("QueryParams").Return(map[string][]string{"id": {"1"}})
+
+	handleGetAll(mockCtx, mockDB, TestEntity{}, cfg)
+	require.Equal(t, 400, mockCtx.Status())
+
+	mockDB = new(MockDB)
+	mockCtx = new(MockContext)
+	mockCtx.On("QueryParams").Return(map[string][]string{"name": {"Bob"}, "sort": {"-name"}})
+	mockDB.On("FindAll", mock.Anything, []db.Filter{db.Eq("Name", "Bob")}, mock.Anything,
+		[]db.Sort{{Field: "Name", Direction: "desc"}}).Return([]TestEntity{}, nil)
+
+	handleGetAll(mockCtx, mockDB, TestEntity{}, cfg)
+	require.Equal(t, 200, mockCtx.Status())
+	mockDB.AssertExpectations(t)
+}
+
+func TestHandleGetAll_Envelope(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	items := []TestEntity{{ID: "11", Name: "K"}, {ID: "12", Name: "L"}}
+	mockDB.On("FindAll", mock.Anything, mock.Anything, db.Pagination{Limit: 2, Offset: 10}, mock.Anything).
+		Return(items, nil)
+	mockDB.On("Count", mock.Anything, mock.Anything).Return(int64(25), nil)
+
+	mockCtx.On("QueryParams").Return(map[string][]string{"limit": {"2"}, "offset": {"10"}})
+	mockCtx.On("Path").Return("/testentities")
+
+	cfg := DefaultConfig()
+	Envelope()(cfg)
+	handleGetAll(mockCtx, mockDB, TestEntity{}, cfg)
+
+	require.Equal(t, 200, mockCtx.Status())
+	resp := mockCtx.Resp.(ListResponse)
+	require.Equal(t, items, resp.Data)
+	require.Equal(t, int64(25), resp.Total)
+	require.Equal(t, "/testentities?limit=2&offset=12", *resp.Next)
+	require.Equal(t, "/testentities?limit=2&offset=8", *resp.Prev)
+}
+
+func TestHandleGetAll_TotalCountHeaders(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	items := []TestEntity{{ID: "1", Name: "A"}}
+	mockDB.On("FindAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(items, nil)
+	mockDB.On("Count", mock.Anything, mock.Anything).Return(int64(3), nil)
+
+	mockCtx.On("QueryParams").Return(map[string][]string{"limit": {"1"}})
+	mockCtx.On("Path").Return("/testentities")
+	cfg := DefaultConfig()
+	TotalCount()(cfg)
+	handleGetAll(mockCtx, mockDB, TestEntity{}, cfg)
+
+	require.Equal(t, 200, mockCtx.Status())
+	require.Equal(t, items, mockCtx.Resp)
+	require.Equal(t, "3", mockCtx.Headers["X-Total-Count"])
+	require.Equal(t, `</testentities?limit=1&offset=1>; rel="next", `+
+		`</testentities?limit=1&offset=0>; rel="first", </testentities?limit=1&offset=2>; rel="last"`,
+		mockCtx.Headers["Link"])
+}
+
+func TestHandleGetAll_Cursor(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	rows := []TestEntity{{ID: "1", Name: "A"}, {ID: "2", Name: "B"}, {ID: "3", Name: "C"}}
+	sortByID := []db.Sort{{Field: "ID", Direction: "asc"}}
+	mockDB.On("FindAll", mock.Anything, mock.Anything, db.Pagination{Limit: 3}, sortByID).
+		Return(rows, nil)
+
+	mockCtx.On("QueryParams").Return(map[string][]string{"limit": {"2"}})
+
+	cfg := DefaultConfig()
+	CursorPagination()(cfg)
+	handleGetAll(mockCtx, mockDB, TestEntity{}, cfg)
+
+	require.Equal(t, 200, mockCtx.Status())
+	resp := mockCtx.Resp.(CursorResponse)
+	require.Equal(t, rows[:2], resp.Data)
+	require.NotNil(t, resp.NextCursor)
+
+	// the cursor resumes after the last returned row
+	after, err := decodeCursor(*resp.NextCursor, 1)
+	require.NoError(t, err)
+	require.Equal(t, []any{"2"}, after)
+
+	mockDB2 := new(MockDB)
+	mockCtx2 := new(MockContext)
+	mockDB2.On("FindAll", mock.Anything, mock.Anything, db.Pagination{Limit: 3, After: []any{"2"}}, sortByID).
+		Return(rows[2:], nil)
+	mockCtx2.On("QueryParams").Return(map[string][]string{"limit": {"2"}, "cursor": {*resp.NextCursor}})
+
+	handleGetAll(mockCtx2, mockDB2, TestEntity{}, cfg)
+
+	require.Equal(t, 200, mockCtx2.Status())
+	resp = mockCtx2.Resp.(CursorResponse)
+	require.Equal(t, rows[2:], resp.Data)
+	require.Nil(t, resp.NextCursor)
+}
+
+func TestHandleGetAll_InvalidCursor(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	mockCtx.On("QueryParams").Return(map[string][]string{"cursor": {"not-a-cursor"}})
+
+	cfg := DefaultConfig()
+	CursorPagination()(cfg)
+	handleGetAll(mockCtx, mockDB, TestEntity{}, cfg)
+
+	require.Equal(t, 400, mockCtx.Status())
+}
+
+func TestHandleGetAll_Search(t *testing.T) {
+	adapter := memory.New()
+	require.NoError(t, adapter.CreateMany([]any{
+		&ValidatedEntity{ID: "1", Email: "go@example.com", Name: "Gopher"},
+		&ValidatedEntity{ID: "2", Email: "rust@example.com", Name: "Ferris"},
+		&ValidatedEntity{ID: "3", Email: "pike@example.com", Name: "Go Go Go"},
+	}))
+
+	mockCtx := new(MockContext)
+	mockCtx.On("QueryParams").Return(map[string][]string{"q": {"go"}})
+	mockCtx.On("Path").Return("/validatedentities")
+
+	cfg := DefaultConfig()
+	Searchable("name", "email")(cfg)
+	Envelope()(cfg)
+	handleGetAll(mockCtx, adapter, ValidatedEntity{}, cfg)
+
+	require.Equal(t, 200, mockCtx.Status())
+	resp := mockCtx.Resp.(ListResponse)
+	require.Equal(t, int64(2), resp.Total)
+	entities := resp.Data.([]ValidatedEntity)
+	require.Equal(t, "3", entities[0].ID, "best match first")
+	require.Equal(t, "1", entities[1].ID)
+}
+
+func TestHandleGetAll_SearchNotEnabled(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+	mockCtx.On("QueryParams").Return(map[string][]string{"q": {"go"}})
+
+	handleGetAll(mockCtx, mockDB, ValidatedEntity{}, DefaultConfig())
+
+	require.Equal(t, 400, mockCtx.Status())
+	require.Nil(t, mockDB.Searched)
+
+	mockDB = new(MockDB)
+	mockDB.On("FindAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]ValidatedEntity{}, nil)
+	cfg := DefaultConfig()
+	Searchable("name")(cfg)
+	handleGetAll(mockCtx, mockDB, ValidatedEntity{}, cfg)
+
+	require.Equal(t, []string{"go", "Name"}, mockDB.Searched)
 }
 
 // handleGetByID
 
+func TestHandleGetAll_Fields(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	mockDB.On("FindAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return([]ValidatedEntity{{ID: "1", Email: "a@example.com", Name: "Alice", Price: 3}}, nil)
+	mockCtx.On("QueryParams").Return(map[string][]string{"fields": {"id,email"}, "sort": {"-price"}})
+
+	handleGetAll(mockCtx, mockDB, ValidatedEntity{}, DefaultConfig())
+
+	require.Equal(t, 200, mockCtx.Status())
+	require.Equal(t, []string{"ID", "Email", "Price"}, mockDB.Selected)
+	require.Equal(t, []map[string]any{{"id": "1", "email": "a@example.com"}}, mockCtx.Resp)
+}
+
+func TestHandleGetAll_InvalidFields(t *testing.T) {
+	for _, fields := range []string{"id,password", "Email", "books"} {
+		mockDB := new(MockDB)
+		mockCtx := new(MockContext)
+		mockCtx.On("QueryParams").Return(map[string][]string{"fields": {fields}})
+
+		entity := any(ValidatedEntity{})
+		if fields == "books" {
+			entity = Author{}
+		}
+		handleGetAll(mockCtx, mockDB, entity, DefaultConfig())
+
+		require.Equal(t, 400, mockCtx.Status(), fields)
+		require.Contains(t, mockCtx.Resp.(gerrors.Problem).Detail, "invalid fields", fields)
+		mockDB.AssertNotCalled(t, "FindAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	}
+}
+
+func TestHandleGetByID_Fields(t *testing.T) {
+	adapter := memory.New()
+	require.NoError(t, adapter.Create(&VersionedEntity{ID: "a", Name: "Alice"}))
+
+	mockCtx := new(MockContext)
+	mockCtx.On("Param", "id").Return("a")
+	mockCtx.On("Query", "fields").Return("Name")
+
+	handleGetByID(mockCtx, adapter, VersionedEntity{}, DefaultConfig())
+
+	require.Equal(t, 200, mockCtx.Status())
+	require.Equal(t, map[string]any{"Name": "Alice"}, mockCtx.Resp)
+	require.Equal(t, `"1"`, mockCtx.Headers["ETag"])
+}
+
 func TestHandleGetByID_Success(t *testing.T) {
 	mockDB := new(MockDB)
 	mockCtx := new(MockContext)
@@ -222,7 +601,8 @@ func TestHandleGetByID_Success(t *testing.T) {
 	mockCtx.On("Param", "id").Return("1")
 	mockDB.On("FindByID", "1", mock.Anything).Return(entity, nil)
 
-	handleGetByID(mockCtx, mockDB, TestEntity{})
+	mockCtx.On("Query", "fields").Return("")
+	handleGetByID(mockCtx, mockDB, TestEntity{}, DefaultConfig())
 
 	require.Equal(t, 200, mockCtx.Status())
 	require.Equal(t, entity, mockCtx.Resp)
@@ -233,12 +613,27 @@ func TestHandleGetByID_NotFound(t *testing.T) {
 	mockCtx := new(MockContext)
 
 	mockCtx.On("Param", "id").Return("99")
-	mockDB.On("FindByID", "99", mock.Anything).Return(nil, errors.New("not found"))
+	mockDB.On("FindByID", "99", mock.Anything).Return(nil, gerrors.NotFound("entity not found"))
 
-	handleGetByID(mockCtx, mockDB, TestEntity{})
+	mockCtx.On("Query", "fields").Return("")
+	handleGetByID(mockCtx, mockDB, TestEntity{}, DefaultConfig())
 
 	require.Equal(t, 404, mockCtx.Status())
-	require.Contains(t, mockCtx.Resp.(map[string]string)["error"], "entity not found")
+	require.Equal(t, "entity not found", mockCtx.Resp.(gerrors.Problem).Detail)
+}
+
+func TestHandleGetByID_DBError(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	mockCtx.On("Param", "id").Return("1")
+	mockDB.On("FindByID", "1", mock.Anything).Return(nil, errors.New("connection refused"))
+
+	mockCtx.On("Query", "fields").Return("")
+	handleGetByID(mockCtx, mockDB, TestEntity{}, DefaultConfig())
+
+	require.Equal(t, 500, mockCtx.Status())
+	require.NotContains(t, mockCtx.Resp.(gerrors.Problem).Detail, "connection refused")
 }
 
 // handleCreate
@@ -271,7 +666,7 @@ func TestHandleCreate_BindError(t *testing.T) {
 	handleCreate(mockCtx, mockDB, TestEntity{})
 
 	require.Equal(t, 400, mockCtx.Status())
-	require.Contains(t, mockCtx.Resp.(map[string]string)["error"], "invalid input")
+	require.Contains(t, mockCtx.Resp.(gerrors.Problem).Detail, "invalid input")
 }
 
 func TestHandleCreate_DBError(t *testing.T) {
@@ -284,7 +679,82 @@ func TestHandleCreate_DBError(t *testing.T) {
 	handleCreate(mockCtx, mockDB, TestEntity{})
 
 	require.Equal(t, 500, mockCtx.Status())
-	require.Contains(t, mockCtx.Resp.(map[string]string)["error"], "insert failed")
+	require.Equal(t, "internal server error", mockCtx.Resp.(gerrors.Problem).Detail)
+}
+
+func TestHandleCreate_Conflict(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	mockCtx.On("Bind", mock.Anything).Return(nil)
+	mockDB.On("Create", mock.Anything).Return(gerrors.Conflict("entity already exists"))
+
+	handleCreate(mockCtx, mockDB, TestEntity{})
+
+	require.Equal(t, 409, mockCtx.Status())
+	require.Equal(t, "Conflict", mockCtx.Resp.(gerrors.Problem).Title)
+}
+
+func TestHandleCreate_AfterHookErrorRollsBack(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	mockCtx.On("Bind", mock.Anything).Run(func(args mock.Arguments) {
+		arg := args.Get(0).(*HookEntity)
+		arg.AfterCreateErr = errors.New("audit failed")
+	}).Return(nil)
+	mockDB.On("Create", mock.Anything).Return(nil)
+
+	handleCreate(mockCtx, mockDB, HookEntity{})
+
+	require.Equal(t, 400, mockCtx.Status())
+	require.Contains(t, mockCtx.Resp.(gerrors.Problem).Detail, "afterSave failed: audit failed")
+	require.True(t, mockDB.RolledBack)
+}
+
+type ValidatedEntity struct {
+	ID    string  `json:"id"`
+	Email string  `json:"email" validate:"required,email"`
+	Name  string  `json:"name" validate:"min=3"`
+	Price float64 `json:"price" validate:"gte=0"`
+}
+
+func TestHandleCreate_ValidationError(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	mockCtx.On("Bind", mock.Anything).Run(func(args mock.Arguments) {
+		arg := args.Get(0).(*ValidatedEntity)
+		arg.Name = "Al"
+		arg.Price = -1
+	}).Return(nil)
+
+	handleCreate(mockCtx, mockDB, ValidatedEntity{})
+
+	require.Equal(t, 422, mockCtx.Status())
+	fields := mockCtx.Resp.(gerrors.Problem).Errors
+	require.Equal(t, []gerrors.FieldError{
+		{Field: "email", Rule: "required", Message: "is required"},
+		{Field: "name", Rule: "min", Param: "3", Message: "must be at least 3"},
+		{Field: "price", Rule: "gte", Param: "0", Message: "must be greater than or equal to 0"},
+	}, fields)
+	mockDB.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestHandleCreate_ValidEntity(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	mockCtx.On("Bind", mock.Anything).Run(func(args mock.Arguments) {
+		arg := args.Get(0).(*ValidatedEntity)
+		arg.Email = "al@example.com"
+		arg.Name = "Alice"
+	}).Return(nil)
+	mockDB.On("Create", mock.Anything).Return(nil)
+
+	handleCreate(mockCtx, mockDB, ValidatedEntity{})
+
+	require.Equal(t, 201, mockCtx.Status())
 }
 
 // handleUpdate
@@ -301,7 +771,7 @@ func TestHandleUpdate_Success(t *testing.T) {
 
 	mockDB.On("Update", mock.Anything).Return(nil)
 
-	handleUpdate(mockCtx, mockDB, TestEntity{})
+	handleUpdate(mockCtx, mockDB, TestEntity{}, DefaultConfig())
 
 	require.Equal(t, 200, mockCtx.Status())
 	require.Equal(t, "Updated", mockCtx.Resp.(*TestEntity).Name)
@@ -314,7 +784,7 @@ func TestHandleUpdate_BindError(t *testing.T) {
 	mockCtx.On("Param", "id").Return("1")
 	mockCtx.On("Bind", mock.Anything).Return(errors.New("bad input"))
 
-	handleUpdate(mockCtx, mockDB, TestEntity{})
+	handleUpdate(mockCtx, mockDB, TestEntity{}, DefaultConfig())
 
 	require.Equal(t, 400, mockCtx.Status())
 }
@@ -327,11 +797,69 @@ func TestHandleUpdate_DBError(t *testing.T) {
 	mockCtx.On("Bind", mock.Anything).Return(nil)
 	mockDB.On("Update", mock.Anything).Return(errors.New("update failed"))
 
-	handleUpdate(mockCtx, mockDB, TestEntity{})
+	handleUpdate(mockCtx, mockDB, TestEntity{}, DefaultConfig())
 
 	require.Equal(t, 500, mockCtx.Status())
 }
 
+func TestHandleUpdate_InvalidID(t *testing.T) {
+	type Counter struct {
+		ID    int
+		Value int
+	}
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	mockCtx.On("Param", "id").Return("abc")
+	mockCtx.On("Bind", mock.Anything).Return(nil)
+
+	handleUpdate(mockCtx, mockDB, Counter{}, DefaultConfig())
+
+	require.Equal(t, 400, mockCtx.Status())
+	mockDB.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestHandleUpdate_NotFound(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	mockCtx.On("Param", "id").Return("404")
+	mockCtx.On("Bind", mock.Anything).Return(nil)
+	mockDB.On("Update", mock.Anything).Return(gerrors.NotFound("entity not found"))
+
+	handleUpdate(mockCtx, mockDB, TestEntity{}, DefaultConfig())
+
+	require.Equal(t, 404, mockCtx.Status())
+	mockDB.AssertNotCalled(t, "Upsert", mock.Anything)
+}
+
+func TestHandleUpdate_PutCreates(t *testing.T) {
+	adapter := memory.New()
+	config := DefaultConfig()
+	PutCreates()(config)
+
+	put := func(name string) *MockContext {
+		ctx := new(MockContext)
+		ctx.On("Param", "id").Return("new")
+		ctx.On("Bind", mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(0).(*TestEntity).Name = name
+		}).Return(nil)
+		handleUpdate(ctx, adapter, TestEntity{}, config)
+		return ctx
+	}
+
+	created := put("first")
+	require.Equal(t, 201, created.Status())
+	require.Equal(t, "new", created.Resp.(*TestEntity).ID)
+
+	updated := put("second")
+	require.Equal(t, 200, updated.Status())
+
+	found, err := adapter.FindByID("new", &TestEntity{})
+	require.NoError(t, err)
+	require.Equal(t, "second", found.(*TestEntity).Name)
+}
+
 // handlePatch
 
 func TestHandlePatch_Success(t *testing.T) {
@@ -350,20 +878,38 @@ func TestHandlePatch_Success(t *testing.T) {
 
 	mockDB.On("Update", mock.Anything).Return(nil)
 
-	handlePatch(mockCtx, mockDB, TestEntity{})
+	handlePatch(mockCtx, mockDB, TestEntity{}, DefaultConfig())
 
 	require.Equal(t, 200, mockCtx.Status())
 	require.Equal(t, "New", mockCtx.Resp.(*TestEntity).Name)
 }
 
+func TestHandlePatch_ValidationError(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	entity := &ValidatedEntity{ID: "1", Email: "al@example.com", Name: "Alice"}
+	mockCtx.On("Param", "id").Return("1")
+	mockDB.On("FindByID", "1", mock.Anything).Return(entity, nil)
+	mockCtx.On("BindJSON", mock.Anything).Run(func(args mock.Arguments) {
+		arg := args.Get(0).(*map[string]interface{})
+		*arg = map[string]interface{}{"email": "not-an-email"}
+	}).Return(nil)
+
+	handlePatch(mockCtx, mockDB, ValidatedEntity{}, DefaultConfig())
+
+	require.Equal(t, 422, mockCtx.Status())
+	mockDB.AssertNotCalled(t, "Update", mock.Anything)
+}
+
 func TestHandlePatch_NotFound(t *testing.T) {
 	mockDB := new(MockDB)
 	mockCtx := new(MockContext)
 
 	mockCtx.On("Param", "id").Return("99")
-	mockDB.On("FindByID", "99", mock.Anything).Return(nil, errors.New("not found"))
+	mockDB.On("FindByID", "99", mock.Anything).Return(nil, gerrors.NotFound("entity not found"))
 
-	handlePatch(mockCtx, mockDB, TestEntity{})
+	handlePatch(mockCtx, mockDB, TestEntity{}, DefaultConfig())
 
 	require.Equal(t, 404, mockCtx.Status())
 }
@@ -378,7 +924,7 @@ func TestHandlePatch_BindError(t *testing.T) {
 
 	mockCtx.On("BindJSON", mock.Anything).Return(errors.New("bad patch"))
 
-	handlePatch(mockCtx, mockDB, TestEntity{})
+	handlePatch(mockCtx, mockDB, TestEntity{}, DefaultConfig())
 
 	require.Equal(t, 400, mockCtx.Status())
 }
@@ -392,7 +938,7 @@ func TestHandleDelete_Success(t *testing.T) {
 	mockCtx.On("Param", "id").Return("1")
 	mockDB.On("Delete", "1", mock.Anything).Return(nil)
 
-	handleDelete(mockCtx, mockDB, TestEntity{})
+	handleDelete(mockCtx, mockDB, TestEntity{}, DefaultConfig())
 
 	require.Equal(t, 204, mockCtx.Status())
 	require.Nil(t, mockCtx.Resp)
@@ -405,8 +951,300 @@ func TestHandleDelete_DBError(t *testing.T) {
 	mockCtx.On("Param", "id").Return("1")
 	mockDB.On("Delete", "1", mock.Anything).Return(errors.New("delete failed"))
 
-	handleDelete(mockCtx, mockDB, TestEntity{})
+	handleDelete(mockCtx, mockDB, TestEntity{}, DefaultConfig())
 
 	require.Equal(t, 500, mockCtx.Status())
-	require.Contains(t, mockCtx.Resp.(map[string]string)["error"], "delete failed")
+	require.Equal(t, "internal server error", mockCtx.Resp.(gerrors.Problem).Detail)
+}
+
+// soft delete
+
+func TestHandleGetAll_IncludeDeleted(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	mockCtx.On("QueryParams").Return(map[string][]string{"include_deleted": {"true"}})
+	mockCtx.On("Get", "user_id").Return("42")
+	mockDB.On("FindAll", mock.Anything, []db.Filter{}, mock.Anything, mock.Anything).
+		Return([]SoftEntity{}, nil)
+
+	handleGetAll(mockCtx, mockDB, SoftEntity{}, DefaultConfig())
+
+	require.Equal(t, 200, mockCtx.Status())
+	require.True(t, mockDB.WithDeleted)
+}
+
+func TestHandleGetAll_IncludeDeletedRequiresAuth(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	mockCtx.On("QueryParams").Return(map[string][]string{"include_deleted": {"true"}})
+	mockCtx.On("Get", "user_id").Return(nil)
+
+	handleGetAll(mockCtx, mockDB, SoftEntity{}, DefaultConfig())
+
+	require.Equal(t, 403, mockCtx.Status())
+	require.False(t, mockDB.WithDeleted)
+	mockDB.AssertNotCalled(t, "FindAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandleGetAll_IncludeDeletedAllowed(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	mockCtx.On("QueryParams").Return(map[string][]string{"include_deleted": {"true"}})
+	mockDB.On("FindAll", mock.Anything, []db.Filter{}, mock.Anything, mock.Anything).
+		Return([]SoftEntity{}, nil)
+
+	config := DefaultConfig()
+	AllowIncludeDeleted()(config)
+	handleGetAll(mockCtx, mockDB, SoftEntity{}, config)
+
+	require.Equal(t, 200, mockCtx.Status())
+	require.True(t, mockDB.WithDeleted)
+	mockCtx.AssertNotCalled(t, "Get", "user_id")
+}
+
+func TestHandleGetByID_IncludeDeleted(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	deletedAt := time.Now()
+	entity := &SoftEntity{ID: "1", DeletedAt: &deletedAt}
+	mockCtx.On("Param", "id").Return("1")
+	mockCtx.On("Query", "include_deleted").Return("true")
+	mockCtx.On("Get", "user_id").Return("42")
+	mockDB.On("FindByID", "1", mock.Anything).Return(entity, nil)
+
+	mockCtx.On("Query", "fields").Return("")
+	handleGetByID(mockCtx, mockDB, SoftEntity{}, DefaultConfig())
+
+	require.Equal(t, 200, mockCtx.Status())
+	require.Equal(t, entity, mockCtx.Resp)
+	require.True(t, mockDB.WithDeleted)
+}
+
+func TestHandleRestore_Success(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	restored := &SoftEntity{ID: "1", Name: "Alice"}
+	mockCtx.On("Param", "id").Return("1")
+	mockDB.On("Restore", "1", mock.Anything).Return(nil)
+	mockDB.On("FindByID", "1", mock.Anything).Return(restored, nil)
+
+	handleRestore(mockCtx, mockDB, SoftEntity{})
+
+	require.Equal(t, 200, mockCtx.Status())
+	require.Equal(t, restored, mockCtx.Resp)
+}
+
+func TestHandleRestore_NotDeleted(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	mockCtx.On("Param", "id").Return("1")
+	mockDB.On("Restore", "1", mock.Anything).Return(gerrors.NotFound("deleted entity not found"))
+
+	handleRestore(mockCtx, mockDB, SoftEntity{})
+
+	require.Equal(t, 404, mockCtx.Status())
+	mockDB.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+}
+
+// optimistic concurrency
+
+func TestHandleGetByID_ETag(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	mockCtx.On("Param", "id").Return("1")
+	mockDB.On("FindByID", "1", mock.Anything).Return(&VersionedEntity{ID: "1", Version: 3}, nil)
+
+	mockCtx.On("Query", "fields").Return("")
+	handleGetByID(mockCtx, mockDB, VersionedEntity{}, DefaultConfig())
+
+	require.Equal(t, 200, mockCtx.Status())
+	require.Equal(t, `"3"`, mockCtx.Headers["ETag"])
+}
+
+func TestHandleUpdate_IfMatch(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	mockCtx.On("Param", "id").Return("1")
+	mockCtx.On("Header", "If-Match").Return(`"3"`)
+	mockCtx.On("Bind", mock.Anything).Return(nil)
+	mockDB.On("Update", mock.Anything).Run(func(args mock.Arguments) {
+		entity := args.Get(0).(*VersionedEntity)
+		require.Equal(t, 3, entity.Version)
+		entity.Version++
+	}).Return(nil)
+
+	handleUpdate(mockCtx, mockDB, VersionedEntity{}, DefaultConfig())
+
+	require.Equal(t, 200, mockCtx.Status())
+	require.Equal(t, `"4"`, mockCtx.Headers["ETag"])
+}
+
+func TestHandleUpdate_StaleVersion(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	mockCtx.On("Param", "id").Return("1")
+	mockCtx.On("Header", "If-Match").Return(`"2"`)
+	mockCtx.On("Bind", mock.Anything).Return(nil)
+	mockDB.On("Update", mock.Anything).Return(gerrors.PreconditionFailed("entity was modified by another request"))
+
+	handleUpdate(mockCtx, mockDB, VersionedEntity{}, DefaultConfig())
+
+	require.Equal(t, 412, mockCtx.Status())
+}
+
+func TestHandleDelete_RequireIfMatch(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	mockCtx.On("Param", "id").Return("1")
+	mockCtx.On("Header", "If-Match").Return("")
+
+	cfg := DefaultConfig()
+	RequireIfMatch()(cfg)
+	handleDelete(mockCtx, mockDB, VersionedEntity{}, cfg)
+
+	require.Equal(t, 428, mockCtx.Status())
+	mockDB.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestHandleDelete_InvalidIfMatch(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	mockCtx.On("Param", "id").Return("1")
+	mockCtx.On("Header", "If-Match").Return("3")
+
+	handleDelete(mockCtx, mockDB, VersionedEntity{}, DefaultConfig())
+
+	require.Equal(t, 400, mockCtx.Status())
+}
+
+func TestParseETag(t *testing.T) {
+	version, err := parseETag(`"7"`)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), version)
+
+	version, err = parseETag(`W/"7"`)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), version)
+
+	for _, tag := range []string{`7`, `"abc"`, `"0"`, `"`} {
+		_, err := parseETag(tag)
+		require.Error(t, err, tag)
+	}
+}
+
+// relations
+
+func TestHandleGetAll_Include(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	mockCtx.On("QueryParams").Return(map[string][]string{"include": {"author"}})
+	mockDB.On("FindAll", mock.Anything, []db.Filter{}, mock.Anything, mock.Anything).Return([]Book{}, nil)
+
+	handleGetAll(mockCtx, mockDB, Book{}, DefaultConfig())
+
+	require.Equal(t, 200, mockCtx.Status())
+	require.Equal(t, []string{"Author"}, mockDB.Preloaded)
+}
+
+func TestHandleGetAll_IncludeRejectsNonRelations(t *testing.T) {
+	for _, include := range []string{"title", "publisher"} {
+		mockDB := new(MockDB)
+		mockCtx := new(MockContext)
+
+		mockCtx.On("QueryParams").Return(map[string][]string{"include": {include}})
+
+		handleGetAll(mockCtx, mockDB, Book{}, DefaultConfig())
+
+		require.Equal(t, 400, mockCtx.Status(), include)
+		require.Empty(t, mockDB.Preloaded)
+	}
+}
+
+func TestHandleGetByID_Include(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	author := &Author{ID: "1", Books: []Book{{ID: "7"}}}
+	mockCtx.On("Param", "id").Return("1")
+	mockCtx.On("Query", "include").Return("books")
+	mockDB.On("FindByID", "1", mock.Anything).Return(author, nil)
+
+	mockCtx.On("Query", "fields").Return("")
+	handleGetByID(mockCtx, mockDB, Author{}, DefaultConfig())
+
+	require.Equal(t, 200, mockCtx.Status())
+	require.Equal(t, []string{"Books"}, mockDB.Preloaded)
+}
+
+func TestHandleGetRelated(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	rel, _ := db.RelationByField(reflect.TypeOf(Author{}), "Books")
+	books := []Book{{ID: "7", AuthorID: "1"}}
+
+	mockCtx.On("Param", "id").Return("1")
+	mockCtx.On("QueryParams").Return(map[string][]string{"title": {"Go"}})
+	mockDB.On("FindByID", "1", mock.Anything).Return(&Author{ID: "1"}, nil)
+	mockDB.On("FindAll", Book{}, []db.Filter{
+		db.Eq("AuthorID", "1"),
+		{Field: "Title", Operator: db.OpEq, Value: "Go"},
+	}, mock.Anything, mock.Anything).Return(books, nil)
+
+	handleGetRelated(mockCtx, mockDB, Author{}, rel, DefaultConfig())
+
+	require.Equal(t, 200, mockCtx.Status())
+	require.Equal(t, books, mockCtx.Resp)
+}
+
+func TestHandleGetRelated_OwnerNotFound(t *testing.T) {
+	mockDB := new(MockDB)
+	mockCtx := new(MockContext)
+
+	rel, _ := db.RelationByField(reflect.TypeOf(Author{}), "Books")
+	mockCtx.On("Param", "id").Return("9")
+	mockDB.On("FindByID", "9", mock.Anything).Return(nil, gerrors.NotFound("entity not found"))
+
+	handleGetRelated(mockCtx, mockDB, Author{}, rel, DefaultConfig())
+
+	require.Equal(t, 404, mockCtx.Status())
+	mockDB.AssertNotCalled(t, "FindAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandlers_MemoryAdapter(t *testing.T) {
+	adapter := memory.New()
+
+	createCtx := new(MockContext)
+	createCtx.On("Bind", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(*TestEntity).Name = "Dana"
+	}).Return(nil)
+	handleCreate(createCtx, adapter, TestEntity{})
+	require.Equal(t, 201, createCtx.Status())
+	id := createCtx.Resp.(*TestEntity).ID
+	require.NotEmpty(t, id)
+
+	getCtx := new(MockContext)
+	getCtx.On("Param", "id").Return(id)
+	getCtx.On("Query", "fields").Return("")
+	handleGetByID(getCtx, adapter, TestEntity{}, DefaultConfig())
+	require.Equal(t, 200, getCtx.Status())
+	require.Equal(t, "Dana", getCtx.Resp.(*TestEntity).Name)
+
+	missingCtx := new(MockContext)
+	missingCtx.On("Param", "id").Return("missing")
+	missingCtx.On("Query", "fields").Return("")
+	handleGetByID(missingCtx, adapter, TestEntity{}, DefaultConfig())
+	require.Equal(t, 404, missingCtx.Status())
 }
@@ -0,0 +1,135 @@
+package crud
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Lumicrate/gompose/db"
+	"github.com/Lumicrate/gompose/http"
+)
+
+// ListResponse is the body of list routes registered with the Envelope option.
+type ListResponse struct {
+	Data   any     `json:"data"`
+	Total  int64   `json:"total"`
+	Limit  int     `json:"limit"`
+	Offset int     `json:"offset"`
+	Next   *string `json:"next"`
+	Prev   *string `json:"prev"`
+}
+
+// CursorResponse is the body of list routes registered with the CursorPagination option.
+type CursorResponse struct {
+	Data       any     `json:"data"`
+	Limit      int     `json:"limit"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+type pageLinks struct {
+	next, prev, first, last *string
+}
+
+// buildPageLinks computes the URLs of the neighbouring pages, keeping every other query parameter.
+func buildPageLinks(ctx http.Context, pagination db.Pagination, total int64) pageLinks {
+	var links pageLinks
+	if pagination.Limit <= 0 {
+		return links
+	}
+
+	pageURL := func(offset int) *string {
+		query := url.Values{}
+		for key, vals := range ctx.QueryParams() {
+			query[key] = vals
+		}
+		query.Set("limit", strconv.Itoa(pagination.Limit))
+		query.Set("offset", strconv.Itoa(offset))
+		u := ctx.Path() + "?" + query.Encode()
+		return &u
+	}
+
+	if int64(pagination.Offset+pagination.Limit) < total {
+		links.next = pageURL(pagination.Offset + pagination.Limit)
+	}
+	if pagination.Offset > 0 {
+		links.prev = pageURL(max(pagination.Offset-pagination.Limit, 0))
+	}
+
+	links.first = pageURL(0)
+	lastOffset := 0
+	if total > 0 {
+		lastOffset = int((total - 1) / int64(pagination.Limit) * int64(pagination.Limit))
+	}
+	links.last = pageURL(lastOffset)
+
+	return links
+}
+
+// linkHeader formats the links as an RFC 8288 Link header value.
+func (l pageLinks) linkHeader() string {
+	var parts []string
+	for _, link := range []struct {
+		rel string
+		url *string
+	}{{"next", l.next}, {"prev", l.prev}, {"first", l.first}, {"last", l.last}} {
+		if link.url != nil {
+			parts = append(parts, fmt.Sprintf(`<%s>; rel="%s"`, *link.url, link.rel))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// encodeCursor makes an opaque cursor from the sort key values of the last row of a page.
+func encodeCursor(values []any) (string, error) {
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// decodeCursor reverses encodeCursor. Numbers are returned as strings so adapters
+// convert them to the column type instead of receiving float64.
+func decodeCursor(cursor string, sortKeys int) ([]any, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var values []any
+	if err := decoder.Decode(&values); err != nil || len(values) != sortKeys {
+		return nil, errors.New("invalid cursor")
+	}
+
+	for i, v := range values {
+		if n, ok := v.(json.Number); ok {
+			values[i] = n.String()
+		}
+	}
+	return values, nil
+}
+
+// sortKeyValues reads the values of the sort fields from a result row.
+func sortKeyValues(row reflect.Value, sort []db.Sort) ([]any, error) {
+	if row.Kind() == reflect.Ptr {
+		row = row.Elem()
+	}
+
+	values := make([]any, len(sort))
+	for i, s := range sort {
+		field, ok := lookupField(row.Type(), s.Field)
+		if !ok {
+			return nil, fmt.Errorf("unknown sort field %q", s.Field)
+		}
+		values[i] = row.FieldByIndex(field.Index).Interface()
+	}
+	return values, nil
+}
@@ -0,0 +1,68 @@
+package crud
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Lumicrate/gompose/db"
+	gerrors "github.com/Lumicrate/gompose/errors"
+	"github.com/Lumicrate/gompose/http"
+)
+
+// withIncludes resolves ?include=customer,line_items to relations of entity
+// and asks the adapter to eager-load them.
+func withIncludes(dbAdapter db.DBAdapter, entity any, include string) (db.DBAdapter, error) {
+	if include == "" {
+		return dbAdapter, nil
+	}
+
+	t := reflect.TypeOf(entity)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var relations []string
+	for _, name := range strings.Split(include, ",") {
+		name = strings.TrimSpace(name)
+		field, ok := lookupField(t, name)
+		if !ok {
+			return nil, gerrors.BadRequest(fmt.Sprintf("invalid include: unknown relation %q", name))
+		}
+		if _, ok := db.RelationByField(t, field.Name); !ok {
+			return nil, gerrors.BadRequest(fmt.Sprintf("invalid include: %q is not a relation", name))
+		}
+		relations = append(relations, field.Name)
+	}
+	return dbAdapter.Preload(relations...), nil
+}
+
+// handleGetRelated lists the entities a has-many relation points to, e.g. GET /customers/:id/orders.
+func handleGetRelated(ctx http.Context, dbAdapter db.DBAdapter, entity any, rel db.Relation, config *Config) {
+	id := ctx.Param("id")
+
+	t := reflect.TypeOf(entity)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	owner, err := dbAdapter.FindByID(id, reflect.New(t).Interface())
+	if err != nil {
+		gerrors.Write(ctx, err)
+		return
+	}
+
+	key := reflect.Indirect(reflect.ValueOf(owner)).FieldByName(rel.References).Interface()
+	related := reflect.New(rel.Target).Elem().Interface()
+
+	handleList(ctx, dbAdapter, related, config, []db.Filter{db.Eq(rel.ForeignKey, key)})
+}
+
+// relationPath is the path segment of a nested route: the json name of the relation field.
+func relationPath(t reflect.Type, rel db.Relation) string {
+	field, _ := t.FieldByName(rel.Field)
+	if name := strings.Split(field.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+		return name
+	}
+	return strings.ToLower(rel.Field)
+}
@@ -1,14 +1,25 @@
 package crud
 
 import (
+	"context"
 	"github.com/Lumicrate/gompose/auth"
 	"github.com/Lumicrate/gompose/db"
 	"github.com/Lumicrate/gompose/http"
 	"github.com/Lumicrate/gompose/utils"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
+// ReadPrimaryHeader lets a client read its own writes on adapters with read replicas:
+// GET requests sending "X-Read-Primary: true" are served by the primary.
+const ReadPrimaryHeader = "X-Read-Primary"
+
+func readsPrimary(ctx http.Context) bool {
+	primary, _ := strconv.ParseBool(ctx.Header(ReadPrimaryHeader))
+	return primary
+}
+
 func RegisterCRUDRoutes(
 	engine http.HTTPEngine,
 	dbAdapter db.DBAdapter,
@@ -23,41 +34,101 @@ func RegisterCRUDRoutes(
 	entityName := t.Name()
 	basePath := "/" + strings.ToLower(utils.Pluralize(entityName))
 
-	register := func(method, path string, handler http.HandlerFunc) {
-		wrapped := handler
-		if config.ProtectedMethods[method] && authProvider != nil {
-			wrapped = authProvider.Middleware()(handler)
+	route := func(method, path string, routeEntity any, protected bool, handler func(ctx http.Context, dbAdapter db.DBAdapter)) {
+		var wrapped http.HandlerFunc = func(ctx http.Context) {
+			reqCtx := ctx.Context()
+			if method != "GET" || readsPrimary(ctx) {
+				// Writes read what they modify from the primary, which replicas may lag behind.
+				reqCtx = db.WithPrimary(reqCtx)
+			}
+			if config.Timeout > 0 {
+				var cancel context.CancelFunc
+				reqCtx, cancel = context.WithTimeout(reqCtx, config.Timeout)
+				defer cancel()
+			}
+			handler(ctx, dbAdapter.WithContext(reqCtx))
+		}
+		if protected && authProvider != nil {
+			wrapped = authProvider.Middleware()(wrapped)
 		}
-		engine.RegisterRoute(method, path, wrapped, entity, config.ProtectedMethods[method])
+		engine.RegisterRoute(method, path, wrapped, routeEntity, protected)
+	}
+	register := func(method, path string, handler func(ctx http.Context, dbAdapter db.DBAdapter)) {
+		route(method, path, entity, config.ProtectedMethods[method], handler)
 	}
 
 	// GET /entities (list)
-	register("GET", basePath, func(ctx http.Context) {
-		handleGetAll(ctx, dbAdapter, entity)
+	register("GET", basePath, func(ctx http.Context, dbAdapter db.DBAdapter) {
+		handleGetAll(ctx, dbAdapter, entity, config)
 	})
 
+	if len(config.Aggregatable) > 0 {
+		// GET /entities/aggregate
+		register("GET", basePath+"/aggregate", func(ctx http.Context, dbAdapter db.DBAdapter) {
+			handleAggregate(ctx, dbAdapter, entity, config)
+		})
+	}
+
 	// GET /entities/:id
-	register("GET", basePath+"/:id", func(ctx http.Context) {
-		handleGetByID(ctx, dbAdapter, entity)
+	register("GET", basePath+"/:id", func(ctx http.Context, dbAdapter db.DBAdapter) {
+		handleGetByID(ctx, dbAdapter, entity, config)
 	})
 
 	// POST /entities
-	register("POST", basePath, func(ctx http.Context) {
+	register("POST", basePath, func(ctx http.Context, dbAdapter db.DBAdapter) {
 		handleCreate(ctx, dbAdapter, entity)
 	})
 
+	if config.Bulk {
+		// POST /entities/bulk
+		register("POST", basePath+"/bulk", func(ctx http.Context, dbAdapter db.DBAdapter) {
+			handleBulkCreate(ctx, dbAdapter, entity, config)
+		})
+
+		// PATCH /entities/bulk
+		register("PATCH", basePath+"/bulk", func(ctx http.Context, dbAdapter db.DBAdapter) {
+			handleBulkPatch(ctx, dbAdapter, entity, config)
+		})
+
+		// DELETE /entities/bulk?ids=
+		register("DELETE", basePath+"/bulk", func(ctx http.Context, dbAdapter db.DBAdapter) {
+			handleBulkDelete(ctx, dbAdapter, entity, config)
+		})
+	}
+
 	// PUT /entities/:id
-	register("PUT", basePath+"/:id", func(ctx http.Context) {
-		handleUpdate(ctx, dbAdapter, entity)
+	register("PUT", basePath+"/:id", func(ctx http.Context, dbAdapter db.DBAdapter) {
+		handleUpdate(ctx, dbAdapter, entity, config)
 	})
 
 	// PATCH /entities/:id
-	register("PATCH", basePath+"/:id", func(ctx http.Context) {
-		handlePatch(ctx, dbAdapter, entity)
+	register("PATCH", basePath+"/:id", func(ctx http.Context, dbAdapter db.DBAdapter) {
+		handlePatch(ctx, dbAdapter, entity, config)
 	})
 
 	// DELETE /entities/:id
-	register("DELETE", basePath+"/:id", func(ctx http.Context) {
-		handleDelete(ctx, dbAdapter, entity)
+	register("DELETE", basePath+"/:id", func(ctx http.Context, dbAdapter db.DBAdapter) {
+		handleDelete(ctx, dbAdapter, entity, config)
 	})
+
+	if db.IsSoftDeletable(entity) {
+		// POST /entities/:id/restore, protected like DELETE
+		route("POST", basePath+"/:id/restore", entity, config.ProtectedMethods["DELETE"], func(ctx http.Context, dbAdapter db.DBAdapter) {
+			handleRestore(ctx, dbAdapter, entity)
+		})
+	}
+
+	// GET /entities/:id/<relation> for has-many relations, e.g. /customers/:id/orders.
+	// Whitelists are per entity, so they do not carry over to the related entity.
+	nested := *config
+	nested.Filterable, nested.Sortable, nested.Searchable, nested.Aggregatable = nil, nil, nil, nil
+	for _, rel := range db.Relations(t) {
+		if rel.Kind != db.HasMany {
+			continue
+		}
+		related := reflect.New(rel.Target).Elem().Interface()
+		route("GET", basePath+"/:id/"+relationPath(t, rel), related, config.ProtectedMethods["GET"], func(ctx http.Context, dbAdapter db.DBAdapter) {
+			handleGetRelated(ctx, dbAdapter, entity, rel, &nested)
+		})
+	}
 }
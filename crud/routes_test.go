@@ -3,6 +3,7 @@ package crud
 import (
 	"testing"
 
+	"github.com/Lumicrate/gompose/db/memory"
 	gomposehttp "github.com/Lumicrate/gompose/http"
 	"github.com/stretchr/testify/require"
 )
@@ -11,6 +12,7 @@ import (
 
 type MockEngine struct {
 	RoutesRegistered []gomposehttp.Route
+	Handlers         map[string]gomposehttp.HandlerFunc // by "METHOD path"
 }
 
 func (m *MockEngine) Init(port int) error {
@@ -18,6 +20,10 @@ func (m *MockEngine) Init(port int) error {
 }
 
 func (m *MockEngine) RegisterRoute(method string, path string, handler gomposehttp.HandlerFunc, entity any, isProtected bool) {
+	if m.Handlers == nil {
+		m.Handlers = map[string]gomposehttp.HandlerFunc{}
+	}
+	m.Handlers[method+" "+path] = handler
 	m.RoutesRegistered = append(m.RoutesRegistered, gomposehttp.Route{
 		Method:    method,
 		Path:      path,
@@ -92,3 +98,105 @@ func TestRegisterCRUDRoutes(t *testing.T) {
 		}
 	}
 }
+
+func TestRegisterCRUDRoutes_SoftDeleteAddsRestore(t *testing.T) {
+	engine := &MockEngine{}
+
+	config := DefaultConfig()
+	Protect("DELETE")(config)
+
+	RegisterCRUDRoutes(engine, &MockDB{}, SoftEntity{}, config, &MockAuth{})
+
+	routes := engine.Routes()
+	require.Len(t, routes, 7)
+
+	restore := routes[len(routes)-1]
+	require.Equal(t, "POST", restore.Method)
+	require.Equal(t, "/softentities/:id/restore", restore.Path)
+	require.True(t, restore.Protected)
+}
+
+func TestRegisterCRUDRoutes_NestedRelations(t *testing.T) {
+	engine := &MockEngine{}
+
+	config := DefaultConfig()
+	Protect("GET")(config)
+
+	RegisterCRUDRoutes(engine, &MockDB{}, Author{}, config, &MockAuth{})
+
+	routes := engine.Routes()
+	require.Len(t, routes, 7)
+
+	nested := routes[len(routes)-1]
+	require.Equal(t, "GET", nested.Method)
+	require.Equal(t, "/authors/:id/books", nested.Path)
+	require.Equal(t, Book{}, nested.Entity)
+	require.True(t, nested.Protected)
+}
+
+func TestRegisterCRUDRoutes_NestedSearch(t *testing.T) {
+	engine := &MockEngine{}
+	adapter := memory.New()
+	require.NoError(t, adapter.Create(&Author{ID: "1", Name: "Gopher"}))
+	require.NoError(t, adapter.Create(&Book{ID: "7", Title: "Go", AuthorID: "1"}))
+
+	config := DefaultConfig()
+	Searchable("name")(config)
+	RegisterCRUDRoutes(engine, adapter, Author{}, config, nil)
+
+	ctx := new(MockContext)
+	ctx.On("Param", "id").Return("1")
+	ctx.On("Header", ReadPrimaryHeader).Return("")
+	ctx.On("QueryParams").Return(map[string][]string{"q": {"go"}})
+	engine.Handlers["GET /authors/:id/books"](ctx)
+
+	// Searchable lists fields of authors, so ?q= is not enabled on their books.
+	require.Equal(t, 400, ctx.Status())
+}
+
+func TestRegisterCRUDRoutes_Bulk(t *testing.T) {
+	engine := &MockEngine{}
+
+	config := DefaultConfig()
+	Bulk()(config)
+	Protect("DELETE")(config)
+
+	RegisterCRUDRoutes(engine, &MockDB{}, TestEntity{}, config, &MockAuth{})
+
+	routes := engine.Routes()
+	require.Len(t, routes, 9)
+
+	var bulk []gomposehttp.Route
+	for _, r := range routes {
+		if r.Path == "/testentities/bulk" {
+			bulk = append(bulk, r)
+		}
+	}
+	require.Len(t, bulk, 3)
+	require.Equal(t, []string{"POST", "PATCH", "DELETE"}, []string{bulk[0].Method, bulk[1].Method, bulk[2].Method})
+	require.False(t, bulk[0].Protected)
+	require.True(t, bulk[2].Protected)
+}
+
+func TestRegisterCRUDRoutes_Aggregate(t *testing.T) {
+	engine := &MockEngine{}
+
+	config := DefaultConfig()
+	Aggregatable("name")(config)
+	Protect("GET")(config)
+
+	RegisterCRUDRoutes(engine, &MockDB{}, TestEntity{}, config, &MockAuth{})
+
+	routes := engine.Routes()
+	require.Len(t, routes, 7)
+
+	var aggregate []gomposehttp.Route
+	for _, r := range routes {
+		if r.Path == "/testentities/aggregate" {
+			aggregate = append(aggregate, r)
+		}
+	}
+	require.Len(t, aggregate, 1)
+	require.Equal(t, "GET", aggregate[0].Method)
+	require.True(t, aggregate[0].Protected)
+}
@@ -0,0 +1,22 @@
+package crud
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/Lumicrate/gompose/db"
+)
+
+// withSearch resolves the Searchable fields of entity and asks the adapter to search them for q.
+func withSearch(dbAdapter db.DBAdapter, entity any, q string, searchable []string) (db.DBAdapter, error) {
+	t := reflect.TypeOf(entity)
+	fields := make([]string, len(searchable))
+	for i, name := range searchable {
+		field, ok := lookupField(t, name)
+		if !ok {
+			return nil, fmt.Errorf("unknown searchable field %q", name)
+		}
+		fields[i] = field.Name
+	}
+	return dbAdapter.Search(q, fields...), nil
+}
@@ -0,0 +1,112 @@
+package crud
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	gerrors "github.com/Lumicrate/gompose/errors"
+	"github.com/Lumicrate/gompose/http"
+	"github.com/go-playground/validator/v10"
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// report fields by the name clients send, not the Go field name
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+	return v
+}
+
+// Validator returns the validator used for `validate` struct tags, e.g. to register custom rules.
+func Validator() *validator.Validate {
+	return validate
+}
+
+// validateEntity checks the `validate` tags of entity and returns every failing field.
+func validateEntity(entity any) ([]gerrors.FieldError, error) {
+	err := validate.Struct(entity)
+	if err == nil {
+		return nil, nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil, err
+	}
+
+	fields := make([]gerrors.FieldError, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		fields = append(fields, gerrors.FieldError{
+			Field:   fieldPath(fe.Namespace()),
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: validationMessage(fe),
+		})
+	}
+	return fields, nil
+}
+
+// fieldPath drops the struct name from a validator namespace: User.address.city → address.city
+func fieldPath(namespace string) string {
+	if i := strings.IndexByte(namespace, '.'); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "uuid":
+		return "must be a valid UUID"
+	case "min":
+		return "must be at least " + fe.Param()
+	case "max":
+		return "must be at most " + fe.Param()
+	case "len":
+		return "must have length " + fe.Param()
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be greater than or equal to " + fe.Param()
+	case "lt":
+		return "must be less than " + fe.Param()
+	case "lte":
+		return "must be less than or equal to " + fe.Param()
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	default:
+		return "failed the " + fe.Tag() + " rule"
+	}
+}
+
+// validateRequest runs the `validate` tags of a bound request body and responds
+// 422 with every failing field when it is invalid.
+func validateRequest(ctx http.Context, entity any) bool {
+	fields, err := validateEntity(entity)
+	if err != nil {
+		gerrors.Write(ctx, err)
+		return false
+	}
+	if len(fields) > 0 {
+		gerrors.Write(ctx, gerrors.Validation(fields))
+		return false
+	}
+	return true
+}
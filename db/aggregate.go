@@ -0,0 +1,106 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type AggregateFunc string
+
+const (
+	AggCount AggregateFunc = "count"
+	AggSum   AggregateFunc = "sum"
+	AggAvg   AggregateFunc = "avg"
+	AggMin   AggregateFunc = "min"
+	AggMax   AggregateFunc = "max"
+)
+
+// Metric is a value computed over every group of an aggregation. Field is empty for AggCount,
+// which counts entities, and names a numeric field for the other functions.
+type Metric struct {
+	Func  AggregateFunc
+	Field string
+}
+
+// ParseMetric parses `count` or `fn(field)`, such as `sum(total)`, into a Metric.
+func ParseMetric(s string) (Metric, error) {
+	s = strings.TrimSpace(s)
+	if s == string(AggCount) {
+		return Metric{Func: AggCount}, nil
+	}
+
+	fn, rest, ok := strings.Cut(s, "(")
+	if !ok || !strings.HasSuffix(rest, ")") {
+		return Metric{}, fmt.Errorf("malformed metric %q", s)
+	}
+	field := strings.TrimSpace(strings.TrimSuffix(rest, ")"))
+	if field == "" {
+		return Metric{}, fmt.Errorf("malformed metric %q", s)
+	}
+
+	switch f := AggregateFunc(strings.ToLower(strings.TrimSpace(fn))); f {
+	case AggSum, AggAvg, AggMin, AggMax:
+		return Metric{Func: f, Field: field}, nil
+	default:
+		return Metric{}, fmt.Errorf("unsupported metric %q", fn)
+	}
+}
+
+// IsNumeric reports whether fields of type t can be summed and averaged.
+func IsNumeric(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() >= reflect.Int && t.Kind() <= reflect.Float64
+}
+
+// AggregateRow is one group of an aggregation: the values of the group-by fields and of the
+// metrics, in the order they were requested. Counts are int64 and the other metrics float64,
+// or nil when the group has no value for the field.
+type AggregateRow struct {
+	Group   []any
+	Metrics []any
+}
+
+// MetricValue converts a metric computed by a database to the type AggregateRow promises.
+// Drivers report numbers as integers, floats, decimal strings or bytes depending on the
+// database and the column type.
+func MetricValue(fn AggregateFunc, value any) (any, error) {
+	if value == nil {
+		if fn == AggCount {
+			return int64(0), nil
+		}
+		return nil, nil
+	}
+
+	var f float64
+	switch v := value.(type) {
+	case int:
+		f = float64(v)
+	case int32:
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	case float32:
+		f = float64(v)
+	case float64:
+		f = v
+	case []byte:
+		return MetricValue(fn, string(v))
+	case string:
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: unexpected value %q", fn, v)
+		}
+		f = parsed
+	default:
+		return nil, fmt.Errorf("%s: unexpected value of type %T", fn, value)
+	}
+
+	if fn == AggCount {
+		return int64(f), nil
+	}
+	return f, nil
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMetric(t *testing.T) {
+	m, err := ParseMetric(" count ")
+	require.NoError(t, err)
+	require.Equal(t, Metric{Func: AggCount}, m)
+
+	m, err = ParseMetric("SUM( total )")
+	require.NoError(t, err)
+	require.Equal(t, Metric{Func: AggSum, Field: "total"}, m)
+
+	for _, bad := range []string{"", "sum", "sum()", "sum(total", "median(total)", "count(total"} {
+		_, err := ParseMetric(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestMetricValue(t *testing.T) {
+	for _, tc := range []struct {
+		fn    AggregateFunc
+		value any
+		want  any
+	}{
+		{AggCount, int64(3), int64(3)},
+		{AggCount, nil, int64(0)},
+		{AggSum, int32(4), 4.0},
+		{AggAvg, "12.50", 12.5},
+		{AggMax, []byte("7"), 7.0},
+		{AggMin, nil, nil},
+	} {
+		got, err := MetricValue(tc.fn, tc.value)
+		require.NoError(t, err)
+		require.Equal(t, tc.want, got)
+	}
+
+	_, err := MetricValue(AggSum, "abc")
+	require.Error(t, err)
+}
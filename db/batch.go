@@ -0,0 +1,45 @@
+package db
+
+import "fmt"
+
+// BatchError reports which item of a CreateMany, UpdateMany or DeleteMany call failed.
+// Index is the position of the item in the slice passed to the call.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// PartialBatchError reports a CreateMany, UpdateMany or DeleteMany call that failed on some
+// items of a database that cannot roll the others back: every item not listed was written.
+// Errors are ordered by Index.
+type PartialBatchError struct {
+	Errors []*BatchError
+}
+
+func (e *PartialBatchError) Error() string {
+	if len(e.Errors) == 0 {
+		return "batch partially failed"
+	}
+	return fmt.Sprintf("%d items failed, first %v", len(e.Errors), e.Errors[0])
+}
+
+// Failed returns the error of item i, or nil when it was written.
+func (e *PartialBatchError) Failed(i int) error {
+	if e == nil {
+		return nil
+	}
+	for _, err := range e.Errors {
+		if err.Index == i {
+			return err.Err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,481 @@
+// Package dbtest holds a conformance suite for db.DBAdapter implementations. Every adapter
+// shipped with gompose runs it, and third-party adapters can run it to prove that the CRUD
+// handlers will behave the same on top of them.
+package dbtest
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Lumicrate/gompose/db"
+	gerrors "github.com/Lumicrate/gompose/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory returns an initialized adapter backed by an empty database. It is called for every
+// test case, so cases never see each other's data.
+type Factory func(t *testing.T) db.DBAdapter
+
+// Widget is the suite's entity with an integer ID.
+type Widget struct {
+	ID       int     `json:"id" gorm:"primaryKey"`
+	Name     string  `json:"name"`
+	Category string  `json:"category"`
+	Price    float64 `json:"price"`
+	Nickname *string `json:"nickname"`
+}
+
+// Tag is the suite's entity with a string ID.
+type Tag struct {
+	ID    string `json:"id" gorm:"primaryKey"`
+	Label string `json:"label"`
+}
+
+// Event is the suite's entity with a time field.
+type Event struct {
+	ID int       `json:"id" gorm:"primaryKey"`
+	At time.Time `json:"at"`
+}
+
+// RunConformance checks the behaviour gompose relies on: creating, reading, updating and
+// deleting entities with integer and string IDs, one at a time and in batches, filtering
+// with every operator, searching, aggregating, field selection, sorting, offset and keyset
+// pagination, and the errors reported for missing entities, unknown fields and invalid values.
+func RunConformance(t *testing.T, factory Factory) {
+	t.Run("Ping", func(t *testing.T) {
+		adapter := setup(t, factory, false)
+		require.NoError(t, adapter.Ping())
+		require.NoError(t, adapter.WithContext(context.Background()).Ping())
+	})
+
+	t.Run("Create/GeneratesIntIDs", func(t *testing.T) {
+		adapter := setup(t, factory, false)
+
+		first, second := &Widget{Name: "first"}, &Widget{Name: "second"}
+		require.NoError(t, adapter.Create(first))
+		require.NoError(t, adapter.Create(second))
+		require.NotZero(t, first.ID)
+		require.NotEqual(t, first.ID, second.ID)
+
+		found, err := adapter.FindByID(strconv.Itoa(second.ID), &Widget{})
+		require.NoError(t, err)
+		require.Equal(t, "second", found.(*Widget).Name)
+	})
+
+	t.Run("Create/StringIDs", func(t *testing.T) {
+		adapter := setup(t, factory, false)
+
+		require.NoError(t, adapter.Create(&Tag{ID: "go", Label: "Go"}))
+
+		found, err := adapter.FindByID("go", &Tag{})
+		require.NoError(t, err)
+		require.Equal(t, &Tag{ID: "go", Label: "Go"}, found)
+	})
+
+	t.Run("FindByID/NotFound", func(t *testing.T) {
+		adapter := setup(t, factory, true)
+
+		_, err := adapter.FindByID("999", &Widget{})
+		require.ErrorIs(t, err, gerrors.ErrNotFound)
+
+		_, err = adapter.FindByID("missing", &Tag{})
+		require.ErrorIs(t, err, gerrors.ErrNotFound)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		adapter := setup(t, factory, true)
+
+		found, err := adapter.FindByID("1", &Widget{})
+		require.NoError(t, err)
+		widget := found.(*Widget)
+		widget.Price = 30
+		require.NoError(t, adapter.Update(widget))
+
+		found, err = adapter.FindByID("1", &Widget{})
+		require.NoError(t, err)
+		require.Equal(t, 30.0, found.(*Widget).Price)
+		require.Equal(t, "apple", found.(*Widget).Name)
+	})
+
+	t.Run("Update/NotFound", func(t *testing.T) {
+		adapter := setup(t, factory, true)
+
+		err := adapter.Update(&Widget{ID: 999, Name: "ghost"})
+		require.ErrorIs(t, err, gerrors.ErrNotFound)
+
+		// A failed update must not create the entity.
+		_, err = adapter.FindByID("999", &Widget{})
+		require.ErrorIs(t, err, gerrors.ErrNotFound)
+
+		// Neither must an update without an ID.
+		require.ErrorIs(t, adapter.Update(&Widget{Name: "nameless"}), gerrors.ErrNotFound)
+		count, err := adapter.Count(&Widget{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, int64(5), count)
+	})
+
+	t.Run("Upsert", func(t *testing.T) {
+		adapter := setup(t, factory, true)
+
+		created, err := adapter.Upsert(&Widget{ID: 42, Name: "fig", Category: "fruit"})
+		require.NoError(t, err)
+		require.True(t, created)
+
+		created, err = adapter.Upsert(&Widget{ID: 1, Name: "apricot", Category: "fruit"})
+		require.NoError(t, err)
+		require.False(t, created)
+
+		found, err := adapter.FindByID("1", &Widget{})
+		require.NoError(t, err)
+		require.Equal(t, "apricot", found.(*Widget).Name)
+
+		created, err = adapter.Upsert(&Tag{ID: "go", Label: "Go"})
+		require.NoError(t, err)
+		require.True(t, created)
+
+		count, err := adapter.Count(&Widget{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, int64(6), count)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		adapter := setup(t, factory, true)
+
+		require.NoError(t, adapter.Delete("1", &Widget{}))
+
+		_, err := adapter.FindByID("1", &Widget{})
+		require.ErrorIs(t, err, gerrors.ErrNotFound)
+
+		count, err := adapter.Count(&Widget{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, int64(4), count)
+
+		require.ErrorIs(t, adapter.Delete("1", &Widget{}), gerrors.ErrNotFound)
+	})
+
+	t.Run("CreateMany", func(t *testing.T) {
+		adapter := setup(t, factory, false)
+
+		widgets := []any{&Widget{Name: "first"}, &Widget{Name: "second"}, &Widget{Name: "third"}}
+		require.NoError(t, adapter.CreateMany(widgets))
+		ids := map[int]bool{}
+		for _, w := range widgets {
+			require.NotZero(t, w.(*Widget).ID)
+			ids[w.(*Widget).ID] = true
+		}
+		require.Len(t, ids, 3)
+
+		found, err := adapter.FindByID(strconv.Itoa(widgets[2].(*Widget).ID), &Widget{})
+		require.NoError(t, err)
+		require.Equal(t, "third", found.(*Widget).Name)
+
+		err = adapter.CreateMany([]any{&Tag{ID: "go"}, &Tag{ID: "go"}})
+		require.ErrorIs(t, err, gerrors.ErrConflict)
+		var batchErr *db.BatchError
+		require.ErrorAs(t, err, &batchErr)
+		require.Equal(t, 1, batchErr.Index)
+
+		// The failing item is reported, also among generated IDs, and nothing is written.
+		err = adapter.CreateMany([]any{&Widget{Name: "fourth"}, &Widget{ID: widgets[0].(*Widget).ID, Name: "again"}, &Widget{Name: "fifth"}})
+		require.ErrorIs(t, err, gerrors.ErrConflict)
+		require.ErrorAs(t, err, &batchErr)
+		require.Equal(t, 1, batchErr.Index)
+		count, err := adapter.Count(&Widget{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, int64(3), count)
+	})
+
+	t.Run("UpdateMany", func(t *testing.T) {
+		adapter := setup(t, factory, true)
+
+		require.NoError(t, adapter.UpdateMany([]any{
+			&Widget{ID: 1, Name: "apricot", Category: "fruit"},
+			&Widget{ID: 2, Name: "blueberry", Category: "fruit"},
+		}))
+		found, err := adapter.FindByID("2", &Widget{})
+		require.NoError(t, err)
+		require.Equal(t, "blueberry", found.(*Widget).Name)
+
+		err = adapter.UpdateMany([]any{&Widget{ID: 3, Name: "celery"}, &Widget{ID: 999, Name: "ghost"}})
+		require.ErrorIs(t, err, gerrors.ErrNotFound)
+		var batchErr *db.BatchError
+		require.ErrorAs(t, err, &batchErr)
+		require.Equal(t, 1, batchErr.Index)
+	})
+
+	t.Run("DeleteMany", func(t *testing.T) {
+		adapter := setup(t, factory, true)
+
+		require.NoError(t, adapter.DeleteMany([]string{"1", "2"}, &Widget{}))
+		count, err := adapter.Count(&Widget{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, int64(3), count)
+
+		err = adapter.DeleteMany([]string{"3", "999"}, &Widget{})
+		require.ErrorIs(t, err, gerrors.ErrNotFound)
+		var batchErr *db.BatchError
+		require.ErrorAs(t, err, &batchErr)
+		require.Equal(t, 1, batchErr.Index)
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		adapter := setup(t, factory, true)
+
+		// Values are strings, as they arrive from the query string.
+		cases := []struct {
+			name    string
+			filters []db.Filter
+			want    []int
+		}{
+			{"eq", []db.Filter{{Field: "Category", Operator: db.OpEq, Value: "fruit"}}, []int{1, 2, 4}},
+			{"ne", []db.Filter{{Field: "Category", Operator: db.OpNe, Value: "fruit"}}, []int{3, 5}},
+			{"gt", []db.Filter{{Field: "Price", Operator: db.OpGt, Value: "3"}}, []int{4, 5}},
+			{"gte", []db.Filter{{Field: "Price", Operator: db.OpGte, Value: "3"}}, []int{1, 4, 5}},
+			{"lt", []db.Filter{{Field: "Price", Operator: db.OpLt, Value: "2"}}, []int{2}},
+			{"lte", []db.Filter{{Field: "Price", Operator: db.OpLte, Value: "2"}}, []int{2, 3}},
+			{"in", []db.Filter{{Field: "Name", Operator: db.OpIn, Value: []string{"apple", "date"}}}, []int{1, 4}},
+			{"like", []db.Filter{{Field: "Name", Operator: db.OpLike, Value: "%an%"}}, []int{2, 5}},
+			{"null", []db.Filter{{Field: "Nickname", Operator: db.OpNull, Value: true}}, []int{2, 3, 5}},
+			{"not null", []db.Filter{{Field: "Nickname", Operator: db.OpNull, Value: false}}, []int{1, 4}},
+			{"combined", []db.Filter{
+				{Field: "Category", Operator: db.OpEq, Value: "fruit"},
+				{Field: "Price", Operator: db.OpGte, Value: "3"},
+			}, []int{1, 4}},
+		}
+
+		for _, c := range cases {
+			result, err := adapter.FindAll(&Widget{}, c.filters, db.Pagination{}, nil)
+			require.NoError(t, err, c.name)
+			ids := widgetIDs(t, result)
+			sort.Ints(ids)
+			require.Equal(t, c.want, ids, c.name)
+
+			count, err := adapter.Count(&Widget{}, c.filters)
+			require.NoError(t, err, c.name)
+			require.Equal(t, int64(len(c.want)), count, c.name)
+		}
+	})
+
+	t.Run("Filters/UnknownField", func(t *testing.T) {
+		adapter := setup(t, factory, true)
+
+		_, err := adapter.FindAll(&Widget{}, []db.Filter{db.Eq("secret", "x")}, db.Pagination{}, nil)
+		require.ErrorIs(t, err, gerrors.ErrBadRequest)
+
+		_, err = adapter.FindAll(&Widget{}, nil, db.Pagination{}, []db.Sort{{Field: "secret", Direction: "asc"}})
+		require.ErrorIs(t, err, gerrors.ErrBadRequest)
+	})
+
+	t.Run("Filters/InvalidValue", func(t *testing.T) {
+		adapter := setup(t, factory, true)
+
+		_, err := adapter.FindAll(&Widget{}, []db.Filter{{Field: "Price", Operator: db.OpGte, Value: "abc"}}, db.Pagination{}, nil)
+		require.ErrorIs(t, err, gerrors.ErrBadRequest)
+
+		_, err = adapter.Count(&Widget{}, []db.Filter{{Field: "ID", Operator: db.OpIn, Value: []string{"1", "two"}}})
+		require.ErrorIs(t, err, gerrors.ErrBadRequest)
+	})
+
+	t.Run("Sort", func(t *testing.T) {
+		adapter := setup(t, factory, true)
+
+		sorts := []db.Sort{{Field: "Category", Direction: "asc"}, {Field: "Price", Direction: "desc"}}
+		result, err := adapter.FindAll(&Widget{}, nil, db.Pagination{}, sorts)
+		require.NoError(t, err)
+		require.Equal(t, []int{4, 1, 2, 5, 3}, widgetIDs(t, result))
+	})
+
+	t.Run("Pagination/Offset", func(t *testing.T) {
+		adapter := setup(t, factory, true)
+		byID := []db.Sort{{Field: "ID", Direction: "asc"}}
+
+		result, err := adapter.FindAll(&Widget{}, nil, db.Pagination{Limit: 2, Offset: 2}, byID)
+		require.NoError(t, err)
+		require.Equal(t, []int{3, 4}, widgetIDs(t, result))
+
+		result, err = adapter.FindAll(&Widget{}, nil, db.Pagination{Limit: 2, Offset: 10}, byID)
+		require.NoError(t, err)
+		require.Empty(t, widgetIDs(t, result))
+	})
+
+	t.Run("Pagination/Keyset", func(t *testing.T) {
+		adapter := setup(t, factory, true)
+
+		byPrice := []db.Sort{{Field: "Price", Direction: "asc"}}
+		result, err := adapter.FindAll(&Widget{}, nil, db.Pagination{Limit: 2, After: []any{2.0}}, byPrice)
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 5}, widgetIDs(t, result))
+
+		byCategory := []db.Sort{{Field: "Category", Direction: "asc"}, {Field: "ID", Direction: "asc"}}
+		result, err = adapter.FindAll(&Widget{}, nil, db.Pagination{After: []any{"fruit", 2}}, byCategory)
+		require.NoError(t, err)
+		require.Equal(t, []int{4, 3, 5}, widgetIDs(t, result))
+	})
+
+	t.Run("Pagination/KeysetTime", func(t *testing.T) {
+		adapter := setup(t, factory, false)
+
+		day := func(d int) time.Time { return time.Date(2026, 1, d, 12, 0, 0, 0, time.UTC) }
+		for _, id := range []int{3, 1, 4, 2} {
+			require.NoError(t, adapter.Create(&Event{ID: id, At: day(id)}))
+		}
+		eventIDs := func(result any) []int {
+			events, ok := result.([]Event)
+			require.True(t, ok, "FindAll returned %T, want []Event", result)
+			ids := make([]int, len(events))
+			for i, e := range events {
+				ids[i] = e.ID
+			}
+			return ids
+		}
+
+		// Cursor values are JSON-decoded, so times arrive as RFC 3339 strings.
+		asc := []db.Sort{{Field: "At", Direction: "asc"}}
+		result, err := adapter.FindAll(&Event{}, nil, db.Pagination{Limit: 2, After: []any{day(2).Format(time.RFC3339Nano)}}, asc)
+		require.NoError(t, err)
+		require.Equal(t, []int{3, 4}, eventIDs(result))
+
+		desc := []db.Sort{{Field: "At", Direction: "desc"}}
+		result, err = adapter.FindAll(&Event{}, nil, db.Pagination{Limit: 2, After: []any{day(3).Format(time.RFC3339Nano)}}, desc)
+		require.NoError(t, err)
+		require.Equal(t, []int{2, 1}, eventIDs(result))
+	})
+
+	t.Run("Select", func(t *testing.T) {
+		adapter := setup(t, factory, true).Select("Name")
+
+		result, err := adapter.FindAll(&Widget{}, []db.Filter{db.Eq("Category", "vegetable")}, db.Pagination{}, []db.Sort{{Field: "ID", Direction: "asc"}})
+		require.NoError(t, err)
+		require.Equal(t, []Widget{{ID: 3, Name: "carrot"}, {ID: 5, Name: "eggplant"}}, result)
+
+		found, err := adapter.FindByID("1", &Widget{})
+		require.NoError(t, err)
+		require.Equal(t, &Widget{ID: 1, Name: "apple"}, found)
+
+		_, err = adapter.Select("Unknown").FindByID("1", &Widget{})
+		require.ErrorIs(t, err, gerrors.ErrBadRequest)
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		adapter := setup(t, factory, true)
+		asc := []db.Sort{{Field: "ID", Direction: "asc"}}
+
+		result, err := adapter.Search("fruit", "Name", "Category").FindAll(&Widget{}, nil, db.Pagination{}, asc)
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 2, 4}, widgetIDs(t, result))
+
+		// Every word has to match, in any of the fields and regardless of case.
+		searched := adapter.Search("Fruit, APPLE!", "Name", "Category")
+		result, err = searched.FindAll(&Widget{}, nil, db.Pagination{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, []int{1}, widgetIDs(t, result))
+
+		count, err := searched.Count(&Widget{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), count)
+
+		result, err = adapter.Search("sweet", "Nickname").FindAll(&Widget{}, []db.Filter{db.Eq("Category", "fruit")}, db.Pagination{}, asc)
+		require.NoError(t, err)
+		require.Equal(t, []int{4}, widgetIDs(t, result))
+
+		count, err = adapter.Search("  ", "Name").Count(&Widget{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, int64(5), count)
+
+		_, err = adapter.Search("fruit", "Unknown").FindAll(&Widget{}, nil, db.Pagination{}, nil)
+		require.ErrorIs(t, err, gerrors.ErrBadRequest)
+	})
+
+	t.Run("Aggregate", func(t *testing.T) {
+		adapter := setup(t, factory, true)
+		metrics := []db.Metric{
+			{Func: db.AggCount},
+			{Func: db.AggSum, Field: "Price"},
+			{Func: db.AggAvg, Field: "Price"},
+			{Func: db.AggMin, Field: "Price"},
+			{Func: db.AggMax, Field: "Price"},
+		}
+
+		rows, err := adapter.Aggregate(&Widget{}, nil, []string{"Category"}, metrics)
+		require.NoError(t, err)
+		require.Equal(t, []db.AggregateRow{
+			{Group: []any{"fruit"}, Metrics: []any{int64(3), 9.0, 3.0, 1.0, 5.0}},
+			{Group: []any{"vegetable"}, Metrics: []any{int64(2), 6.0, 3.0, 2.0, 4.0}},
+		}, rows)
+
+		rows, err = adapter.Aggregate(&Widget{}, []db.Filter{{Field: "Price", Operator: db.OpGt, Value: 2}}, []string{"Category"}, metrics[:1])
+		require.NoError(t, err)
+		require.Equal(t, []db.AggregateRow{
+			{Group: []any{"fruit"}, Metrics: []any{int64(2)}},
+			{Group: []any{"vegetable"}, Metrics: []any{int64(1)}},
+		}, rows)
+
+		rows, err = adapter.Aggregate(&Widget{}, nil, nil, metrics[:2])
+		require.NoError(t, err)
+		require.Equal(t, []db.AggregateRow{{Group: []any{}, Metrics: []any{int64(5), 15.0}}}, rows)
+
+		// Without groups there is a row even when nothing matches.
+		rows, err = adapter.Aggregate(&Widget{}, []db.Filter{db.Eq("Category", "mineral")}, nil, metrics[:2])
+		require.NoError(t, err)
+		require.Equal(t, []db.AggregateRow{{Group: []any{}, Metrics: []any{int64(0), nil}}}, rows)
+
+		_, err = adapter.Aggregate(&Widget{}, nil, []string{"Unknown"}, metrics[:1])
+		require.ErrorIs(t, err, gerrors.ErrBadRequest)
+	})
+
+	t.Run("FindAll/Empty", func(t *testing.T) {
+		adapter := setup(t, factory, false)
+
+		result, err := adapter.FindAll(&Tag{}, nil, db.Pagination{}, nil)
+		require.NoError(t, err)
+		require.IsType(t, []Tag{}, result)
+		require.Empty(t, result)
+	})
+}
+
+// setup migrates the suite's entities and, when seeded, stores these widgets:
+//
+//	ID  Name      Category   Price  Nickname
+//	1   apple     fruit      3      red
+//	2   banana    fruit      1      -
+//	3   carrot    vegetable  2      -
+//	4   date      fruit      5      sweet
+//	5   eggplant  vegetable  4      -
+func setup(t *testing.T, factory Factory, seeded bool) db.DBAdapter {
+	t.Helper()
+
+	adapter := factory(t)
+	require.NoError(t, adapter.Migrate([]any{&Widget{}, &Tag{}, &Event{}}))
+	if !seeded {
+		return adapter
+	}
+
+	red, sweet := "red", "sweet"
+	widgets := []*Widget{
+		{ID: 1, Name: "apple", Category: "fruit", Price: 3, Nickname: &red},
+		{ID: 2, Name: "banana", Category: "fruit", Price: 1},
+		{ID: 3, Name: "carrot", Category: "vegetable", Price: 2},
+		{ID: 4, Name: "date", Category: "fruit", Price: 5, Nickname: &sweet},
+		{ID: 5, Name: "eggplant", Category: "vegetable", Price: 4},
+	}
+	for _, w := range widgets {
+		require.NoError(t, adapter.Create(w))
+	}
+	return adapter
+}
+
+func widgetIDs(t *testing.T, result any) []int {
+	t.Helper()
+
+	widgets, ok := result.([]Widget)
+	require.True(t, ok, "FindAll returned %T, want []Widget", result)
+
+	ids := make([]int, len(widgets))
+	for i, w := range widgets {
+		ids[i] = w.ID
+	}
+	return ids
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Operator string
+
+const (
+	OpEq   Operator = "eq"
+	OpNe   Operator = "ne"
+	OpGt   Operator = "gt"
+	OpGte  Operator = "gte"
+	OpLt   Operator = "lt"
+	OpLte  Operator = "lte"
+	OpIn   Operator = "in"
+	OpLike Operator = "like"
+	OpNull Operator = "null"
+)
+
+// Filter is a single backend-neutral condition; adapters combine filters with AND.
+//
+// Value holds a []string for OpIn, a bool for OpNull and a scalar for every other operator.
+type Filter struct {
+	Field    string
+	Operator Operator
+	Value    any
+}
+
+// Eq is a shorthand for an equality filter.
+func Eq(field string, value any) Filter {
+	return Filter{Field: field, Operator: OpEq, Value: value}
+}
+
+// ParseFilter parses a query parameter such as `age[gte]=18`, `status[in]=a,b`
+// or `name=jo` (plain equality) into a Filter.
+func ParseFilter(key, value string) (Filter, error) {
+	field, op := key, OpEq
+	if i := strings.IndexByte(key, '['); i >= 0 {
+		if !strings.HasSuffix(key, "]") {
+			return Filter{}, fmt.Errorf("malformed filter %q", key)
+		}
+		field, op = key[:i], Operator(key[i+1:len(key)-1])
+	}
+	if field == "" {
+		return Filter{}, fmt.Errorf("malformed filter %q", key)
+	}
+
+	switch op {
+	case OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpLike:
+		return Filter{Field: field, Operator: op, Value: value}, nil
+	case OpIn:
+		return Filter{Field: field, Operator: op, Value: strings.Split(value, ",")}, nil
+	case OpNull:
+		isNull, err := strconv.ParseBool(value)
+		if err != nil {
+			return Filter{}, fmt.Errorf("filter %q expects true or false", key)
+		}
+		return Filter{Field: field, Operator: op, Value: isNull}, nil
+	default:
+		return Filter{}, fmt.Errorf("unsupported filter operator %q", op)
+	}
+}
+
+// OperatorsFor returns the operators that make sense for a field of type t.
+func OperatorsFor(t reflect.Type) []Operator {
+	nullable := false
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var ops []Operator
+	switch {
+	case t.Kind() == reflect.String:
+		ops = []Operator{OpEq, OpNe, OpIn, OpLike}
+	case t.Kind() == reflect.Bool:
+		ops = []Operator{OpEq, OpNe}
+	case t == reflect.TypeOf(time.Time{}):
+		ops = []Operator{OpEq, OpNe, OpGt, OpGte, OpLt, OpLte}
+		nullable = true
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Float64:
+		ops = []Operator{OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpIn}
+	default:
+		return nil
+	}
+
+	if nullable {
+		ops = append(ops, OpNull)
+	}
+	return ops
+}
+
+// LikeToRegexp translates a SQL LIKE pattern (% and _ wildcards) into an anchored regular expression.
+func LikeToRegexp(pattern string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			sb.WriteString(".*")
+		case '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return sb.String()
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilter_Equality(t *testing.T) {
+	f, err := ParseFilter("name", "alice")
+	require.NoError(t, err)
+	require.Equal(t, Filter{Field: "name", Operator: OpEq, Value: "alice"}, f)
+}
+
+func TestParseFilter_Operators(t *testing.T) {
+	f, err := ParseFilter("age[gte]", "18")
+	require.NoError(t, err)
+	require.Equal(t, Filter{Field: "age", Operator: OpGte, Value: "18"}, f)
+
+	f, err = ParseFilter("status[in]", "a,b")
+	require.NoError(t, err)
+	require.Equal(t, Filter{Field: "status", Operator: OpIn, Value: []string{"a", "b"}}, f)
+
+	f, err = ParseFilter("deleted_at[null]", "true")
+	require.NoError(t, err)
+	require.Equal(t, Filter{Field: "deleted_at", Operator: OpNull, Value: true}, f)
+}
+
+func TestParseFilter_Invalid(t *testing.T) {
+	_, err := ParseFilter("age[between]", "1")
+	require.Error(t, err)
+
+	_, err = ParseFilter("age[gte", "1")
+	require.Error(t, err)
+
+	_, err = ParseFilter("[eq]", "1")
+	require.Error(t, err)
+
+	_, err = ParseFilter("deleted_at[null]", "maybe")
+	require.Error(t, err)
+}
+
+func TestOperatorsFor(t *testing.T) {
+	require.Contains(t, OperatorsFor(reflect.TypeOf("")), OpLike)
+	require.NotContains(t, OperatorsFor(reflect.TypeOf("")), OpGt)
+	require.Contains(t, OperatorsFor(reflect.TypeOf(0)), OpGte)
+	require.NotContains(t, OperatorsFor(reflect.TypeOf(0)), OpNull)
+	require.Contains(t, OperatorsFor(reflect.TypeOf(&time.Time{})), OpNull)
+	require.Nil(t, OperatorsFor(reflect.TypeOf(struct{}{})))
+}
+
+func TestLikeToRegexp_EscapesMeta(t *testing.T) {
+	require.Equal(t, `^a\.b.c.*$`, LikeToRegexp("a.b_c%"))
+}
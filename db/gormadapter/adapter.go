@@ -0,0 +1,805 @@
+package gormadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Lumicrate/gompose/db"
+	gerrors "github.com/Lumicrate/gompose/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Adapter implements db.DBAdapter on top of GORM. The SQL adapters (postgres, mysql,
+// sqlite) embed it and only contribute the dialector and their driver's error codes.
+type Adapter struct {
+	dialector gorm.Dialector
+	options   Options
+	db        *gorm.DB
+
+	// replicaConfig is set by SetReplicas and connected on Init. replicas is nil in
+	// transactions, whose reads must see their own writes.
+	replicaConfig *Replicas
+	replicas      *replicaSet
+
+	// translate maps driver errors GORM does not translate itself.
+	translate func(error) error
+
+	// withDeleted disables the soft-delete scope on reads.
+	withDeleted bool
+	preloads    []string
+	selects     []string
+
+	// search and searchFields are set by Search.
+	search       string
+	searchFields []string
+}
+
+// New returns an adapter that connects through dialector on Init.
+func New(dialector gorm.Dialector, translate func(error) error) *Adapter {
+	return NewWithOptions(dialector, translate, Options{})
+}
+
+// NewWithOptions is New with a tuned connection pool and logger.
+func NewWithOptions(dialector gorm.Dialector, translate func(error) error, opts Options) *Adapter {
+	return &Adapter{dialector: dialector, options: opts, translate: translate}
+}
+
+// FromDB wraps an already opened connection; Init is then a no-op.
+func FromDB(conn *gorm.DB, translate func(error) error) *Adapter {
+	return &Adapter{db: conn, translate: translate}
+}
+
+func (a *Adapter) Init() error {
+	if a.db != nil {
+		return nil
+	}
+	config, err := a.options.gormConfig()
+	if err != nil {
+		return err
+	}
+	conn, err := gorm.Open(a.dialector, config)
+	if err != nil {
+		return err
+	}
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return err
+	}
+	a.options.applyPool(sqlDB)
+
+	if a.replicaConfig != nil {
+		if a.replicas, err = openReplicas(*a.replicaConfig, a.options); err != nil {
+			sqlDB.Close()
+			return err
+		}
+	}
+	a.db = conn
+	return nil
+}
+
+// Ping checks that the primary database is reachable.
+func (a *Adapter) Ping() error {
+	if a.db == nil {
+		return errors.New("adapter is not initialized")
+	}
+	sqlDB, err := a.db.DB()
+	if err != nil {
+		return err
+	}
+	return a.translateError(sqlDB.PingContext(a.db.Statement.Context))
+}
+
+// Close closes the connection pool. The adapter and its copies cannot be used afterwards.
+func (a *Adapter) Close() error {
+	if a.db == nil {
+		return nil
+	}
+	if a.replicas != nil {
+		if err := a.replicas.close(); err != nil {
+			return err
+		}
+	}
+	sqlDB, err := a.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// DB returns the underlying GORM connection.
+func (a *Adapter) DB() *gorm.DB {
+	return a.db
+}
+
+// ExecSQL runs a raw SQL script, as SQL-file migrations do.
+func (a *Adapter) ExecSQL(ctx context.Context, query string) error {
+	return a.translateError(a.db.WithContext(ctx).Exec(query).Error)
+}
+
+func (a *Adapter) Migrate(entities []any) error {
+	for _, entity := range entities {
+		if err := a.db.AutoMigrate(entity); err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+	}
+	return nil
+}
+
+func (a *Adapter) WithContext(ctx context.Context) db.DBAdapter {
+	return a.with(a.db.WithContext(ctx))
+}
+
+func (a *Adapter) WithTransaction(fn func(tx db.DBAdapter) error) error {
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		clone := a.with(tx)
+		clone.replicas = nil
+		return fn(clone)
+	})
+}
+
+func (a *Adapter) IncludeDeleted() db.DBAdapter {
+	clone := a.with(a.db)
+	clone.withDeleted = true
+	return clone
+}
+
+func (a *Adapter) Preload(relations ...string) db.DBAdapter {
+	clone := a.with(a.db)
+	clone.preloads = append(append([]string{}, a.preloads...), relations...)
+	return clone
+}
+
+func (a *Adapter) Select(fields ...string) db.DBAdapter {
+	clone := a.with(a.db)
+	clone.selects = append(append([]string{}, a.selects...), fields...)
+	return clone
+}
+
+func (a *Adapter) Search(query string, fields ...string) db.DBAdapter {
+	clone := a.with(a.db)
+	clone.search = query
+	clone.searchFields = fields
+	return clone
+}
+
+// with returns a copy of the adapter bound to conn.
+func (a *Adapter) with(conn *gorm.DB) *Adapter {
+	clone := *a
+	clone.db = conn
+	return &clone
+}
+
+func (a *Adapter) Create(entity any) error {
+	if db.IsVersioned(entity) {
+		db.SetEntityVersion(entity, 1)
+	}
+	return a.translateError(a.db.Create(entity).Error)
+}
+
+func (a *Adapter) Update(entity any) (err error) {
+	sch, err := a.schemaFor(entity)
+	if err != nil {
+		return err
+	}
+
+	deletedAt, soft := deletedAtColumn(sch)
+	version, versioned := versionColumn(sch)
+
+	// Selecting the columns explicitly keeps Save from falling back to an insert when no row matches.
+	id := primaryKey(sch, entity)
+	if isZero(id) {
+		// Save inserts rows without a primary key, whatever the columns selected.
+		return gerrors.NotFound("entity not found")
+	}
+	tx := a.db.Unscoped().Select("*")
+
+	if soft {
+		// Deleted rows cannot be updated, and the deletion mark is never overwritten by a request body.
+		tx = tx.Omit(deletedAt).Where(notDeleted(deletedAt))
+	}
+
+	if versioned {
+		expected := db.EntityVersion(entity)
+		if expected == 0 {
+			// No version given: the update is unconditional, so build on the stored version.
+			if expected, err = a.currentVersion(sch, version, id); err != nil {
+				return err
+			}
+		}
+		tx = tx.Where(clause.Eq{Column: clause.Column{Name: version}, Value: expected})
+
+		// Save writes the incremented version; the caller keeps its own unless it was stored.
+		previous := db.EntityVersion(entity)
+		db.SetEntityVersion(entity, expected+1)
+		defer func() {
+			if err != nil {
+				db.SetEntityVersion(entity, previous)
+			}
+		}()
+	}
+
+	res := tx.Save(entity)
+	if res.Error != nil {
+		return a.translateError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return a.unmatchedWriteError(sch, id)
+	}
+	return nil
+}
+
+func (a *Adapter) Delete(id string, entity any) error {
+	sch, err := a.schemaFor(entity)
+	if err != nil {
+		return err
+	}
+
+	deletedAt, soft := deletedAtColumn(sch)
+	version, versioned := versionColumn(sch)
+	expected := db.EntityVersion(entity)
+
+	tx := a.db
+	if versioned && expected > 0 {
+		tx = tx.Where(clause.Eq{Column: clause.Column{Name: version}, Value: expected})
+	}
+
+	var res *gorm.DB
+	if soft {
+		res = tx.Unscoped().Model(entity).Where("id = ?", id).Where(notDeleted(deletedAt)).Update(deletedAt, time.Now())
+	} else {
+		res = tx.Delete(entity, "id = ?", id)
+	}
+	if res.Error != nil {
+		return a.translateError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return a.unmatchedWriteError(sch, id)
+	}
+	return nil
+}
+
+// createBatchSize bounds the rows of one INSERT statement written by CreateMany.
+const createBatchSize = 500
+
+func (a *Adapter) CreateMany(entities []any) error {
+	if len(entities) == 0 {
+		return nil
+	}
+
+	// CreateInBatches needs a typed slice to build multi-row INSERTs and back-fill generated IDs.
+	rows := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(entities[0])), 0, len(entities))
+	for i, entity := range entities {
+		if reflect.TypeOf(entity) != rows.Type().Elem() {
+			return &db.BatchError{Index: i, Err: fmt.Errorf("expected %s, got %T", rows.Type().Elem(), entity)}
+		}
+		if db.IsVersioned(entity) {
+			db.SetEntityVersion(entity, 1)
+		}
+		rows = reflect.Append(rows, reflect.ValueOf(entity))
+	}
+
+	sch, err := a.schemaFor(entities[0])
+	if err != nil {
+		return err
+	}
+	// Rows without an ID get one from the batch, which the retry below must not reuse.
+	generated := make([]bool, len(entities))
+	for i, entity := range entities {
+		generated[i] = isZero(primaryKey(sch, entity))
+	}
+
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.SavePoint("create_many").Error; err != nil {
+			return a.translateError(err)
+		}
+		err := tx.CreateInBatches(rows.Interface(), createBatchSize).Error
+		if err == nil {
+			return nil
+		}
+
+		// A multi-row INSERT does not tell which row failed, so the batch is rolled back and
+		// the rows are inserted one by one until the failing one is found.
+		if err := tx.RollbackTo("create_many").Error; err != nil {
+			return a.translateError(err)
+		}
+		for i, entity := range entities {
+			if generated[i] {
+				clearPrimaryKey(sch, entity)
+			}
+			if err := tx.Create(entity).Error; err != nil {
+				return &db.BatchError{Index: i, Err: a.translateError(err)}
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateMany updates row by row, since every row may carry its own version condition.
+func (a *Adapter) UpdateMany(entities []any) error {
+	return a.WithTransaction(func(tx db.DBAdapter) error {
+		for i, entity := range entities {
+			if err := tx.Update(entity); err != nil {
+				return &db.BatchError{Index: i, Err: err}
+			}
+		}
+		return nil
+	})
+}
+
+func (a *Adapter) DeleteMany(ids []string, entity any) error {
+	return a.WithTransaction(func(tx db.DBAdapter) error {
+		for i, id := range ids {
+			if err := tx.Delete(id, entity); err != nil {
+				return &db.BatchError{Index: i, Err: err}
+			}
+		}
+		return nil
+	})
+}
+
+func (a *Adapter) Upsert(entity any) (bool, error) {
+	var created bool
+	err := a.WithTransaction(func(tx db.DBAdapter) error {
+		var err error
+		created, err = db.UpsertWith(tx, entity)
+		return err
+	})
+	return created, err
+}
+
+func (a *Adapter) Restore(id string, entity any) error {
+	sch, err := a.schemaFor(entity)
+	if err != nil {
+		return err
+	}
+
+	column, ok := deletedAtColumn(sch)
+	if !ok {
+		return gerrors.BadRequest(fmt.Sprintf("%s does not support soft delete", sch.Name))
+	}
+
+	res := a.db.Unscoped().Model(entity).
+		Where("id = ?", id).
+		Where(clause.Neq{Column: clause.Column{Name: column}, Value: nil}).
+		Update(column, nil)
+	if res.Error != nil {
+		return a.translateError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return gerrors.NotFound("deleted entity not found")
+	}
+	return nil
+}
+
+func (a *Adapter) FindAll(entity any, filters []db.Filter, pagination db.Pagination, sort []db.Sort) (any, error) {
+	entityType := reflect.TypeOf(entity)
+	if entityType.Kind() == reflect.Ptr {
+		entityType = entityType.Elem()
+	}
+
+	sliceType := reflect.SliceOf(entityType)
+
+	resultValue := reflect.New(sliceType) // *([]Entity)
+
+	sch, err := a.schemaFor(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := a.preloaded(sch, a.scoped(a.reader(), sch).Model(entity))
+	if err != nil {
+		return nil, err
+	}
+	if tx, err = a.selected(sch, tx); err != nil {
+		return nil, err
+	}
+
+	for _, f := range filters {
+		expr, err := filterExpression(sch, f)
+		if err != nil {
+			return nil, err
+		}
+		tx = tx.Where(expr)
+	}
+
+	if tx, err = a.searched(sch, tx, len(sort) == 0); err != nil {
+		return nil, err
+	}
+
+	if len(pagination.After) > 0 {
+		expr, err := keysetCondition(sch, sort, pagination.After)
+		if err != nil {
+			return nil, err
+		}
+		tx = tx.Where(expr)
+	}
+
+	for _, s := range sort {
+		column, err := columnFor(sch, s.Field)
+		if err != nil {
+			return nil, err
+		}
+		tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: s.Direction == "desc"})
+	}
+
+	if pagination.Limit > 0 {
+		tx = tx.Limit(pagination.Limit)
+	}
+
+	if pagination.Offset > 0 && len(pagination.After) == 0 {
+		tx = tx.Offset(pagination.Offset)
+	}
+
+	if err := tx.Find(resultValue.Interface()).Error; err != nil {
+		return nil, a.translateError(err)
+	}
+
+	result := resultValue.Elem().Interface()
+
+	return result, nil
+}
+
+func (a *Adapter) FindByID(id string, entity any) (any, error) {
+	sch, err := a.schemaFor(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := a.preloaded(sch, a.scoped(a.reader(), sch))
+	if err != nil {
+		return nil, err
+	}
+	if tx, err = a.selected(sch, tx); err != nil {
+		return nil, err
+	}
+
+	err = tx.First(entity, "id = ?", id).Error
+	if err != nil {
+		return nil, a.translateError(err)
+	}
+	return entity, nil
+}
+
+func (a *Adapter) Count(entity any, filters []db.Filter) (int64, error) {
+	sch, err := a.schemaFor(entity)
+	if err != nil {
+		return 0, err
+	}
+
+	tx := a.scoped(a.reader(), sch).Model(entity)
+
+	for _, f := range filters {
+		expr, err := filterExpression(sch, f)
+		if err != nil {
+			return 0, err
+		}
+		tx = tx.Where(expr)
+	}
+
+	tx, err = a.searched(sch, tx, false)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	if err := tx.Count(&count).Error; err != nil {
+		return 0, a.translateError(err)
+	}
+	return count, nil
+}
+
+// schemaFor parses the entity with GORM's naming strategy so query fields can be mapped to real columns.
+func (a *Adapter) schemaFor(entity any) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: a.db}
+	if err := stmt.Parse(entity); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+// reader returns the connection serving reads: a healthy replica, or the primary in
+// transactions, for contexts marked with db.WithPrimary and when no replica is healthy.
+func (a *Adapter) reader() *gorm.DB {
+	if a.replicas == nil || db.ReadsPrimary(a.db.Statement.Context) {
+		return a.db
+	}
+	conn := a.replicas.pick()
+	if conn == nil {
+		return a.db
+	}
+	return conn.WithContext(a.db.Statement.Context)
+}
+
+// scoped applies the soft-delete scope of the entity to conn. The scope is built here
+// rather than left to gorm.DeletedAt so that *time.Time fields behave the same way.
+func (a *Adapter) scoped(conn *gorm.DB, sch *schema.Schema) *gorm.DB {
+	column, ok := deletedAtColumn(sch)
+	if !ok {
+		return conn
+	}
+	if a.withDeleted {
+		return conn.Unscoped()
+	}
+	return conn.Unscoped().Where(notDeleted(column))
+}
+
+// preloaded eager-loads the requested relations. Only associations GORM knows
+// about can be preloaded, so relation names never reach the query unchecked.
+func (a *Adapter) preloaded(sch *schema.Schema, tx *gorm.DB) (*gorm.DB, error) {
+	for _, name := range a.preloads {
+		if _, ok := sch.Relationships.Relations[name]; !ok {
+			return nil, gerrors.BadRequest(fmt.Sprintf("unknown relation %q", name))
+		}
+		tx = tx.Preload(name)
+	}
+	return tx, nil
+}
+
+// selected restricts the loaded columns to the selected fields.
+func (a *Adapter) selected(sch *schema.Schema, tx *gorm.DB) (*gorm.DB, error) {
+	if len(a.selects) == 0 {
+		return tx, nil
+	}
+	var columns []string
+	for _, name := range db.SelectedFields(sch.ModelType, a.selects, a.preloads) {
+		column, err := columnFor(sch, name)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return tx.Select(columns), nil
+}
+
+// currentVersion reads the stored version of the row with the given primary key.
+func (a *Adapter) currentVersion(sch *schema.Schema, column string, id any) (int64, error) {
+	var versions []int64
+	err := a.scoped(a.db, sch).Table(sch.Table).Where("id = ?", id).Pluck(column, &versions).Error
+	if err != nil {
+		return 0, a.translateError(err)
+	}
+	if len(versions) == 0 {
+		return 0, gerrors.NotFound("entity not found")
+	}
+	return versions[0], nil
+}
+
+// unmatchedWriteError explains a conditional write that matched no row:
+// either the row is gone or its version moved on.
+func (a *Adapter) unmatchedWriteError(sch *schema.Schema, id any) error {
+	var count int64
+	if err := a.scoped(a.db, sch).Table(sch.Table).Where("id = ?", id).Count(&count).Error; err != nil {
+		return a.translateError(err)
+	}
+	if count == 0 {
+		return gerrors.NotFound("entity not found")
+	}
+	return gerrors.PreconditionFailed("entity was modified by another request")
+}
+
+func clearPrimaryKey(sch *schema.Schema, entity any) {
+	if field := sch.PrioritizedPrimaryField; field != nil {
+		_ = field.Set(context.Background(), reflect.Indirect(reflect.ValueOf(entity)), reflect.Zero(field.FieldType).Interface())
+	}
+}
+
+func isZero(v any) bool {
+	return v == nil || reflect.ValueOf(v).IsZero()
+}
+
+func primaryKey(sch *schema.Schema, entity any) any {
+	if sch.PrioritizedPrimaryField == nil {
+		return nil
+	}
+	id, _ := sch.PrioritizedPrimaryField.ValueOf(context.Background(), reflect.Indirect(reflect.ValueOf(entity)))
+	return id
+}
+
+func versionColumn(sch *schema.Schema) (string, bool) {
+	field := sch.LookUpField(db.VersionField)
+	if field == nil || field.DBName == "" {
+		return "", false
+	}
+	return field.DBName, true
+}
+
+func deletedAtColumn(sch *schema.Schema) (string, bool) {
+	field := sch.LookUpField(db.DeletedAtField)
+	if field == nil || field.DBName == "" {
+		return "", false
+	}
+	return field.DBName, true
+}
+
+func notDeleted(column string) clause.Expression {
+	return clause.Eq{Column: clause.Column{Name: column}, Value: nil}
+}
+
+// columnFor resolves a field name or column name to a column of the entity's table.
+// Anything else is rejected, so query parameters never reach the SQL text.
+func columnFor(sch *schema.Schema, name string) (string, error) {
+	field, err := fieldFor(sch, name)
+	if err != nil {
+		return "", err
+	}
+	return field.DBName, nil
+}
+
+func fieldFor(sch *schema.Schema, name string) (*schema.Field, error) {
+	field := sch.LookUpField(name)
+	if field == nil || field.DBName == "" {
+		return nil, gerrors.BadRequest(fmt.Sprintf("unknown field %q", name))
+	}
+	return field, nil
+}
+
+// fieldValue converts a filter or cursor value, typically a query string or a JSON-decoded
+// value, to the type of field. Without it the database compares the text, or rejects it.
+func fieldValue(field *schema.Field, value any) (any, error) {
+	t := field.IndirectFieldType
+	invalid := gerrors.BadRequest(fmt.Sprintf("invalid value %v for field %q", value, field.Name))
+
+	if s, ok := value.(string); ok {
+		var parsed any
+		var err error
+		switch {
+		case t.Kind() == reflect.String:
+			return s, nil
+		case isInt(t.Kind()):
+			parsed, err = strconv.ParseInt(s, 10, 64)
+		case isUint(t.Kind()):
+			parsed, err = strconv.ParseUint(s, 10, 64)
+		case isFloat(t.Kind()):
+			parsed, err = strconv.ParseFloat(s, 64)
+		case t.Kind() == reflect.Bool:
+			parsed, err = strconv.ParseBool(s)
+		case t == reflect.TypeOf(time.Time{}):
+			parsed, err = time.Parse(time.RFC3339Nano, s)
+		default:
+			// Custom column types parse their own text.
+			return s, nil
+		}
+		if err != nil {
+			return nil, invalid
+		}
+		return parsed, nil
+	}
+
+	// JSON numbers decode as float64, whatever the column.
+	v := reflect.ValueOf(value)
+	if v.IsValid() && isNumber(v.Kind()) && isNumber(t.Kind()) {
+		return v.Convert(t).Interface(), nil
+	}
+	return value, nil
+}
+
+func isInt(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUint(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uint64
+}
+
+func isFloat(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
+
+func isNumber(k reflect.Kind) bool {
+	return isInt(k) || isUint(k) || isFloat(k)
+}
+
+func filterExpression(sch *schema.Schema, f db.Filter) (clause.Expression, error) {
+	field, err := fieldFor(sch, f.Field)
+	if err != nil {
+		return nil, err
+	}
+	col := clause.Column{Name: field.DBName}
+
+	var value any
+	if f.Operator != db.OpIn && f.Operator != db.OpLike && f.Operator != db.OpNull {
+		if value, err = fieldValue(field, f.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	switch f.Operator {
+	case db.OpEq:
+		return clause.Eq{Column: col, Value: value}, nil
+	case db.OpNe:
+		return clause.Neq{Column: col, Value: value}, nil
+	case db.OpGt:
+		return clause.Gt{Column: col, Value: value}, nil
+	case db.OpGte:
+		return clause.Gte{Column: col, Value: value}, nil
+	case db.OpLt:
+		return clause.Lt{Column: col, Value: value}, nil
+	case db.OpLte:
+		return clause.Lte{Column: col, Value: value}, nil
+	case db.OpIn:
+		values := reflect.ValueOf(f.Value)
+		if values.Kind() != reflect.Slice {
+			values = reflect.ValueOf([]any{f.Value})
+		}
+		in := make([]any, values.Len())
+		for i := range in {
+			if in[i], err = fieldValue(field, values.Index(i).Interface()); err != nil {
+				return nil, err
+			}
+		}
+		return clause.IN{Column: col, Values: in}, nil
+	case db.OpLike:
+		return clause.Like{Column: col, Value: f.Value}, nil
+	case db.OpNull:
+		// clause.Eq/Neq with a nil value render IS NULL / IS NOT NULL
+		if isNull, _ := f.Value.(bool); isNull {
+			return clause.Eq{Column: col, Value: nil}, nil
+		}
+		return clause.Neq{Column: col, Value: nil}, nil
+	default:
+		return nil, gerrors.BadRequest(fmt.Sprintf("unsupported filter operator %q", f.Operator))
+	}
+}
+
+// keysetCondition builds `(a > ?) OR (a = ? AND b > ?) ...` so rows strictly after the
+// given sort key values are selected, honouring the direction of every sort key.
+func keysetCondition(sch *schema.Schema, sort []db.Sort, after []any) (clause.Expression, error) {
+	if len(after) != len(sort) {
+		return nil, gerrors.BadRequest(fmt.Sprintf("cursor has %d values but %d sort fields", len(after), len(sort)))
+	}
+
+	columns := make([]clause.Column, len(sort))
+	values := make([]any, len(after))
+	for i, s := range sort {
+		field, err := fieldFor(sch, s.Field)
+		if err != nil {
+			return nil, err
+		}
+		columns[i] = clause.Column{Name: field.DBName}
+		// Cursors hold JSON values; times compared as text would not order like the column.
+		if values[i], err = fieldValue(field, after[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	var clauses []clause.Expression
+	for i, s := range sort {
+		var parts []clause.Expression
+		for j := 0; j < i; j++ {
+			parts = append(parts, clause.Eq{Column: columns[j], Value: values[j]})
+		}
+
+		if s.Direction == "desc" {
+			parts = append(parts, clause.Lt{Column: columns[i], Value: values[i]})
+		} else {
+			parts = append(parts, clause.Gt{Column: columns[i], Value: values[i]})
+		}
+
+		clauses = append(clauses, clause.And(parts...))
+	}
+
+	return clause.Or(clauses...), nil
+}
+
+// translateError maps GORM errors, then driver specific ones, onto gompose errors.
+func (a *Adapter) translateError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return gerrors.NotFound("entity not found").Wrap(err)
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return gerrors.Conflict("entity already exists").Wrap(err)
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return gerrors.Conflict("entity is referenced by or references a missing entity").Wrap(err)
+	}
+	if a.translate != nil {
+		return a.translate(err)
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+package gormadapter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Lumicrate/gompose/db"
+	gerrors "github.com/Lumicrate/gompose/errors"
+	"gorm.io/gorm/schema"
+)
+
+func (a *Adapter) Aggregate(entity any, filters []db.Filter, groupBy []string, metrics []db.Metric) ([]db.AggregateRow, error) {
+	sch, err := a.schemaFor(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := a.scoped(a.reader(), sch).Model(entity)
+	for _, f := range filters {
+		expr, err := filterExpression(sch, f)
+		if err != nil {
+			return nil, err
+		}
+		tx = tx.Where(expr)
+	}
+	if tx, err = a.searched(sch, tx, false); err != nil {
+		return nil, err
+	}
+
+	var selects, groups []string
+	for i, name := range groupBy {
+		column, err := columnFor(sch, name)
+		if err != nil {
+			return nil, err
+		}
+		quoted := tx.Statement.Quote(column)
+		groups = append(groups, quoted)
+		selects = append(selects, fmt.Sprintf("%s AS g%d", quoted, i))
+	}
+	for i, m := range metrics {
+		expr, err := metricExpression(sch, m, tx.Statement.Quote)
+		if err != nil {
+			return nil, err
+		}
+		selects = append(selects, fmt.Sprintf("%s AS m%d", expr, i))
+	}
+
+	tx = tx.Select(strings.Join(selects, ", "))
+	if len(groups) > 0 {
+		tx = tx.Group(strings.Join(groups, ", ")).Order(strings.Join(groups, ", "))
+	}
+
+	rows, err := tx.Rows()
+	if err != nil {
+		return nil, a.translateError(err)
+	}
+	defer rows.Close()
+
+	var result []db.AggregateRow
+	for rows.Next() {
+		values := make([]any, len(groupBy)+len(metrics))
+		pointers := make([]any, len(values))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, a.translateError(err)
+		}
+
+		row := db.AggregateRow{Group: values[:len(groupBy)], Metrics: make([]any, len(metrics))}
+		for i, v := range row.Group {
+			if b, ok := v.([]byte); ok {
+				row.Group[i] = string(b)
+			}
+		}
+		for i, m := range metrics {
+			if row.Metrics[i], err = db.MetricValue(m.Func, values[len(groupBy)+i]); err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, row)
+	}
+	return result, a.translateError(rows.Err())
+}
+
+// metricExpression renders a metric as SQL. Only the function names known to db reach the
+// SQL text, and fields are resolved to columns of the entity.
+func metricExpression(sch *schema.Schema, m db.Metric, quote func(any) string) (string, error) {
+	if m.Func == db.AggCount {
+		return "COUNT(*)", nil
+	}
+
+	column, err := columnFor(sch, m.Field)
+	if err != nil {
+		return "", err
+	}
+	switch m.Func {
+	case db.AggSum, db.AggAvg, db.AggMin, db.AggMax:
+		return fmt.Sprintf("%s(%s)", strings.ToUpper(string(m.Func)), quote(column)), nil
+	default:
+		return "", gerrors.BadRequest(fmt.Sprintf("unsupported metric %q", m.Func))
+	}
+}
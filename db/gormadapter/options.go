@@ -0,0 +1,59 @@
+package gormadapter
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Options tunes the connection pool and logging of a GORM adapter. Zero values keep the
+// defaults of database/sql and GORM.
+type Options struct {
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+
+	// LogLevel is one of silent, error, warn or info (every statement).
+	LogLevel string `yaml:"log_level"`
+}
+
+// gormConfig returns the GORM configuration of the options.
+func (o Options) gormConfig() (*gorm.Config, error) {
+	config := &gorm.Config{TranslateError: true}
+	if o.LogLevel == "" {
+		return config, nil
+	}
+
+	levels := map[string]logger.LogLevel{
+		"silent": logger.Silent,
+		"error":  logger.Error,
+		"warn":   logger.Warn,
+		"info":   logger.Info,
+	}
+	level, ok := levels[o.LogLevel]
+	if !ok {
+		return nil, fmt.Errorf("unknown log level %q", o.LogLevel)
+	}
+	config.Logger = logger.Default.LogMode(level)
+	return config, nil
+}
+
+// applyPool configures the connection pool of conn.
+func (o Options) applyPool(conn *sql.DB) {
+	if o.MaxOpenConns > 0 {
+		conn.SetMaxOpenConns(o.MaxOpenConns)
+	}
+	if o.MaxIdleConns > 0 {
+		conn.SetMaxIdleConns(o.MaxIdleConns)
+	}
+	if o.ConnMaxLifetime > 0 {
+		conn.SetConnMaxLifetime(o.ConnMaxLifetime)
+	}
+	if o.ConnMaxIdleTime > 0 {
+		conn.SetConnMaxIdleTime(o.ConnMaxIdleTime)
+	}
+}
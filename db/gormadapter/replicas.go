@@ -0,0 +1,185 @@
+package gormadapter
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReplicaPolicy picks the replica serving a read among the healthy ones.
+type ReplicaPolicy string
+
+const (
+	RoundRobin ReplicaPolicy = "round_robin"
+	Random     ReplicaPolicy = "random"
+)
+
+// replicaPingTimeout bounds each health check ping, so replicas that are down cannot hold
+// up Init for long.
+const replicaPingTimeout = 2 * time.Second
+
+// Replicas routes reads to read replicas, see SetReplicas.
+type Replicas struct {
+	Dialectors []gorm.Dialector
+
+	// Policy defaults to RoundRobin.
+	Policy ReplicaPolicy
+
+	// HealthCheckInterval is how often replicas are pinged, 10 seconds by default.
+	HealthCheckInterval time.Duration
+}
+
+// SetReplicas makes FindAll, FindByID, Count and Aggregate read from the replicas, which
+// are connected on Init with the options of the primary. Writes, transactions and contexts
+// marked with db.WithPrimary use the primary, as do reads while no replica is healthy.
+func (a *Adapter) SetReplicas(r Replicas) *Adapter {
+	a.replicaConfig = &r
+	return a
+}
+
+type replica struct {
+	db      *gorm.DB
+	healthy atomic.Bool
+}
+
+// replicaSet holds the replica connections shared by an adapter and its copies.
+type replicaSet struct {
+	replicas []*replica
+	policy   ReplicaPolicy
+	next     atomic.Uint64
+	timeout  time.Duration
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// openReplicas connects to the replicas and starts their health checks. The first check
+// takes at most one ping timeout. Replicas that are down keep their connection pool and
+// join once a health check reaches them.
+func openReplicas(r Replicas, opts Options) (*replicaSet, error) {
+	switch r.Policy {
+	case "":
+		r.Policy = RoundRobin
+	case RoundRobin, Random:
+	default:
+		return nil, fmt.Errorf("unknown replica policy %q", r.Policy)
+	}
+	if r.HealthCheckInterval <= 0 {
+		r.HealthCheckInterval = 10 * time.Second
+	}
+
+	set := &replicaSet{
+		policy:  r.Policy,
+		timeout: min(r.HealthCheckInterval, replicaPingTimeout),
+		stop:    make(chan struct{}),
+	}
+	for _, dialector := range r.Dialectors {
+		config, err := opts.gormConfig()
+		if err != nil {
+			return nil, err
+		}
+		config.DisableAutomaticPing = true
+
+		conn, err := gorm.Open(dialector, config)
+		if err != nil {
+			set.close()
+			return nil, err
+		}
+		sqlDB, err := conn.DB()
+		if err != nil {
+			set.close()
+			return nil, err
+		}
+		opts.applyPool(sqlDB)
+		set.replicas = append(set.replicas, &replica{db: conn})
+	}
+
+	set.check()
+	go set.watch(r.HealthCheckInterval)
+	return set, nil
+}
+
+// pick returns a healthy replica, or nil when there is none.
+func (s *replicaSet) pick() *gorm.DB {
+	n := len(s.replicas)
+	if n == 0 {
+		return nil
+	}
+
+	var start int
+	if s.policy == Random {
+		start = rand.IntN(n)
+	} else {
+		start = int((s.next.Add(1) - 1) % uint64(n))
+	}
+	for i := range n {
+		if r := s.replicas[(start+i)%n]; r.healthy.Load() {
+			return r.db
+		}
+	}
+	return nil
+}
+
+// check pings the replicas concurrently and records which ones answered in time.
+func (s *replicaSet) check() {
+	var wg sync.WaitGroup
+	for i, r := range s.replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			healthy := ping(r.db, s.timeout) == nil
+			if previous := r.healthy.Swap(healthy); previous != healthy {
+				log.Printf("gompose: read replica %d healthy: %t", i, healthy)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *replicaSet) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.check()
+		}
+	}
+}
+
+// close stops the health checks and closes the replica connections.
+func (s *replicaSet) close() error {
+	var firstErr error
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		for _, r := range s.replicas {
+			r.healthy.Store(false)
+			sqlDB, err := r.db.DB()
+			if err == nil {
+				err = sqlDB.Close()
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	})
+	return firstErr
+}
+
+func ping(conn *gorm.DB, timeout time.Duration) error {
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
@@ -0,0 +1,117 @@
+package gormadapter
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Lumicrate/gompose/db"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+)
+
+type Item struct {
+	ID   int `gorm:"primaryKey;autoIncrement"`
+	Name string
+}
+
+// seededDatabase creates a SQLite database at path holding one item named name. Its
+// rows tell which database served a read.
+func seededDatabase(t *testing.T, path, name string) {
+	adapter := New(sqlite.Open(path), nil)
+	require.NoError(t, adapter.Init())
+	require.NoError(t, adapter.Migrate([]any{&Item{}}))
+	require.NoError(t, adapter.Create(&Item{Name: name}))
+	require.NoError(t, adapter.Close())
+}
+
+func setupReplicated(t *testing.T, policy ReplicaPolicy, replicas ...string) *Adapter {
+	dir := t.TempDir()
+	config := Replicas{Policy: policy, HealthCheckInterval: time.Hour}
+	for _, name := range replicas {
+		path := filepath.Join(dir, name+".db")
+		seededDatabase(t, path, name)
+		config.Dialectors = append(config.Dialectors, sqlite.Open(path))
+	}
+
+	adapter := New(sqlite.Open(filepath.Join(dir, "primary.db")), nil).SetReplicas(config)
+	require.NoError(t, adapter.Init())
+	t.Cleanup(func() { adapter.Close() })
+	require.NoError(t, adapter.Migrate([]any{&Item{}}))
+	require.NoError(t, adapter.Create(&Item{Name: "primary"}))
+	return adapter
+}
+
+func readName(t *testing.T, adapter db.DBAdapter) string {
+	item, err := adapter.FindByID("1", &Item{})
+	require.NoError(t, err)
+	return item.(*Item).Name
+}
+
+func TestReplicas_Routing(t *testing.T) {
+	adapter := setupReplicated(t, RoundRobin, "replica")
+
+	require.Equal(t, "replica", readName(t, adapter))
+	items, err := adapter.FindAll(&Item{}, nil, db.Pagination{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []Item{{ID: 1, Name: "replica"}}, items)
+
+	// Writes go to the primary.
+	require.NoError(t, adapter.Create(&Item{Name: "second"}))
+	count, err := adapter.Count(&Item{}, nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, count)
+
+	primary := adapter.WithContext(db.WithPrimary(context.Background()))
+	require.Equal(t, "primary", readName(t, primary))
+	count, err = primary.Count(&Item{}, nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, count)
+
+	require.NoError(t, adapter.WithTransaction(func(tx db.DBAdapter) error {
+		require.Equal(t, "primary", readName(t, tx))
+		return nil
+	}))
+}
+
+func TestReplicas_RoundRobinSkipsUnhealthy(t *testing.T) {
+	adapter := setupReplicated(t, RoundRobin, "first", "second")
+	require.Equal(t, []string{"first", "second", "first"},
+		[]string{readName(t, adapter), readName(t, adapter), readName(t, adapter)})
+
+	closeReplica := func(i int) {
+		sqlDB, err := adapter.replicas.replicas[i].db.DB()
+		require.NoError(t, err)
+		require.NoError(t, sqlDB.Close())
+		adapter.replicas.check()
+	}
+
+	closeReplica(0)
+	require.Equal(t, []string{"second", "second"}, []string{readName(t, adapter), readName(t, adapter)})
+
+	// Without a healthy replica reads fall back to the primary.
+	closeReplica(1)
+	require.Equal(t, "primary", readName(t, adapter))
+}
+
+func TestReplicas_Random(t *testing.T) {
+	adapter := setupReplicated(t, Random, "first", "second")
+
+	seen := map[string]bool{}
+	for range 50 {
+		seen[readName(t, adapter)] = true
+	}
+	require.Equal(t, map[string]bool{"first": true, "second": true}, seen)
+}
+
+func TestReplicas_UnknownPolicy(t *testing.T) {
+	adapter := New(sqlite.Open(filepath.Join(t.TempDir(), "primary.db")), nil).
+		SetReplicas(Replicas{Policy: "closest"})
+	require.ErrorContains(t, adapter.Init(), `unknown replica policy "closest"`)
+}
+
+func TestWithPrimary(t *testing.T) {
+	require.False(t, db.ReadsPrimary(context.Background()))
+	require.True(t, db.ReadsPrimary(db.WithPrimary(context.Background())))
+}
@@ -0,0 +1,99 @@
+package gormadapter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Lumicrate/gompose/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// textSearchConfig is the Postgres text search configuration. "simple" only lower-cases
+// words, so search behaves the same whatever the language of the data.
+const textSearchConfig = "simple"
+
+// searched restricts tx to the rows matching the search query, ordered by relevance when ranked is true.
+// Postgres matches the words as prefixes against a tsvector of the fields; the other
+// dialects fall back to a case-insensitive LIKE per word and field.
+func (a *Adapter) searched(sch *schema.Schema, tx *gorm.DB, ranked bool) (*gorm.DB, error) {
+	terms := db.SearchTerms(a.search)
+	if len(terms) == 0 {
+		return tx, nil
+	}
+
+	if len(a.searchFields) == 0 {
+		return nil, errors.New("search needs at least one field")
+	}
+
+	columns := make([]string, len(a.searchFields))
+	for i, name := range a.searchFields {
+		column, err := columnFor(sch, name)
+		if err != nil {
+			return nil, err
+		}
+		columns[i] = tx.Statement.Quote(column)
+	}
+
+	build := likeSearch
+	if tx.Dialector.Name() == "postgres" {
+		build = tsvectorSearch
+	}
+	condition, rank := build(columns, terms)
+	tx = tx.Where(condition)
+	if ranked {
+		tx = tx.Order(rank)
+	}
+	return tx, nil
+}
+
+// tsvectorSearch builds `document @@ to_tsquery('a:* & b:*')` and its ts_rank ordering.
+func tsvectorSearch(columns []string, terms []string) (clause.Expression, clause.OrderBy) {
+	parts := make([]string, len(columns))
+	for i, column := range columns {
+		parts[i] = fmt.Sprintf("coalesce(%s::text, '')", column)
+	}
+	document := fmt.Sprintf("to_tsvector('%s', %s)", textSearchConfig, strings.Join(parts, " || ' ' || "))
+	query := fmt.Sprintf("to_tsquery('%s', ?)", textSearchConfig)
+
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = term + ":*"
+	}
+	tsquery := strings.Join(prefixes, " & ")
+
+	condition := clause.Expr{SQL: document + " @@ " + query, Vars: []any{tsquery}}
+	rank := clause.OrderBy{Expression: clause.Expr{
+		SQL:                "ts_rank(" + document + ", " + query + ") DESC",
+		Vars:               []any{tsquery},
+		WithoutParentheses: true,
+	}}
+	return condition, rank
+}
+
+// likeSearch requires every term in one of the columns and ranks rows by the number of
+// term and column pairs that match.
+func likeSearch(columns []string, terms []string) (clause.Expression, clause.OrderBy) {
+	var conditions []clause.Expression
+	var scores []string
+	var scoreVars []any
+	for _, term := range terms {
+		pattern := "%" + term + "%"
+		var matches []clause.Expression
+		for _, column := range columns {
+			matches = append(matches, clause.Expr{SQL: "LOWER(" + column + ") LIKE ?", Vars: []any{pattern}})
+			scores = append(scores, "CASE WHEN LOWER("+column+") LIKE ? THEN 1 ELSE 0 END")
+			scoreVars = append(scoreVars, pattern)
+		}
+		conditions = append(conditions, clause.Or(matches...))
+	}
+
+	rank := clause.OrderBy{Expression: clause.Expr{
+		SQL:                "(" + strings.Join(scores, " + ") + ") DESC",
+		Vars:               scoreVars,
+		WithoutParentheses: true,
+	}}
+	return clause.And(conditions...), rank
+}
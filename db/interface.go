@@ -1,8 +1,14 @@
 package db
 
+import "context"
+
 type Pagination struct {
 	Limit  int
 	Offset int
+
+	// After switches to keyset pagination: it holds the sort key values of the last row
+	// of the previous page, in the order of the sort passed to FindAll, and Offset is ignored.
+	After []any
 }
 
 type Sort struct {
@@ -10,14 +16,88 @@ type Sort struct {
 	Direction string // "asc" or "desc"
 }
 
+// IndexEnsurer is implemented by adapters whose indexes are declared on the entities rather
+// than created by migrations, such as MongoDB. App.Run creates them on every start, with or
+// without AutoMigrate.
+type IndexEnsurer interface {
+	EnsureIndexes(entities []any) error
+}
+
+// TransactionSupporter is implemented by adapters whose WithTransaction cannot always roll
+// back, such as MongoDB on a standalone server.
+type TransactionSupporter interface {
+	SupportsTransactions() bool
+}
+
+// SupportsTransactions reports whether the writes of adapter.WithTransaction are rolled back
+// when it fails. Adapters that do not implement TransactionSupporter are assumed to be.
+func SupportsTransactions(adapter DBAdapter) bool {
+	if supporter, ok := adapter.(TransactionSupporter); ok {
+		return supporter.SupportsTransactions()
+	}
+	return true
+}
+
 type DBAdapter interface {
 	Init() error
 	Migrate(entities []any) error
 
+	// Ping checks that the database is reachable, e.g. for health checks.
+	Ping() error
+
+	// Close releases the connections opened by Init.
+	Close() error
+
+	// WithContext returns a copy of the adapter whose operations are bound to ctx,
+	// so cancellation and deadlines of the request reach the database driver.
+	WithContext(ctx context.Context) DBAdapter
+
+	// WithTransaction runs fn atomically: the writes made through tx are committed
+	// when fn returns nil and rolled back when it returns an error.
+	WithTransaction(fn func(tx DBAdapter) error) error
+
+	// IncludeDeleted returns a copy of the adapter whose reads also return soft-deleted records.
+	IncludeDeleted() DBAdapter
+
+	// Preload returns a copy of the adapter whose reads eager-load the given relations,
+	// named by the Go field that declares them (see Relations).
+	Preload(relations ...string) DBAdapter
+
+	// Select returns a copy of the adapter whose reads only load the given fields, named by
+	// their Go field name, and leave the others zero. The ID is always loaded.
+	Select(fields ...string) DBAdapter
+
+	// Search returns a copy of the adapter whose FindAll and Count only match entities where
+	// every word of query occurs in one of the given text fields. How words match (prefixes,
+	// stemming) depends on the database. When FindAll is given no sort, the best matches
+	// come first.
+	Search(query string, fields ...string) DBAdapter
+
 	Create(entity any) error
 	Update(entity any) error
 	Delete(id string, entity any) error
+	Restore(id string, entity any) error
+
+	// Upsert updates the entity with the ID of entity, or creates it when there is none, and
+	// reports whether it was created. Update never creates: it fails with a not found error.
+	// A soft-deleted entity is not recreated (conflict), and a non-zero version only
+	// matches an existing entity (precondition failed).
+	Upsert(entity any) (created bool, err error)
 
-	FindAll(entity any, filters map[string]any, pagination Pagination, sort []Sort) (any, error)
+	// CreateMany, UpdateMany and DeleteMany write several entities of one type in as few
+	// round trips as the database allows. They behave like Create, Update and Delete applied
+	// to every item and are all-or-nothing where the database supports transactions.
+	// When the failing item is known, the error is a *BatchError carrying its index.
+	CreateMany(entities []any) error
+	UpdateMany(entities []any) error
+	DeleteMany(ids []string, entity any) error
+
+	FindAll(entity any, filters []Filter, pagination Pagination, sort []Sort) (any, error)
 	FindByID(id string, entity any) (any, error)
+	Count(entity any, filters []Filter) (int64, error)
+
+	// Aggregate groups the entities matching filters by the groupBy fields and computes the
+	// metrics of every group. Rows are ordered by group. Without groupBy there is one row
+	// over all the matching entities.
+	Aggregate(entity any, filters []Filter, groupBy []string, metrics []Metric) ([]AggregateRow, error)
 }
@@ -0,0 +1,1114 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lumicrate/gompose/db"
+	gerrors "github.com/Lumicrate/gompose/errors"
+	"github.com/google/uuid"
+)
+
+// MemoryAdapter keeps entities in process memory. It implements the whole DBAdapter
+// contract without any infrastructure, which makes it a fit for tests and prototypes;
+// the data is gone when the process exits.
+//
+// Integer IDs are assigned from a per-entity sequence and string IDs get a random UUID
+// when they are left empty on Create. The adapter is safe for concurrent use.
+type MemoryAdapter struct {
+	store *store
+	ctx   context.Context
+
+	// inTx is set on the adapter handed to a transaction, whose store is a private snapshot.
+	inTx bool
+
+	// withDeleted disables the soft-delete scope on reads.
+	withDeleted bool
+	preloads    []string
+	selects     []string
+
+	// search and searchFields are set by Search.
+	search       string
+	searchFields []string
+}
+
+type store struct {
+	mu     sync.RWMutex
+	tables map[reflect.Type]*table
+
+	// writes serializes writers, so that nothing changes between the snapshot a
+	// transaction starts from and its commit.
+	writes sync.Mutex
+}
+
+type table struct {
+	rows   map[string]reflect.Value
+	order  []string
+	lastID int64
+}
+
+func New() *MemoryAdapter {
+	return &MemoryAdapter{
+		store: &store{tables: map[reflect.Type]*table{}},
+		ctx:   context.Background(),
+	}
+}
+
+func (m *MemoryAdapter) Init() error {
+	return nil
+}
+
+// Ping reports the error of the adapter's context, as there is no server to reach.
+func (m *MemoryAdapter) Ping() error {
+	return m.ctx.Err()
+}
+
+func (m *MemoryAdapter) Close() error {
+	return nil
+}
+
+func (m *MemoryAdapter) Migrate(entities []any) error {
+	return m.write(func() error {
+		for _, entity := range entities {
+			m.tableFor(elemType(entity))
+		}
+		return nil
+	})
+}
+
+func (m *MemoryAdapter) WithContext(ctx context.Context) db.DBAdapter {
+	clone := *m
+	clone.ctx = ctx
+	return &clone
+}
+
+// WithTransaction runs fn against a snapshot of the data and swaps it in when fn succeeds.
+// Other writers wait until the transaction ends; readers keep seeing the committed data.
+func (m *MemoryAdapter) WithTransaction(fn func(tx db.DBAdapter) error) error {
+	if m.inTx {
+		return fn(m)
+	}
+	if err := m.ctx.Err(); err != nil {
+		return err
+	}
+
+	m.store.writes.Lock()
+	defer m.store.writes.Unlock()
+
+	m.store.mu.RLock()
+	snapshot := m.store.clone()
+	m.store.mu.RUnlock()
+
+	tx := *m
+	tx.store = snapshot
+	tx.inTx = true
+	if err := fn(&tx); err != nil {
+		return err
+	}
+
+	m.store.mu.Lock()
+	m.store.tables = snapshot.tables
+	m.store.mu.Unlock()
+	return nil
+}
+
+func (m *MemoryAdapter) IncludeDeleted() db.DBAdapter {
+	clone := *m
+	clone.withDeleted = true
+	return &clone
+}
+
+func (m *MemoryAdapter) Preload(relations ...string) db.DBAdapter {
+	clone := *m
+	clone.preloads = append(append([]string{}, m.preloads...), relations...)
+	return &clone
+}
+
+func (m *MemoryAdapter) Select(fields ...string) db.DBAdapter {
+	clone := *m
+	clone.selects = append(append([]string{}, m.selects...), fields...)
+	return &clone
+}
+
+func (m *MemoryAdapter) Search(query string, fields ...string) db.DBAdapter {
+	clone := *m
+	clone.search = query
+	clone.searchFields = fields
+	return &clone
+}
+
+func (m *MemoryAdapter) Create(entity any) error {
+	v, err := structPointer(entity)
+	if err != nil {
+		return err
+	}
+
+	return m.write(func() error {
+		tbl := m.tableFor(v.Type())
+		id := v.FieldByName("ID")
+		if !id.IsValid() {
+			return fmt.Errorf("%s has no ID field", v.Type().Name())
+		}
+
+		if id.IsZero() {
+			if err := tbl.assignID(id); err != nil {
+				return err
+			}
+		} else if id.CanInt() && id.Int() > tbl.lastID {
+			tbl.lastID = id.Int()
+		} else if id.CanUint() && int64(id.Uint()) > tbl.lastID {
+			tbl.lastID = int64(id.Uint())
+		}
+
+		key := fmt.Sprint(id.Interface())
+		if _, exists := tbl.rows[key]; exists {
+			return gerrors.Conflict("entity already exists")
+		}
+
+		if db.IsVersioned(entity) {
+			db.SetEntityVersion(entity, 1)
+		}
+		tbl.insert(key, copyOf(v))
+		return nil
+	})
+}
+
+func (m *MemoryAdapter) Update(entity any) error {
+	v, err := structPointer(entity)
+	if err != nil {
+		return err
+	}
+
+	return m.write(func() error {
+		tbl := m.tableFor(v.Type())
+		id := v.FieldByName("ID")
+		if !id.IsValid() {
+			return fmt.Errorf("%s has no ID field", v.Type().Name())
+		}
+
+		key := fmt.Sprint(id.Interface())
+		stored, ok := tbl.rows[key]
+		if !ok || isDeleted(stored) {
+			return gerrors.NotFound("entity not found")
+		}
+
+		if db.IsVersioned(entity) {
+			current := db.EntityVersion(stored.Interface())
+			if expected := db.EntityVersion(entity); expected != 0 && expected != current {
+				return gerrors.PreconditionFailed("entity was modified by another request")
+			}
+			db.SetEntityVersion(entity, current+1)
+		}
+
+		row := copyOf(v)
+		if deletedAt := row.FieldByName(db.DeletedAtField); deletedAt.IsValid() {
+			// The deletion mark is never overwritten by a request body.
+			deletedAt.Set(stored.FieldByName(db.DeletedAtField))
+		}
+		tbl.rows[key] = row
+		return nil
+	})
+}
+
+func (m *MemoryAdapter) Delete(id string, entity any) error {
+	t := elemType(entity)
+	key, err := parseID(t, id)
+	if err != nil {
+		return err
+	}
+
+	return m.write(func() error {
+		tbl := m.tableFor(t)
+		stored, ok := tbl.rows[key]
+		if !ok || isDeleted(stored) {
+			return gerrors.NotFound("entity not found")
+		}
+
+		if expected := db.EntityVersion(entity); expected > 0 && expected != db.EntityVersion(stored.Interface()) {
+			return gerrors.PreconditionFailed("entity was modified by another request")
+		}
+
+		if !db.IsSoftDeletable(entity) {
+			tbl.remove(key)
+			return nil
+		}
+
+		row := copyOf(stored)
+		setDeletedAt(row.FieldByName(db.DeletedAtField), time.Now())
+		tbl.rows[key] = row
+		return nil
+	})
+}
+
+func (m *MemoryAdapter) Upsert(entity any) (bool, error) {
+	var created bool
+	err := m.WithTransaction(func(tx db.DBAdapter) error {
+		var err error
+		created, err = db.UpsertWith(tx, entity)
+		return err
+	})
+	return created, err
+}
+
+// CreateMany, UpdateMany and DeleteMany apply their items in one transaction.
+func (m *MemoryAdapter) CreateMany(entities []any) error {
+	return m.WithTransaction(func(tx db.DBAdapter) error {
+		for i, entity := range entities {
+			if err := tx.Create(entity); err != nil {
+				return &db.BatchError{Index: i, Err: err}
+			}
+		}
+		return nil
+	})
+}
+
+func (m *MemoryAdapter) UpdateMany(entities []any) error {
+	return m.WithTransaction(func(tx db.DBAdapter) error {
+		for i, entity := range entities {
+			if err := tx.Update(entity); err != nil {
+				return &db.BatchError{Index: i, Err: err}
+			}
+		}
+		return nil
+	})
+}
+
+func (m *MemoryAdapter) DeleteMany(ids []string, entity any) error {
+	return m.WithTransaction(func(tx db.DBAdapter) error {
+		for i, id := range ids {
+			if err := tx.Delete(id, entity); err != nil {
+				return &db.BatchError{Index: i, Err: err}
+			}
+		}
+		return nil
+	})
+}
+
+func (m *MemoryAdapter) Restore(id string, entity any) error {
+	t := elemType(entity)
+	if !db.IsSoftDeletable(entity) {
+		return gerrors.BadRequest(fmt.Sprintf("%s does not support soft delete", t.Name()))
+	}
+	key, err := parseID(t, id)
+	if err != nil {
+		return err
+	}
+
+	return m.write(func() error {
+		tbl := m.tableFor(t)
+		stored, ok := tbl.rows[key]
+		if !ok || !isDeleted(stored) {
+			return gerrors.NotFound("deleted entity not found")
+		}
+
+		row := copyOf(stored)
+		deletedAt := row.FieldByName(db.DeletedAtField)
+		deletedAt.Set(reflect.Zero(deletedAt.Type()))
+		tbl.rows[key] = row
+		return nil
+	})
+}
+
+func (m *MemoryAdapter) FindAll(entity any, filters []db.Filter, pagination db.Pagination, sorts []db.Sort) (any, error) {
+	t := elemType(entity)
+	result := reflect.MakeSlice(reflect.SliceOf(t), 0, 0)
+
+	err := m.read(func() error {
+		rows, err := m.matching(t, filters)
+		if err != nil {
+			return err
+		}
+		if rows, err = m.searched(t, rows, len(sorts) == 0); err != nil {
+			return err
+		}
+
+		keys := make([]fieldPath, len(sorts))
+		for i, s := range sorts {
+			if keys[i], err = fieldByName(t, s.Field); err != nil {
+				return err
+			}
+		}
+		sort.SliceStable(rows, func(i, j int) bool {
+			return compareRows(rows[i], rows[j], keys, sorts) < 0
+		})
+
+		if len(pagination.After) > 0 {
+			if rows, err = afterCursor(rows, keys, sorts, pagination.After); err != nil {
+				return err
+			}
+		} else if pagination.Offset > 0 {
+			rows = rows[min(pagination.Offset, len(rows)):]
+		}
+		if pagination.Limit > 0 && pagination.Limit < len(rows) {
+			rows = rows[:pagination.Limit]
+		}
+
+		for _, row := range rows {
+			if row, err = m.project(row); err != nil {
+				return err
+			}
+			if err := m.preload(row); err != nil {
+				return err
+			}
+			result = reflect.Append(result, row)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Interface(), nil
+}
+
+func (m *MemoryAdapter) FindByID(id string, entity any) (any, error) {
+	v, err := structPointer(entity)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parseID(v.Type(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.read(func() error {
+		stored, ok := m.rowsOf(v.Type())[key]
+		if !ok || (isDeleted(stored) && !m.withDeleted) {
+			return gerrors.NotFound("entity not found")
+		}
+
+		row, err := m.project(stored)
+		if err != nil {
+			return err
+		}
+		if err := m.preload(row); err != nil {
+			return err
+		}
+		v.Set(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+func (m *MemoryAdapter) Count(entity any, filters []db.Filter) (int64, error) {
+	var count int64
+	err := m.read(func() error {
+		t := elemType(entity)
+		rows, err := m.matching(t, filters)
+		if err != nil {
+			return err
+		}
+		rows, err = m.searched(t, rows, false)
+		count = int64(len(rows))
+		return err
+	})
+	return count, err
+}
+
+func (m *MemoryAdapter) Aggregate(entity any, filters []db.Filter, groupBy []string, metrics []db.Metric) ([]db.AggregateRow, error) {
+	t := elemType(entity)
+	keys := make([]fieldPath, len(groupBy))
+	sorts := make([]db.Sort, len(groupBy))
+	for i, name := range groupBy {
+		var err error
+		if keys[i], err = fieldByName(t, name); err != nil {
+			return nil, err
+		}
+		sorts[i] = db.Sort{Field: name, Direction: "asc"}
+	}
+	fields := make([]fieldPath, len(metrics))
+	for i, metric := range metrics {
+		if metric.Func == db.AggCount {
+			continue
+		}
+		var err error
+		if fields[i], err = fieldByName(t, metric.Field); err != nil {
+			return nil, err
+		}
+		if !db.IsNumeric(fields[i].typ) {
+			return nil, gerrors.BadRequest(fmt.Sprintf("cannot compute %s of %s", metric.Func, metric.Field))
+		}
+	}
+
+	var result []db.AggregateRow
+	err := m.read(func() error {
+		rows, err := m.matching(t, filters)
+		if err != nil {
+			return err
+		}
+		if rows, err = m.searched(t, rows, false); err != nil {
+			return err
+		}
+
+		if len(groupBy) == 0 {
+			result = append(result, aggregateRows(rows, keys, metrics, fields))
+			return nil
+		}
+
+		// Sorted by group, every group is a run of rows with equal keys.
+		sort.SliceStable(rows, func(i, j int) bool {
+			return compareRows(rows[i], rows[j], keys, sorts) < 0
+		})
+		for start := 0; start < len(rows); {
+			end := start + 1
+			for end < len(rows) && compareRows(rows[start], rows[end], keys, sorts) == 0 {
+				end++
+			}
+			result = append(result, aggregateRows(rows[start:end], keys, metrics, fields))
+			start = end
+		}
+		return nil
+	})
+	return result, err
+}
+
+// aggregateRows computes the metrics of a group of rows sharing the values of keys.
+func aggregateRows(rows []reflect.Value, keys []fieldPath, metrics []db.Metric, fields []fieldPath) db.AggregateRow {
+	row := db.AggregateRow{Group: make([]any, len(keys)), Metrics: make([]any, len(metrics))}
+	if len(rows) > 0 {
+		for i, key := range keys {
+			if v, ok := key.of(rows[0]); ok {
+				row.Group[i] = v.Interface()
+			}
+		}
+	}
+
+	for i, metric := range metrics {
+		if metric.Func == db.AggCount {
+			row.Metrics[i] = int64(len(rows))
+			continue
+		}
+
+		var values []float64
+		for _, r := range rows {
+			if v, ok := fields[i].of(r); ok {
+				values = append(values, v.Convert(reflect.TypeOf(float64(0))).Float())
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+		total, lowest, highest := 0.0, values[0], values[0]
+		for _, v := range values {
+			total += v
+			lowest, highest = min(lowest, v), max(highest, v)
+		}
+		switch metric.Func {
+		case db.AggSum:
+			row.Metrics[i] = total
+		case db.AggAvg:
+			row.Metrics[i] = total / float64(len(values))
+		case db.AggMin:
+			row.Metrics[i] = lowest
+		case db.AggMax:
+			row.Metrics[i] = highest
+		}
+	}
+	return row
+}
+
+// write runs fn with exclusive access to the store.
+func (m *MemoryAdapter) write(fn func() error) error {
+	if err := m.ctx.Err(); err != nil {
+		return err
+	}
+	if !m.inTx {
+		m.store.writes.Lock()
+		defer m.store.writes.Unlock()
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	return fn()
+}
+
+// read runs fn with shared access to the store.
+func (m *MemoryAdapter) read(fn func() error) error {
+	if err := m.ctx.Err(); err != nil {
+		return err
+	}
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+	return fn()
+}
+
+// project returns a copy of a stored row holding only the selected fields.
+func (m *MemoryAdapter) project(row reflect.Value) (reflect.Value, error) {
+	if len(m.selects) == 0 {
+		return copyOf(row), nil
+	}
+	projected := reflect.New(row.Type()).Elem()
+	for _, name := range db.SelectedFields(row.Type(), m.selects, m.preloads) {
+		field, err := fieldByName(row.Type(), name)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		projected.FieldByIndex(field.index).Set(row.FieldByIndex(field.index))
+	}
+	return projected, nil
+}
+
+// tableFor returns the table of entity type t, creating it if needed. Callers hold the write lock.
+func (m *MemoryAdapter) tableFor(t reflect.Type) *table {
+	tbl, ok := m.store.tables[t]
+	if !ok {
+		tbl = &table{rows: map[string]reflect.Value{}}
+		m.store.tables[t] = tbl
+	}
+	return tbl
+}
+
+func (m *MemoryAdapter) rowsOf(t reflect.Type) map[string]reflect.Value {
+	if tbl, ok := m.store.tables[t]; ok {
+		return tbl.rows
+	}
+	return nil
+}
+
+// matching returns the stored rows of type t that are in scope and match every filter, in insertion order.
+func (m *MemoryAdapter) matching(t reflect.Type, filters []db.Filter) ([]reflect.Value, error) {
+	conditions := make([]func(reflect.Value) bool, len(filters))
+	for i, f := range filters {
+		cond, err := condition(t, f)
+		if err != nil {
+			return nil, err
+		}
+		conditions[i] = cond
+	}
+
+	tbl, ok := m.store.tables[t]
+	if !ok {
+		return nil, nil
+	}
+
+	var rows []reflect.Value
+	for _, key := range tbl.order {
+		row := tbl.rows[key]
+		if isDeleted(row) && !m.withDeleted {
+			continue
+		}
+		matches := true
+		for _, cond := range conditions {
+			if !cond(row) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// searched keeps the rows where every search term occurs in one of the search fields,
+// ignoring case. When ranked is true the rows with the most occurrences come first.
+func (m *MemoryAdapter) searched(t reflect.Type, rows []reflect.Value, ranked bool) ([]reflect.Value, error) {
+	terms := db.SearchTerms(m.search)
+	if len(terms) == 0 {
+		return rows, nil
+	}
+	if len(m.searchFields) == 0 {
+		return nil, fmt.Errorf("search needs at least one field")
+	}
+
+	fields := make([]fieldPath, len(m.searchFields))
+	for i, name := range m.searchFields {
+		var err error
+		if fields[i], err = fieldByName(t, name); err != nil {
+			return nil, err
+		}
+	}
+
+	type scored struct {
+		row   reflect.Value
+		score int
+	}
+	var matched []scored
+	for _, row := range rows {
+		var texts []string
+		for _, f := range fields {
+			if v, ok := f.of(row); ok {
+				texts = append(texts, strings.ToLower(fmt.Sprint(v.Interface())))
+			}
+		}
+
+		score := 0
+		for _, term := range terms {
+			occurrences := 0
+			for _, text := range texts {
+				occurrences += strings.Count(text, term)
+			}
+			if occurrences == 0 {
+				score = 0
+				break
+			}
+			score += occurrences
+		}
+		if score > 0 {
+			matched = append(matched, scored{row, score})
+		}
+	}
+
+	if ranked {
+		sort.SliceStable(matched, func(i, j int) bool { return matched[i].score > matched[j].score })
+	}
+	result := make([]reflect.Value, len(matched))
+	for i, match := range matched {
+		result[i] = match.row
+	}
+	return result, nil
+}
+
+// preload fills the requested relations of row with live records of the related entity.
+func (m *MemoryAdapter) preload(row reflect.Value) error {
+	for _, name := range m.preloads {
+		rel, ok := db.RelationByField(row.Type(), name)
+		if !ok {
+			return gerrors.BadRequest(fmt.Sprintf("unknown relation %q", name))
+		}
+
+		ownerKey, targetKey := rel.References, rel.ForeignKey
+		if rel.Kind == db.BelongsTo {
+			ownerKey, targetKey = rel.ForeignKey, rel.References
+		}
+		want, ok := scalar(row.FieldByName(ownerKey))
+		if !ok {
+			continue
+		}
+
+		field := row.FieldByName(rel.Field)
+		related := reflect.MakeSlice(reflect.SliceOf(rel.Target), 0, 0)
+		if tbl, ok := m.store.tables[rel.Target]; ok {
+			for _, key := range tbl.order {
+				target := tbl.rows[key]
+				if isDeleted(target) {
+					continue
+				}
+				if got, ok := scalar(target.FieldByName(targetKey)); ok && fmt.Sprint(got.Interface()) == fmt.Sprint(want.Interface()) {
+					related = reflect.Append(related, copyOf(target))
+				}
+			}
+		}
+
+		switch {
+		case rel.Kind == db.HasMany:
+			if field.Type().Elem().Kind() == reflect.Ptr {
+				pointers := reflect.MakeSlice(field.Type(), related.Len(), related.Len())
+				for i := 0; i < related.Len(); i++ {
+					pointers.Index(i).Set(related.Index(i).Addr())
+				}
+				related = pointers
+			}
+			field.Set(related)
+		case related.Len() == 0:
+		case field.Kind() == reflect.Ptr:
+			field.Set(related.Index(0).Addr())
+		default:
+			field.Set(related.Index(0))
+		}
+	}
+	return nil
+}
+
+func (s *store) clone() *store {
+	tables := make(map[reflect.Type]*table, len(s.tables))
+	for t, tbl := range s.tables {
+		rows := make(map[string]reflect.Value, len(tbl.rows))
+		for key, row := range tbl.rows {
+			rows[key] = row
+		}
+		tables[t] = &table{rows: rows, order: append([]string{}, tbl.order...), lastID: tbl.lastID}
+	}
+	return &store{tables: tables}
+}
+
+func (tbl *table) assignID(id reflect.Value) error {
+	switch {
+	case id.CanInt():
+		tbl.lastID++
+		id.SetInt(tbl.lastID)
+	case id.CanUint():
+		tbl.lastID++
+		id.SetUint(uint64(tbl.lastID))
+	case id.Kind() == reflect.String:
+		id.SetString(uuid.NewString())
+	default:
+		return fmt.Errorf("unsupported ID type: %s", id.Type())
+	}
+	return nil
+}
+
+func (tbl *table) insert(key string, row reflect.Value) {
+	tbl.rows[key] = row
+	tbl.order = append(tbl.order, key)
+}
+
+func (tbl *table) remove(key string) {
+	delete(tbl.rows, key)
+	for i, k := range tbl.order {
+		if k == key {
+			tbl.order = append(tbl.order[:i:i], tbl.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// parseID normalizes a path ID to the key rows of type t are stored under, so "007" finds ID 7.
+func parseID(t reflect.Type, id string) (string, error) {
+	field, ok := t.FieldByName("ID")
+	if !ok {
+		return "", fmt.Errorf("%s has no ID field", t.Name())
+	}
+
+	switch field.Type.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return "", gerrors.BadRequest(fmt.Sprintf("invalid int ID: %q", id))
+		}
+		return strconv.FormatInt(n, 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return "", gerrors.BadRequest(fmt.Sprintf("invalid uint ID: %q", id))
+		}
+		return strconv.FormatUint(n, 10), nil
+	}
+	return id, nil
+}
+
+func elemType(entity any) reflect.Type {
+	t := reflect.TypeOf(entity)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// structPointer returns the struct entity points to.
+func structPointer(entity any) (reflect.Value, error) {
+	v := reflect.ValueOf(entity)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("expected a pointer to a struct, got %T", entity)
+	}
+	return v.Elem(), nil
+}
+
+// copyOf returns an addressable copy of the struct v. Stored rows are never modified in place,
+// so a copy is all it takes to keep callers and transaction snapshots apart.
+func copyOf(v reflect.Value) reflect.Value {
+	c := reflect.New(v.Type()).Elem()
+	c.Set(v)
+	return c
+}
+
+func isDeleted(row reflect.Value) bool {
+	v, deleted := scalar(row.FieldByName(db.DeletedAtField))
+	if deleted && v.Type() == reflect.TypeOf(time.Time{}) {
+		// A plain time.Time cannot be null, so its zero value marks a live row.
+		return !v.Interface().(time.Time).IsZero()
+	}
+	return deleted
+}
+
+// setDeletedAt stores now in a DeletedAt field of type *time.Time, time.Time,
+// gorm.DeletedAt or sql.NullTime.
+func setDeletedAt(field reflect.Value, now time.Time) {
+	switch {
+	case field.Kind() == reflect.Ptr:
+		field.Set(reflect.ValueOf(&now))
+	case field.Type() == reflect.TypeOf(now):
+		field.Set(reflect.ValueOf(now))
+	case isNullable(field.Type()):
+		field.Field(0).Set(reflect.ValueOf(now))
+		field.Field(1).SetBool(true)
+	}
+}
+
+// isNullable matches the sql.Null* family and its derivatives such as gorm.DeletedAt:
+// a struct holding the value followed by a Valid flag.
+func isNullable(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.NumField() == 2 &&
+		t.Field(1).Name == "Valid" && t.Field(1).Type.Kind() == reflect.Bool
+}
+
+// scalar unwraps pointers and nullable structs. It reports false for an invalid or null value.
+func scalar(v reflect.Value) (reflect.Value, bool) {
+	switch {
+	case !v.IsValid():
+		return v, false
+	case v.Kind() == reflect.Ptr:
+		if v.IsNil() {
+			return v, false
+		}
+		return scalar(v.Elem())
+	case isNullable(v.Type()):
+		if !v.Field(1).Bool() {
+			return v, false
+		}
+		return v.Field(0), true
+	}
+	return v, true
+}
+
+func scalarType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if isNullable(t) {
+		return t.Field(0).Type
+	}
+	return t
+}
+
+type fieldPath struct {
+	name  string
+	index []int
+	typ   reflect.Type
+}
+
+func (f fieldPath) of(row reflect.Value) (reflect.Value, bool) {
+	return scalar(row.FieldByIndex(f.index))
+}
+
+// fieldByName resolves a Go field name or json tag of t. Unknown names are rejected.
+func fieldByName(t reflect.Type, name string) (fieldPath, error) {
+	normalized := strings.ReplaceAll(strings.ToLower(name), "_", "")
+	for _, f := range reflect.VisibleFields(t) {
+		if f.Anonymous || !f.IsExported() {
+			continue
+		}
+		if f.Name == name || strings.Split(f.Tag.Get("json"), ",")[0] == name || strings.ToLower(f.Name) == normalized {
+			return fieldPath{name: f.Name, index: f.Index, typ: scalarType(f.Type)}, nil
+		}
+	}
+	return fieldPath{}, gerrors.BadRequest(fmt.Sprintf("unknown field %q", name))
+}
+
+// condition compiles a filter into a predicate over stored rows of type t.
+func condition(t reflect.Type, f db.Filter) (func(reflect.Value) bool, error) {
+	field, err := fieldByName(t, f.Field)
+	if err != nil {
+		return nil, err
+	}
+
+	switch f.Operator {
+	case db.OpNull:
+		isNull, _ := f.Value.(bool)
+		return func(row reflect.Value) bool {
+			_, ok := field.of(row)
+			return ok != isNull
+		}, nil
+	case db.OpLike:
+		if field.typ.Kind() != reflect.String {
+			return nil, gerrors.BadRequest(fmt.Sprintf("field %q does not support like", f.Field))
+		}
+		pattern, err := regexp.Compile(db.LikeToRegexp(fmt.Sprint(f.Value)))
+		if err != nil {
+			return nil, gerrors.BadRequest(fmt.Sprintf("invalid like pattern %q", f.Value))
+		}
+		return func(row reflect.Value) bool {
+			v, ok := field.of(row)
+			return ok && pattern.MatchString(v.String())
+		}, nil
+	case db.OpIn:
+		var values []reflect.Value
+		for _, raw := range inValues(f.Value) {
+			value, err := coerce(field, raw)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return func(row reflect.Value) bool {
+			v, ok := field.of(row)
+			if !ok {
+				return false
+			}
+			for _, value := range values {
+				if compare(v, value) == 0 {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+
+	value, err := coerce(field, f.Value)
+	if err != nil {
+		return nil, err
+	}
+	var accept func(c int) bool
+	switch f.Operator {
+	case db.OpEq:
+		accept = func(c int) bool { return c == 0 }
+	case db.OpNe:
+		accept = func(c int) bool { return c != 0 }
+	case db.OpGt:
+		accept = func(c int) bool { return c > 0 }
+	case db.OpGte:
+		accept = func(c int) bool { return c >= 0 }
+	case db.OpLt:
+		accept = func(c int) bool { return c < 0 }
+	case db.OpLte:
+		accept = func(c int) bool { return c <= 0 }
+	default:
+		return nil, gerrors.BadRequest(fmt.Sprintf("unsupported filter operator %q", f.Operator))
+	}
+	return func(row reflect.Value) bool {
+		v, ok := field.of(row)
+		return ok && accept(compare(v, value))
+	}, nil
+}
+
+func inValues(value any) []any {
+	switch v := value.(type) {
+	case []string:
+		values := make([]any, len(v))
+		for i, s := range v {
+			values[i] = s
+		}
+		return values
+	case []any:
+		return v
+	}
+	return []any{value}
+}
+
+// coerce converts a filter or cursor value, typically a query string or a JSON-decoded
+// value, to the type of field so that it can be compared with stored values.
+func coerce(field fieldPath, value any) (reflect.Value, error) {
+	t := field.typ
+	invalid := gerrors.BadRequest(fmt.Sprintf("invalid value %v for field %q", value, field.name))
+
+	if s, ok := value.(string); ok {
+		var parsed any
+		var err error
+		switch t.Kind() {
+		case reflect.String:
+			parsed = s
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			parsed, err = strconv.ParseInt(s, 10, 64)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			parsed, err = strconv.ParseUint(s, 10, 64)
+		case reflect.Float32, reflect.Float64:
+			parsed, err = strconv.ParseFloat(s, 64)
+		case reflect.Bool:
+			parsed, err = strconv.ParseBool(s)
+		default:
+			if t != reflect.TypeOf(time.Time{}) {
+				return reflect.Value{}, invalid
+			}
+			parsed, err = time.Parse(time.RFC3339Nano, s)
+		}
+		if err != nil {
+			return reflect.Value{}, invalid
+		}
+		value = parsed
+	}
+
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || (t.Kind() == reflect.String) != (v.Kind() == reflect.String) || !v.CanConvert(t) {
+		return reflect.Value{}, invalid
+	}
+	return v.Convert(t), nil
+}
+
+// compare orders two non-null values of the same type.
+func compare(a, b reflect.Value) int {
+	switch {
+	case a.CanInt():
+		return cmp.Compare(a.Int(), b.Int())
+	case a.CanUint():
+		return cmp.Compare(a.Uint(), b.Uint())
+	case a.CanFloat():
+		return cmp.Compare(a.Float(), b.Float())
+	case a.Kind() == reflect.String:
+		return strings.Compare(a.String(), b.String())
+	case a.Kind() == reflect.Bool:
+		if a.Bool() == b.Bool() {
+			return 0
+		} else if b.Bool() {
+			return -1
+		}
+		return 1
+	case a.Type() == reflect.TypeOf(time.Time{}):
+		return a.Interface().(time.Time).Compare(b.Interface().(time.Time))
+	}
+	return 0
+}
+
+// compareNullable orders null before any value, as ascending SQL sorts do on most databases.
+func compareNullable(a reflect.Value, aok bool, b reflect.Value, bok bool) int {
+	switch {
+	case !aok && !bok:
+		return 0
+	case !aok:
+		return -1
+	case !bok:
+		return 1
+	}
+	return compare(a, b)
+}
+
+func compareRows(a, b reflect.Value, keys []fieldPath, sorts []db.Sort) int {
+	for i, key := range keys {
+		av, aok := key.of(a)
+		bv, bok := key.of(b)
+		c := compareNullable(av, aok, bv, bok)
+		if sorts[i].Direction == "desc" {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// afterCursor drops the sorted rows up to and including the given sort key values.
+func afterCursor(rows []reflect.Value, keys []fieldPath, sorts []db.Sort, after []any) ([]reflect.Value, error) {
+	if len(after) != len(sorts) {
+		return nil, gerrors.BadRequest(fmt.Sprintf("cursor has %d values but %d sort fields", len(after), len(sorts)))
+	}
+
+	values := make([]reflect.Value, len(after))
+	present := make([]bool, len(after))
+	for i, raw := range after {
+		if raw == nil {
+			continue
+		}
+		value, err := coerce(keys[i], raw)
+		if err != nil {
+			return nil, err
+		}
+		values[i], present[i] = value, true
+	}
+
+	for i, row := range rows {
+		for j, key := range keys {
+			v, ok := key.of(row)
+			c := compareNullable(v, ok, values[j], present[j])
+			if sorts[j].Direction == "desc" {
+				c = -c
+			}
+			if c > 0 {
+				return rows[i:], nil
+			}
+			if c < 0 {
+				break
+			}
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,259 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Lumicrate/gompose/db"
+	"github.com/Lumicrate/gompose/db/dbtest"
+	gerrors "github.com/Lumicrate/gompose/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type Product struct {
+	ID    int
+	Name  string `json:"name"`
+	Price float64
+}
+
+type Note struct {
+	ID        string
+	Text      string
+	Version   int
+	DeletedAt *time.Time
+}
+
+type Customer struct {
+	ID     uint
+	Name   string
+	Orders []Order
+}
+
+type Order struct {
+	ID         uint
+	CustomerID uint
+	Customer   *Customer
+	Total      float64
+}
+
+func setupTestAdapter(t *testing.T) *MemoryAdapter {
+	adapter := New()
+	require.NoError(t, adapter.Init())
+	require.NoError(t, adapter.Migrate([]any{&Product{}}))
+
+	for i, name := range []string{"apple", "banana", "cherry", "date"} {
+		require.NoError(t, adapter.Create(&Product{Name: name, Price: float64(i + 1)}))
+	}
+	return adapter
+}
+
+func TestMemoryAdapter_CRUD(t *testing.T) {
+	adapter := setupTestAdapter(t)
+
+	product := &Product{Name: "elderberry", Price: 9}
+	require.NoError(t, adapter.Create(product))
+	require.Equal(t, 5, product.ID)
+
+	product.Price = 10
+	require.NoError(t, adapter.Update(product))
+
+	found, err := adapter.FindByID("005", &Product{})
+	require.NoError(t, err)
+	require.Equal(t, 10.0, found.(*Product).Price)
+
+	// Changing a returned entity does not change the stored one.
+	found.(*Product).Price = 0
+	found, err = adapter.FindByID("5", &Product{})
+	require.NoError(t, err)
+	require.Equal(t, 10.0, found.(*Product).Price)
+
+	require.NoError(t, adapter.Delete("5", &Product{}))
+	_, err = adapter.FindByID("5", &Product{})
+	require.ErrorIs(t, err, gerrors.ErrNotFound)
+
+	require.ErrorIs(t, adapter.Update(&Product{ID: 42}), gerrors.ErrNotFound)
+	require.ErrorIs(t, adapter.Create(&Product{ID: 1}), gerrors.ErrConflict)
+
+	_, err = adapter.FindByID("abc", &Product{})
+	require.ErrorIs(t, err, gerrors.ErrBadRequest)
+}
+
+func TestMemoryAdapter_StringIDs(t *testing.T) {
+	adapter := New()
+
+	note := &Note{Text: "hello"}
+	require.NoError(t, adapter.Create(note))
+	require.Len(t, note.ID, 36)
+
+	require.NoError(t, adapter.Create(&Note{ID: "custom", Text: "mine"}))
+	found, err := adapter.FindByID("custom", &Note{})
+	require.NoError(t, err)
+	require.Equal(t, "mine", found.(*Note).Text)
+}
+
+func TestMemoryAdapter_FindAll(t *testing.T) {
+	adapter := setupTestAdapter(t)
+
+	filters := []db.Filter{
+		{Field: "Price", Operator: db.OpGte, Value: "2"},
+		{Field: "name", Operator: db.OpLike, Value: "%a%"},
+	}
+	sort := []db.Sort{{Field: "Price", Direction: "desc"}}
+
+	result, err := adapter.FindAll(&Product{}, filters, db.Pagination{Limit: 2}, sort)
+	require.NoError(t, err)
+	products := result.([]Product)
+	require.Len(t, products, 2)
+	require.Equal(t, "date", products[0].Name)
+	require.Equal(t, "banana", products[1].Name)
+
+	count, err := adapter.Count(&Product{}, filters)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	result, err = adapter.FindAll(&Product{}, nil, db.Pagination{Limit: 2, After: []any{"2"}}, []db.Sort{{Field: "ID", Direction: "asc"}})
+	require.NoError(t, err)
+	require.Equal(t, "cherry", result.([]Product)[0].Name)
+
+	result, err = adapter.FindAll(&Product{}, nil, db.Pagination{Limit: 2, Offset: 3}, nil)
+	require.NoError(t, err)
+	require.Len(t, result.([]Product), 1)
+
+	result, err = adapter.FindAll(&Product{}, []db.Filter{{Field: "Name", Operator: db.OpIn, Value: []string{"apple", "date"}}}, db.Pagination{}, nil)
+	require.NoError(t, err)
+	require.Len(t, result.([]Product), 2)
+
+	_, err = adapter.FindAll(&Product{}, []db.Filter{db.Eq("secret", "x")}, db.Pagination{}, nil)
+	require.ErrorIs(t, err, gerrors.ErrBadRequest)
+
+	_, err = adapter.FindAll(&Product{}, []db.Filter{{Field: "Price", Operator: db.OpGt, Value: "cheap"}}, db.Pagination{}, nil)
+	require.ErrorIs(t, err, gerrors.ErrBadRequest)
+}
+
+func TestMemoryAdapter_SoftDeleteAndVersion(t *testing.T) {
+	adapter := New()
+
+	note := &Note{Text: "draft"}
+	require.NoError(t, adapter.Create(note))
+	require.Equal(t, 1, note.Version)
+
+	note.Text = "final"
+	require.NoError(t, adapter.Update(note))
+	require.Equal(t, 2, note.Version)
+
+	stale := &Note{ID: note.ID, Text: "stale", Version: 1}
+	require.ErrorIs(t, adapter.Update(stale), gerrors.ErrPreconditionFailed)
+	require.ErrorIs(t, adapter.Delete(note.ID, stale), gerrors.ErrPreconditionFailed)
+
+	require.NoError(t, adapter.Delete(note.ID, &Note{}))
+	_, err := adapter.FindByID(note.ID, &Note{})
+	require.ErrorIs(t, err, gerrors.ErrNotFound)
+
+	found, err := adapter.IncludeDeleted().FindByID(note.ID, &Note{})
+	require.NoError(t, err)
+	require.NotNil(t, found.(*Note).DeletedAt)
+
+	require.NoError(t, adapter.Restore(note.ID, &Note{}))
+	require.ErrorIs(t, adapter.Restore(note.ID, &Note{}), gerrors.ErrNotFound)
+	require.ErrorIs(t, adapter.Restore("1", &Product{}), gerrors.ErrBadRequest)
+}
+
+func TestMemoryAdapter_UpsertVersionedAndDeleted(t *testing.T) {
+	adapter := New()
+
+	created, err := adapter.Upsert(&Note{ID: "n1", Text: "draft"})
+	require.NoError(t, err)
+	require.True(t, created)
+
+	note := &Note{ID: "n1", Text: "final", Version: 1}
+	created, err = adapter.Upsert(note)
+	require.NoError(t, err)
+	require.False(t, created)
+	require.Equal(t, 2, note.Version)
+
+	_, err = adapter.Upsert(&Note{ID: "n2", Text: "stale", Version: 3})
+	require.ErrorIs(t, err, gerrors.ErrPreconditionFailed)
+
+	require.NoError(t, adapter.Delete("n1", &Note{}))
+	_, err = adapter.Upsert(&Note{ID: "n1", Text: "again"})
+	require.ErrorIs(t, err, gerrors.ErrConflict)
+}
+
+func TestMemoryAdapter_Preload(t *testing.T) {
+	adapter := New()
+
+	customer := &Customer{Name: "ada"}
+	require.NoError(t, adapter.Create(customer))
+	require.NoError(t, adapter.Create(&Order{CustomerID: customer.ID, Total: 5}))
+	require.NoError(t, adapter.Create(&Order{CustomerID: customer.ID, Total: 7}))
+
+	found, err := adapter.Preload("Orders").FindByID("1", &Customer{})
+	require.NoError(t, err)
+	require.Len(t, found.(*Customer).Orders, 2)
+
+	result, err := adapter.Preload("Customer").FindAll(&Order{}, nil, db.Pagination{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "ada", result.([]Order)[1].Customer.Name)
+
+	_, err = adapter.Preload("Bogus").FindByID("1", &Customer{})
+	require.ErrorIs(t, err, gerrors.ErrBadRequest)
+}
+
+func TestMemoryAdapter_Transaction(t *testing.T) {
+	adapter := setupTestAdapter(t)
+
+	err := adapter.WithTransaction(func(tx db.DBAdapter) error {
+		require.NoError(t, tx.Create(&Product{Name: "fig"}))
+		require.NoError(t, tx.Delete("1", &Product{}))
+		return errors.New("boom")
+	})
+	require.EqualError(t, err, "boom")
+
+	count, err := adapter.Count(&Product{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), count)
+
+	require.NoError(t, adapter.WithTransaction(func(tx db.DBAdapter) error {
+		return tx.Create(&Product{Name: "fig"})
+	}))
+	count, err = adapter.Count(&Product{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), count)
+}
+
+func TestMemoryAdapter_Concurrent(t *testing.T) {
+	adapter := New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, adapter.Create(&Product{Name: "p"}))
+			_, err := adapter.FindAll(&Product{}, nil, db.Pagination{}, nil)
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	count, err := adapter.Count(&Product{}, []db.Filter{{Field: "ID", Operator: db.OpLte, Value: "50"}})
+	require.NoError(t, err)
+	require.Equal(t, int64(50), count)
+}
+
+func TestMemoryAdapter_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := New().WithContext(ctx).FindAll(&Product{}, nil, db.Pagination{}, nil)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestMemoryAdapter_Conformance(t *testing.T) {
+	dbtest.RunConformance(t, func(t *testing.T) db.DBAdapter {
+		return New()
+	})
+}
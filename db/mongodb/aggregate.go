@@ -0,0 +1,123 @@
+package mongodb
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/Lumicrate/gompose/db"
+	gerrors "github.com/Lumicrate/gompose/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (m *MongoAdapter) Aggregate(entity any, filters []db.Filter, groupBy []string, metrics []db.Metric) ([]db.AggregateRow, error) {
+	elemType := getElemType(entity)
+	filter, err := buildFilter(elemType, filters)
+	if err != nil {
+		return nil, err
+	}
+	search, _, err := searchFilter(elemType, m.search, m.searchFields)
+	if err != nil {
+		return nil, err
+	}
+	if search != nil {
+		filter = bson.M{"$and": []bson.M{filter, search}}
+	}
+
+	group, sortDoc, err := groupStage(elemType, groupBy, metrics)
+	if err != nil {
+		return nil, err
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: m.scoped(elemType, filter)}},
+		{{Key: "$group", Value: group}},
+	}
+	if len(sortDoc) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$sort", Value: sortDoc}})
+	}
+
+	cursor, err := m.collectionFor(entity).Aggregate(m.ctx, pipeline)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer cursor.Close(m.ctx)
+
+	var docs []bson.M
+	if err := cursor.All(m.ctx, &docs); err != nil {
+		return nil, translateError(err)
+	}
+	if len(docs) == 0 && len(groupBy) == 0 {
+		// $group yields no document for no input; SQL still reports the empty totals.
+		docs = []bson.M{{}}
+	}
+
+	rows := make([]db.AggregateRow, len(docs))
+	for i, doc := range docs {
+		rows[i] = db.AggregateRow{Group: make([]any, len(groupBy)), Metrics: make([]any, len(metrics))}
+		for j := range groupBy {
+			rows[i].Group[j] = groupValue(doc["_id"], fmt.Sprintf("g%d", j))
+		}
+		for j, metric := range metrics {
+			if rows[i].Metrics[j], err = db.MetricValue(metric.Func, doc[fmt.Sprintf("m%d", j)]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return rows, nil
+}
+
+// groupValue reads a group value from the _id of a $group result.
+func groupValue(id any, alias string) any {
+	switch id := id.(type) {
+	case bson.M:
+		return id[alias]
+	case bson.D:
+		for _, e := range id {
+			if e.Key == alias {
+				return e.Value
+			}
+		}
+	}
+	return nil
+}
+
+// groupStage builds the $group stage of an aggregation, whose _id holds the group values as
+// g0, g1... and whose metrics are m0, m1..., and the $sort on the group values.
+func groupStage(t reflect.Type, groupBy []string, metrics []db.Metric) (bson.D, bson.D, error) {
+	var id any
+	var sortDoc bson.D
+	if len(groupBy) > 0 {
+		keys := bson.D{}
+		for i, name := range groupBy {
+			key, _, err := fieldByName(t, name)
+			if err != nil {
+				return nil, nil, err
+			}
+			alias := fmt.Sprintf("g%d", i)
+			keys = append(keys, bson.E{Key: alias, Value: "$" + key})
+			sortDoc = append(sortDoc, bson.E{Key: "_id." + alias, Value: 1})
+		}
+		id = keys
+	}
+
+	group := bson.D{{Key: "_id", Value: id}}
+	for i, metric := range metrics {
+		var accumulator bson.M
+		if metric.Func == db.AggCount {
+			accumulator = bson.M{"$sum": 1}
+		} else {
+			key, _, err := fieldByName(t, metric.Field)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch metric.Func {
+			case db.AggSum, db.AggAvg, db.AggMin, db.AggMax:
+				accumulator = bson.M{"$" + string(metric.Func): "$" + key}
+			default:
+				return nil, nil, gerrors.BadRequest(fmt.Sprintf("unsupported metric %q", metric.Func))
+			}
+		}
+		group = append(group, bson.E{Key: fmt.Sprintf("m%d", i), Value: accumulator})
+	}
+	return group, sortDoc, nil
+}
@@ -0,0 +1,45 @@
+package mongodb
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Lumicrate/gompose/db"
+	gerrors "github.com/Lumicrate/gompose/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type Sale struct {
+	ID     int     `bson:"id"`
+	Status string  `bson:"status"`
+	Total  float64 `bson:"total"`
+}
+
+func TestGroupStage(t *testing.T) {
+	group, sortDoc, err := groupStage(reflect.TypeOf(Sale{}), []string{"Status"}, []db.Metric{
+		{Func: db.AggCount},
+		{Func: db.AggAvg, Field: "Total"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, bson.D{
+		{Key: "_id", Value: bson.D{{Key: "g0", Value: "$status"}}},
+		{Key: "m0", Value: bson.M{"$sum": 1}},
+		{Key: "m1", Value: bson.M{"$avg": "$total"}},
+	}, group)
+	require.Equal(t, bson.D{{Key: "_id.g0", Value: 1}}, sortDoc)
+
+	group, sortDoc, err = groupStage(reflect.TypeOf(Sale{}), nil, []db.Metric{{Func: db.AggSum, Field: "Total"}})
+	require.NoError(t, err)
+	require.Equal(t, bson.D{{Key: "_id", Value: nil}, {Key: "m0", Value: bson.M{"$sum": "$total"}}}, group)
+	require.Empty(t, sortDoc)
+
+	_, _, err = groupStage(reflect.TypeOf(Sale{}), []string{"Missing"}, nil)
+	require.ErrorIs(t, err, gerrors.ErrBadRequest)
+}
+
+func TestGroupValue(t *testing.T) {
+	require.Equal(t, "paid", groupValue(bson.M{"g0": "paid"}, "g0"))
+	require.Equal(t, "paid", groupValue(bson.D{{Key: "g0", Value: "paid"}}, "g0"))
+	require.Nil(t, groupValue(nil, "g0"))
+}
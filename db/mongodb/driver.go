@@ -4,12 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"github.com/Lumicrate/gompose/db"
+	gerrors "github.com/Lumicrate/gompose/errors"
 	"github.com/Lumicrate/gompose/utils"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/net/context"
+	"log"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -20,14 +24,37 @@ type MongoAdapter struct {
 	database *mongo.Database
 	ctx      context.Context
 
-	uri    string
-	dbName string
+	uri     string
+	dbName  string
+	options Options
+
+	// transactions is true when the deployment is a replica set or a sharded cluster.
+	// Standalone servers reject multi-document transactions.
+	transactions bool
+
+	// withDeleted disables the soft-delete scope on reads.
+	withDeleted bool
+	preloads    []string
+	selects     []string
+
+	// search and searchFields are set by Search.
+	search       string
+	searchFields []string
+
+	// idStrategies holds the strategies chosen with SetIDStrategy.
+	idStrategies map[reflect.Type]string
 }
 
 func New(uri string, dbName string) *MongoAdapter {
+	return NewWithOptions(uri, dbName, Options{})
+}
+
+// NewWithOptions is New with a tuned connection pool, timeouts and read preference.
+func NewWithOptions(uri string, dbName string, opts Options) *MongoAdapter {
 	return &MongoAdapter{
-		uri:    uri,
-		dbName: dbName,
+		uri:     uri,
+		dbName:  dbName,
+		options: opts,
 	}
 }
 
@@ -35,7 +62,11 @@ func (m *MongoAdapter) Init() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(m.uri))
+	opts, err := clientOptions(m.uri, m.options)
+	if err != nil {
+		return err
+	}
+	client, err := mongo.Connect(ctx, opts)
 	if err != nil {
 		return err
 	}
@@ -44,18 +75,126 @@ func (m *MongoAdapter) Init() error {
 	m.database = client.Database(m.dbName)
 	m.ctx = context.TODO()
 
+	var hello bson.M
+	if err := m.database.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err == nil {
+		_, isReplicaSet := hello["setName"]
+		m.transactions = isReplicaSet || hello["msg"] == "isdbgrid"
+	}
+
 	return nil
 }
 
+// Ping checks that the deployment is reachable through the read preference.
+func (m *MongoAdapter) Ping() error {
+	if m.client == nil {
+		return errors.New("adapter is not initialized")
+	}
+	return m.client.Ping(m.ctx, nil)
+}
+
+// Close disconnects the client. The adapter and its copies cannot be used afterwards.
+func (m *MongoAdapter) Close() error {
+	if m.client == nil {
+		return nil
+	}
+	return m.client.Disconnect(m.ctx)
+}
+
+// Database returns the underlying database handle, e.g. for Go migrations.
+func (m *MongoAdapter) Database() *mongo.Database {
+	return m.database
+}
+
+// Migrate creates the indexes of the entities, as collections need no schema.
 func (m *MongoAdapter) Migrate(entities []any) error {
+	return m.EnsureIndexes(entities)
+}
+
+// EnsureIndexes creates the indexes declared by the entities (see indexSpec) and logs the
+// indexes that differ from their declaration. Existing indexes are never dropped or
+// rebuilt, so App.Run calls it on every start.
+func (m *MongoAdapter) EnsureIndexes(entities []any) error {
+	for _, entity := range entities {
+		drifts, err := m.ensureIndexes(entity)
+		if err != nil {
+			return err
+		}
+		for _, drift := range drifts {
+			log.Printf("mongodb: index drift on %s", drift)
+		}
+	}
 	return nil
 }
 
+func (m *MongoAdapter) WithContext(ctx context.Context) db.DBAdapter {
+	clone := *m
+	clone.ctx = ctx
+	return &clone
+}
+
+func (m *MongoAdapter) WithTransaction(fn func(tx db.DBAdapter) error) error {
+	// Without transaction support, or when already inside a session, writes go straight through.
+	if !m.transactions || mongo.SessionFromContext(m.ctx) != nil {
+		return fn(m)
+	}
+
+	session, err := m.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(m.ctx)
+
+	_, err = session.WithTransaction(m.ctx, func(sc mongo.SessionContext) (any, error) {
+		clone := *m
+		clone.ctx = sc
+		return nil, fn(&clone)
+	})
+	return err
+}
+
+// SupportsTransactions reports whether the deployment is a replica set or a sharded cluster,
+// the only ones on which WithTransaction rolls back.
+func (m *MongoAdapter) SupportsTransactions() bool {
+	return m.transactions
+}
+
+func (m *MongoAdapter) IncludeDeleted() db.DBAdapter {
+	clone := *m
+	clone.withDeleted = true
+	return &clone
+}
+
+func (m *MongoAdapter) Preload(relations ...string) db.DBAdapter {
+	clone := *m
+	clone.preloads = append(append([]string{}, m.preloads...), relations...)
+	return &clone
+}
+
+func (m *MongoAdapter) Select(fields ...string) db.DBAdapter {
+	clone := *m
+	clone.selects = append(append([]string{}, m.selects...), fields...)
+	return &clone
+}
+
+func (m *MongoAdapter) Search(query string, fields ...string) db.DBAdapter {
+	clone := *m
+	clone.search = query
+	clone.searchFields = fields
+	return &clone
+}
+
 func (m *MongoAdapter) Create(entity any) error {
 	collection := m.collectionFor(entity)
 
+	if err := m.assignID(entity); err != nil {
+		return err
+	}
+	if db.IsVersioned(entity) {
+		db.SetEntityVersion(entity, 1)
+	}
+
 	_, err := collection.InsertOne(m.ctx, entity)
-	return err
+	return translateError(err)
 }
 
 func (m *MongoAdapter) Update(entity any) error {
@@ -74,31 +213,292 @@ func (m *MongoAdapter) Update(entity any) error {
 
 	elemType := getElemType(entity)
 	typedID, err := getTypedId(idValue, elemType)
+	if err != nil {
+		return err
+	}
 
 	filter := bson.M{"id": typedID}
-	update := bson.M{"$set": updateDoc}
+	if key, ok := deletedAtKey(elemType); ok {
+		// Deleted documents cannot be updated, and the deletion mark is never overwritten by a request body.
+		filter[key] = nil
+		updateDoc = withoutKey(updateDoc, key)
+	}
+
+	key, versioned := versionKey(elemType)
+	if !versioned {
+		res, err := collection.UpdateOne(m.ctx, filter, bson.M{"$set": updateDoc})
+		if err != nil {
+			return translateError(err)
+		}
+		if res.MatchedCount == 0 {
+			return gerrors.NotFound(fmt.Sprintf("no document found with id = %v", idValue))
+		}
+		return nil
+	}
+
+	update := bson.M{"$set": withoutKey(updateDoc, key), "$inc": bson.M{key: 1}}
+	conditional := bson.M{}
+	for k, v := range filter {
+		conditional[k] = v
+	}
+	if expected := db.EntityVersion(entity); expected > 0 {
+		conditional[key] = expected
+	}
 
-	res, err := collection.UpdateOne(m.ctx, filter, update)
+	// Decoding the updated document hands the new version back to the caller.
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = collection.FindOneAndUpdate(m.ctx, conditional, update, opts).Decode(entity)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return m.unmatchedWriteError(collection, filter)
+	}
+	return translateError(err)
+}
+
+func (m *MongoAdapter) Delete(id string, entity any) error {
+	collection := m.collectionFor(entity)
+
+	// Determine the correct ID type from the entity
+	elemType := getElemType(entity)
+	typedID, err := getTypedId(id, elemType)
 	if err != nil {
 		return err
 	}
+
+	filter := bson.M{"id": typedID}
+	deletedKey, soft := deletedAtKey(elemType)
+	if soft {
+		filter[deletedKey] = nil
+	}
+
+	conditional := bson.M{}
+	for k, v := range filter {
+		conditional[k] = v
+	}
+	key, versioned := versionKey(elemType)
+	expected := db.EntityVersion(entity)
+	if versioned && expected > 0 {
+		conditional[key] = expected
+	}
+
+	if !soft {
+		res, err := collection.DeleteOne(m.ctx, conditional)
+		if err != nil {
+			return translateError(err)
+		}
+		if res.DeletedCount == 0 {
+			return m.unmatchedWriteError(collection, filter)
+		}
+		return nil
+	}
+
+	res, err := collection.UpdateOne(m.ctx, conditional, bson.M{"$set": bson.M{deletedKey: time.Now()}})
+	if err != nil {
+		return translateError(err)
+	}
 	if res.MatchedCount == 0 {
-		return fmt.Errorf("no document found with id = %v", idValue)
+		return m.unmatchedWriteError(collection, filter)
 	}
 	return nil
 }
 
-func (m *MongoAdapter) Delete(id string, entity any) error {
+// Upsert uses a native upsert for entities without version or soft delete, which is atomic
+// even without transactions. The others need the checks of Update and go through db.UpsertWith.
+func (m *MongoAdapter) Upsert(entity any) (bool, error) {
+	elemType := getElemType(entity)
+	_, versioned := versionKey(elemType)
+	_, soft := deletedAtKey(elemType)
+	if versioned || soft {
+		var created bool
+		err := m.WithTransaction(func(tx db.DBAdapter) error {
+			var err error
+			created, err = db.UpsertWith(tx, entity)
+			return err
+		})
+		return created, err
+	}
+
+	// For Increment IDs this moves the counter past the ID, in case the document is created.
+	if err := m.assignID(entity); err != nil {
+		return false, err
+	}
+	idValue, err := getEntityID(entity)
+	if err != nil {
+		return false, err
+	}
+	typedID, err := getTypedId(idValue, elemType)
+	if err != nil {
+		return false, err
+	}
+	doc, err := toBsonDWithoutID(entity)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := m.collectionFor(entity).UpdateOne(m.ctx, bson.M{"id": typedID}, bson.M{"$set": doc}, options.Update().SetUpsert(true))
+	if err != nil {
+		return false, translateError(err)
+	}
+	return res.UpsertedCount > 0, nil
+}
+
+func (m *MongoAdapter) CreateMany(entities []any) error {
+	if len(entities) == 0 {
+		return nil
+	}
+	collection := m.collectionFor(entities[0])
+
+	for i, entity := range entities {
+		if err := m.assignID(entity); err != nil {
+			return &db.BatchError{Index: i, Err: err}
+		}
+		if db.IsVersioned(entity) {
+			db.SetEntityVersion(entity, 1)
+		}
+	}
+
+	if !m.transactions {
+		// Nothing can be rolled back, so every item is attempted and the failed ones reported.
+		_, err := collection.InsertMany(m.ctx, entities, options.InsertMany().SetOrdered(false))
+		return partialBatchError(err)
+	}
+
+	return m.WithTransaction(func(tx db.DBAdapter) error {
+		// Inserts are ordered, so the first write error is the item that stopped the batch.
+		_, err := collection.InsertMany(tx.(*MongoAdapter).ctx, entities)
+		var bulkErr mongo.BulkWriteException
+		if errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0 {
+			first := bulkErr.WriteErrors[0]
+			return &db.BatchError{Index: first.Index, Err: translateError(first.WriteError)}
+		}
+		return translateError(err)
+	})
+}
+
+// UpdateMany updates document by document, since every document may carry its own version condition.
+func (m *MongoAdapter) UpdateMany(entities []any) error {
+	if !m.transactions {
+		partial := &db.PartialBatchError{}
+		for i, entity := range entities {
+			if err := m.Update(entity); err != nil {
+				partial.Errors = append(partial.Errors, &db.BatchError{Index: i, Err: err})
+			}
+		}
+		if len(partial.Errors) > 0 {
+			return partial
+		}
+		return nil
+	}
+
+	return m.WithTransaction(func(tx db.DBAdapter) error {
+		for i, entity := range entities {
+			if err := tx.Update(entity); err != nil {
+				return &db.BatchError{Index: i, Err: err}
+			}
+		}
+		return nil
+	})
+}
+
+func (m *MongoAdapter) DeleteMany(ids []string, entity any) error {
+	collection := m.collectionFor(entity)
+	elemType := getElemType(entity)
+	deletedKey, soft := deletedAtKey(elemType)
+
+	typedIDs := make([]any, len(ids))
+	models := make([]mongo.WriteModel, len(ids))
+	now := time.Now()
+	for i, id := range ids {
+		typedID, err := getTypedId(id, elemType)
+		if err != nil {
+			return &db.BatchError{Index: i, Err: err}
+		}
+		typedIDs[i] = typedID
+
+		filter := bson.M{"id": typedID}
+		if soft {
+			filter[deletedKey] = nil
+			models[i] = mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.M{"$set": bson.M{deletedKey: now}})
+		} else {
+			models[i] = mongo.NewDeleteOneModel().SetFilter(filter)
+		}
+	}
+
+	return m.WithTransaction(func(tx db.DBAdapter) error {
+		ctx := tx.(*MongoAdapter).ctx
+
+		// Missing documents are looked up before writing, so that a batch failing on one
+		// leaves the others alone even on deployments without transactions.
+		filter := bson.M{"id": bson.M{"$in": typedIDs}}
+		if soft {
+			filter[deletedKey] = nil
+		}
+		cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"id": 1}))
+		if err != nil {
+			return translateError(err)
+		}
+		var existing []bson.M
+		if err := cursor.All(ctx, &existing); err != nil {
+			return translateError(err)
+		}
+		found := make(map[string]bool, len(existing))
+		for _, doc := range existing {
+			found[fmt.Sprint(doc["id"])] = true
+		}
+		for i, typedID := range typedIDs {
+			if !found[fmt.Sprint(typedID)] {
+				return &db.BatchError{Index: i, Err: gerrors.NotFound(fmt.Sprintf("no document found with id = %v", ids[i]))}
+			}
+		}
+
+		if !m.transactions {
+			res, err := collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+			if err != nil {
+				return partialBatchError(err)
+			}
+			if res.DeletedCount+res.MatchedCount < int64(len(ids)) {
+				return gerrors.NotFound("entity not found")
+			}
+			return nil
+		}
+
+		res, err := collection.BulkWrite(ctx, models)
+		if err != nil {
+			return translateError(err)
+		}
+		if res.DeletedCount+res.MatchedCount < int64(len(ids)) {
+			return gerrors.NotFound("entity not found")
+		}
+		return nil
+	})
+}
+
+func (m *MongoAdapter) Restore(id string, entity any) error {
 	collection := m.collectionFor(entity)
 
-	// Determine the correct ID type from the entity
 	elemType := getElemType(entity)
+	key, ok := deletedAtKey(elemType)
+	if !ok {
+		return gerrors.BadRequest(fmt.Sprintf("%s does not support soft delete", elemType.Name()))
+	}
+
 	typedID, err := getTypedId(id, elemType)
-	_, err = collection.DeleteOne(m.ctx, bson.M{"id": typedID})
-	return err
+	if err != nil {
+		return err
+	}
+
+	res, err := collection.UpdateOne(m.ctx,
+		bson.M{"id": typedID, key: bson.M{"$ne": nil}},
+		bson.M{"$set": bson.M{key: nil}})
+	if err != nil {
+		return translateError(err)
+	}
+	if res.MatchedCount == 0 {
+		return gerrors.NotFound(fmt.Sprintf("no deleted document found with id = %v", id))
+	}
+	return nil
 }
 
-func (m *MongoAdapter) FindAll(entity any, filters map[string]any, pagination db.Pagination, sort []db.Sort) (any, error) {
+func (m *MongoAdapter) FindAll(entity any, filters []db.Filter, pagination db.Pagination, sort []db.Sort) (any, error) {
 	entityType := reflect.TypeOf(entity)
 	if entityType.Kind() == reflect.Ptr {
 		entityType = entityType.Elem()
@@ -113,29 +513,90 @@ func (m *MongoAdapter) FindAll(entity any, filters map[string]any, pagination db
 	if pagination.Limit > 0 {
 		findOptions.SetLimit(int64(pagination.Limit))
 	}
-	if pagination.Offset > 0 {
+	if pagination.Offset > 0 && len(pagination.After) == 0 {
 		findOptions.SetSkip(int64(pagination.Offset))
 	}
+	sortDoc := bson.D{}
 	if len(sort) > 0 {
-		sortDoc := bson.D{}
 		for _, s := range sort {
 			dir := 1
 			if s.Direction == "desc" {
 				dir = -1
 			}
-			sortDoc = append(sortDoc, bson.E{Key: s.Field, Value: dir})
+			key, _, err := fieldByName(entityType, s.Field)
+			if err != nil {
+				return nil, err
+			}
+			sortDoc = append(sortDoc, bson.E{Key: key, Value: dir})
 		}
 		findOptions.SetSort(sortDoc)
 	}
 
-	cursor, err := collection.Find(m.ctx, filters, findOptions)
+	filter, err := buildFilter(entityType, filters)
 	if err != nil {
 		return nil, err
 	}
+
+	search, ranked, err := searchFilter(entityType, m.search, m.searchFields)
+	if err != nil {
+		return nil, err
+	}
+	if search != nil {
+		filter = bson.M{"$and": []bson.M{filter, search}}
+		if ranked && len(sort) == 0 {
+			sortDoc = textScoreSort
+			findOptions.SetSort(sortDoc)
+		}
+	}
+
+	if len(pagination.After) > 0 {
+		keyset, err := keysetCondition(entityType, sort, pagination.After)
+		if err != nil {
+			return nil, err
+		}
+		filter = bson.M{"$and": []bson.M{filter, keyset}}
+	}
+	filter = m.scoped(entityType, filter)
+
+	projection, err := m.projection(entityType)
+	if err != nil {
+		return nil, err
+	}
+	if projection != nil {
+		findOptions.SetProjection(projection)
+	}
+
+	var cursor *mongo.Cursor
+	if len(m.preloads) > 0 {
+		lookups, err := lookupStages(entityType, m.preloads)
+		if err != nil {
+			return nil, err
+		}
+
+		pipeline := mongo.Pipeline{{{Key: "$match", Value: filter}}}
+		if len(sortDoc) > 0 {
+			pipeline = append(pipeline, bson.D{{Key: "$sort", Value: sortDoc}})
+		}
+		if findOptions.Skip != nil {
+			pipeline = append(pipeline, bson.D{{Key: "$skip", Value: *findOptions.Skip}})
+		}
+		if findOptions.Limit != nil {
+			pipeline = append(pipeline, bson.D{{Key: "$limit", Value: *findOptions.Limit}})
+		}
+		if projection != nil {
+			pipeline = append(pipeline, bson.D{{Key: "$project", Value: projection}})
+		}
+		cursor, err = collection.Aggregate(m.ctx, append(pipeline, lookups...))
+	} else {
+		cursor, err = collection.Find(m.ctx, filter, findOptions)
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
 	defer cursor.Close(m.ctx)
 
 	if err := cursor.All(m.ctx, slicePtr); err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	return reflect.ValueOf(slicePtr).Elem().Interface(), nil
@@ -146,20 +607,209 @@ func (m *MongoAdapter) FindByID(id string, entity any) (any, error) {
 
 	elemType := getElemType(entity)
 	typedID, err := getTypedId(id, elemType)
+	if err != nil {
+		return nil, err
+	}
 	result := reflect.New(elemType).Interface()
-	err = collection.FindOne(m.ctx, bson.M{"id": typedID}).Decode(result)
+	filter := m.scoped(elemType, bson.M{"id": typedID})
+
+	projection, err := m.projection(elemType)
 	if err != nil {
 		return nil, err
 	}
+
+	if len(m.preloads) > 0 {
+		lookups, err := lookupStages(elemType, m.preloads)
+		if err != nil {
+			return nil, err
+		}
+
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: filter}},
+			{{Key: "$limit", Value: 1}},
+		}
+		if projection != nil {
+			pipeline = append(pipeline, bson.D{{Key: "$project", Value: projection}})
+		}
+		pipeline = append(pipeline, lookups...)
+		cursor, err := collection.Aggregate(m.ctx, pipeline)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		defer cursor.Close(m.ctx)
+
+		if !cursor.Next(m.ctx) {
+			if err := cursor.Err(); err != nil {
+				return nil, translateError(err)
+			}
+			return nil, translateError(mongo.ErrNoDocuments)
+		}
+		if err := cursor.Decode(result); err != nil {
+			return nil, translateError(err)
+		}
+		return result, nil
+	}
+
+	findOptions := options.FindOne()
+	if projection != nil {
+		findOptions.SetProjection(projection)
+	}
+	err = collection.FindOne(m.ctx, filter, findOptions).Decode(result)
+	if err != nil {
+		return nil, translateError(err)
+	}
 	return result, nil
 }
 
+func (m *MongoAdapter) Count(entity any, filters []db.Filter) (int64, error) {
+	collection := m.collectionFor(entity)
+
+	elemType := getElemType(entity)
+	filter, err := buildFilter(elemType, filters)
+	if err != nil {
+		return 0, err
+	}
+	search, _, err := searchFilter(elemType, m.search, m.searchFields)
+	if err != nil {
+		return 0, err
+	}
+	if search != nil {
+		filter = bson.M{"$and": []bson.M{filter, search}}
+	}
+
+	count, err := collection.CountDocuments(m.ctx, m.scoped(elemType, filter))
+	return count, translateError(err)
+}
+
 func (m *MongoAdapter) collectionFor(entity any) *mongo.Collection {
 	t := reflect.TypeOf(entity)
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
-	return m.database.Collection(strings.ToLower(utils.Pluralize(t.Name())))
+	return m.database.Collection(collectionName(t))
+}
+
+// collectionName names the collection of entities of type t: the name returned by their
+// TableName method, as with GORM, or else their pluralized lower-case type name.
+func collectionName(t reflect.Type) string {
+	if tabler, ok := reflect.New(t).Interface().(interface{ TableName() string }); ok {
+		return tabler.TableName()
+	}
+	return strings.ToLower(utils.Pluralize(t.Name()))
+}
+
+// lookupStages builds a $lookup for every requested relation. Single-valued relations
+// are unwrapped from the array $lookup produces.
+func lookupStages(elemType reflect.Type, relations []string) (mongo.Pipeline, error) {
+	var stages mongo.Pipeline
+	for _, name := range relations {
+		rel, ok := db.RelationByField(elemType, name)
+		if !ok {
+			return nil, gerrors.BadRequest(fmt.Sprintf("unknown relation %q", name))
+		}
+
+		as, _, err := fieldByName(elemType, rel.Field)
+		if err != nil {
+			return nil, err
+		}
+
+		// For BelongsTo the owner holds the foreign key, otherwise the target does.
+		localType, localField, foreignType, foreignField := elemType, rel.References, rel.Target, rel.ForeignKey
+		if rel.Kind == db.BelongsTo {
+			localField, foreignField = rel.ForeignKey, rel.References
+		}
+		localKey, _, err := fieldByName(localType, localField)
+		if err != nil {
+			return nil, err
+		}
+		foreignKey, _, err := fieldByName(foreignType, foreignField)
+		if err != nil {
+			return nil, err
+		}
+
+		stages = append(stages, bson.D{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: collectionName(rel.Target)},
+			{Key: "localField", Value: localKey},
+			{Key: "foreignField", Value: foreignKey},
+			{Key: "as", Value: as},
+		}}})
+		if rel.Kind != db.HasMany {
+			stages = append(stages, bson.D{{Key: "$addFields", Value: bson.D{
+				{Key: as, Value: bson.D{{Key: "$arrayElemAt", Value: bson.A{"$" + as, 0}}}},
+			}}})
+		}
+	}
+	return stages, nil
+}
+
+// scoped restricts filter to documents that are not soft-deleted, unless deleted ones were requested.
+func (m *MongoAdapter) scoped(elemType reflect.Type, filter bson.M) bson.M {
+	key, ok := deletedAtKey(elemType)
+	if !ok || m.withDeleted {
+		return filter
+	}
+	if len(filter) == 0 {
+		return bson.M{key: nil}
+	}
+	return bson.M{"$and": []bson.M{filter, {key: nil}}}
+}
+
+// projection includes only the selected fields, or is nil when every field is loaded.
+func (m *MongoAdapter) projection(elemType reflect.Type) (bson.M, error) {
+	if len(m.selects) == 0 {
+		return nil, nil
+	}
+	projection := bson.M{}
+	for _, name := range db.SelectedFields(elemType, m.selects, m.preloads) {
+		key, _, err := fieldByName(elemType, name)
+		if err != nil {
+			return nil, err
+		}
+		projection[key] = 1
+	}
+	return projection, nil
+}
+
+// unmatchedWriteError explains a conditional write that matched no document:
+// either the document is gone or its version moved on. filter identifies the document.
+func (m *MongoAdapter) unmatchedWriteError(collection *mongo.Collection, filter bson.M) error {
+	count, err := collection.CountDocuments(m.ctx, filter)
+	if err != nil {
+		return translateError(err)
+	}
+	if count == 0 {
+		return gerrors.NotFound(fmt.Sprintf("no document found with id = %v", filter["id"]))
+	}
+	return gerrors.PreconditionFailed("entity was modified by another request")
+}
+
+// versionKey returns the bson key of the entity's Version field, if it has one.
+func versionKey(elemType reflect.Type) (string, bool) {
+	if !db.IsVersioned(reflect.New(elemType).Interface()) {
+		return "", false
+	}
+	key, _, err := fieldByName(elemType, db.VersionField)
+	return key, err == nil
+}
+
+// deletedAtKey returns the bson key of the entity's DeletedAt field, if it has one.
+// A nil value matches both null and missing keys, so documents written before the field existed count as live.
+func deletedAtKey(elemType reflect.Type) (string, bool) {
+	if _, ok := elemType.FieldByName(db.DeletedAtField); !ok {
+		return "", false
+	}
+	key, _, err := fieldByName(elemType, db.DeletedAtField)
+	return key, err == nil
+}
+
+func withoutKey(doc bson.D, key string) bson.D {
+	cleaned := doc[:0]
+	for _, elem := range doc {
+		if elem.Key != key {
+			cleaned = append(cleaned, elem)
+		}
+	}
+	return cleaned
 }
 
 func getEntityID(entity any) (string, error) {
@@ -173,12 +823,15 @@ func getEntityID(entity any) (string, error) {
 		return "", errors.New("ID field not found")
 	}
 
-	if idField.Kind() == reflect.String {
+	switch {
+	case idField.Type() == reflect.TypeOf(primitive.ObjectID{}):
+		return idField.Interface().(primitive.ObjectID).Hex(), nil
+	case idField.Kind() == reflect.String:
 		return idField.String(), nil
-	}
-
-	if idField.Kind() == reflect.Int || idField.Kind() == reflect.Int64 || idField.Kind() == reflect.Int32 {
-		return fmt.Sprintf("%d", idField.Int()), nil
+	case idField.CanInt():
+		return strconv.FormatInt(idField.Int(), 10), nil
+	case idField.CanUint():
+		return strconv.FormatUint(idField.Uint(), 10), nil
 	}
 
 	return "", errors.New("unsupported ID type")
@@ -213,32 +866,227 @@ func getElemType(entity any) reflect.Type {
 	return elemType
 }
 
+// getTypedId parses a path ID into the type of the entity's ID field, so that it matches the stored value.
 func getTypedId(id string, elemType reflect.Type) (any, error) {
 	idField, ok := elemType.FieldByName("ID")
 	if !ok {
 		return nil, fmt.Errorf("entity does not have an ID field")
 	}
 
-	// Convert string ID to correct type
-	var typedID any
-	switch idField.Type.Kind() {
-	case reflect.Int, reflect.Int64:
-		if intVal, err := strconv.Atoi(id); err == nil {
-			typedID = intVal
-		} else {
-			return nil, fmt.Errorf("invalid int ID: %v", err)
+	switch kind := idField.Type.Kind(); {
+	case idField.Type == reflect.TypeOf(primitive.ObjectID{}):
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, gerrors.BadRequest(fmt.Sprintf("invalid ObjectID: %q", id))
 		}
-	case reflect.Uint, reflect.Uint64:
-		if uintVal, err := strconv.ParseUint(id, 10, 64); err == nil {
-			typedID = uintVal
-		} else {
-			return nil, fmt.Errorf("invalid uint ID: %v", err)
+		return oid, nil
+	case kind == reflect.String:
+		return reflect.ValueOf(id).Convert(idField.Type).Interface(), nil
+	case kind >= reflect.Int && kind <= reflect.Int64:
+		n, err := strconv.ParseInt(id, 10, idField.Type.Bits())
+		if err != nil {
+			return nil, gerrors.BadRequest(fmt.Sprintf("invalid int ID: %v", err))
 		}
-	case reflect.String:
-		typedID = id
-	default:
-		return nil, fmt.Errorf("unsupported ID type: %s", idField.Type.Kind())
+		return reflect.ValueOf(n).Convert(idField.Type).Interface(), nil
+	case kind >= reflect.Uint && kind <= reflect.Uint64:
+		n, err := strconv.ParseUint(id, 10, idField.Type.Bits())
+		if err != nil {
+			return nil, gerrors.BadRequest(fmt.Sprintf("invalid uint ID: %v", err))
+		}
+		return reflect.ValueOf(n).Convert(idField.Type).Interface(), nil
+	}
+
+	return nil, fmt.Errorf("unsupported ID type: %s", idField.Type)
+}
+
+func buildFilter(elemType reflect.Type, filters []db.Filter) (bson.M, error) {
+	if len(filters) == 0 {
+		return bson.M{}, nil
+	}
+
+	conditions := make([]bson.M, 0, len(filters))
+	for _, f := range filters {
+		key, fieldType, err := fieldByName(elemType, f.Field)
+		if err != nil {
+			return nil, err
+		}
+		value, err := coerceFilterValue(f.Field, fieldType, f.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		var cond any
+		switch f.Operator {
+		case db.OpEq:
+			cond = value
+		case db.OpNe:
+			cond = bson.M{"$ne": value}
+		case db.OpGt:
+			cond = bson.M{"$gt": value}
+		case db.OpGte:
+			cond = bson.M{"$gte": value}
+		case db.OpLt:
+			cond = bson.M{"$lt": value}
+		case db.OpLte:
+			cond = bson.M{"$lte": value}
+		case db.OpIn:
+			cond = bson.M{"$in": value}
+		case db.OpLike:
+			cond = bson.M{"$regex": db.LikeToRegexp(fmt.Sprint(f.Value))}
+		case db.OpNull:
+			if isNull, _ := f.Value.(bool); isNull {
+				cond = nil
+			} else {
+				cond = bson.M{"$ne": nil}
+			}
+		default:
+			return nil, gerrors.BadRequest(fmt.Sprintf("unsupported filter operator %q", f.Operator))
+		}
+		conditions = append(conditions, bson.M{key: cond})
+	}
+
+	return bson.M{"$and": conditions}, nil
+}
+
+// translateError maps Mongo driver errors onto gompose errors.
+func translateError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return gerrors.NotFound("entity not found").Wrap(err)
+	case mongo.IsDuplicateKeyError(err):
+		return gerrors.Conflict("entity already exists").Wrap(err)
+	}
+	return err
+}
+
+// partialBatchError reports the write errors of an unordered bulk write, after which the
+// other items stay written, as a db.PartialBatchError.
+func partialBatchError(err error) error {
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || len(bulkErr.WriteErrors) == 0 {
+		return translateError(err)
+	}
+
+	partial := &db.PartialBatchError{}
+	for _, writeErr := range bulkErr.WriteErrors {
+		partial.Errors = append(partial.Errors, &db.BatchError{Index: writeErr.Index, Err: translateError(writeErr.WriteError)})
+	}
+	sort.Slice(partial.Errors, func(i, j int) bool { return partial.Errors[i].Index < partial.Errors[j].Index })
+	return partial
+}
+
+// keysetCondition selects documents strictly after the given sort key values,
+// honouring the direction of every sort key.
+func keysetCondition(elemType reflect.Type, sort []db.Sort, after []any) (bson.M, error) {
+	if len(after) != len(sort) {
+		return nil, gerrors.BadRequest(fmt.Sprintf("cursor has %d values but %d sort fields", len(after), len(sort)))
+	}
+
+	keys := make([]string, len(sort))
+	values := make([]any, len(after))
+	for i, s := range sort {
+		key, fieldType, err := fieldByName(elemType, s.Field)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+		if values[i], err = coerceFilterValue(s.Field, fieldType, after[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	clauses := make([]bson.M, 0, len(sort))
+	for i, s := range sort {
+		clause := bson.M{}
+		for j := 0; j < i; j++ {
+			clause[keys[j]] = values[j]
+		}
+
+		op := "$gt"
+		if s.Direction == "desc" {
+			op = "$lt"
+		}
+		clause[keys[i]] = bson.M{op: values[i]}
+
+		clauses = append(clauses, clause)
+	}
+
+	return bson.M{"$or": clauses}, nil
+}
+
+// fieldByName resolves a Go field name, bson key or json tag to the bson key and Go type of
+// the entity's field. Unknown names are rejected so arbitrary query keys never reach the query.
+func fieldByName(elemType reflect.Type, name string) (string, reflect.Type, error) {
+	for _, f := range reflect.VisibleFields(elemType) {
+		if f.Anonymous || !f.IsExported() {
+			continue
+		}
+		bsonName := strings.Split(f.Tag.Get("bson"), ",")[0]
+		if bsonName == "-" {
+			continue
+		}
+		if bsonName == "" {
+			bsonName = strings.ToLower(f.Name)
+		}
+		if f.Name == name || bsonName == name || strings.Split(f.Tag.Get("json"), ",")[0] == name {
+			return bsonName, f.Type, nil
+		}
+	}
+	return "", nil, gerrors.BadRequest(fmt.Sprintf("unknown field %q", name))
+}
+
+// coerceFilterValue converts query string values to the field's type, since Mongo compares by BSON type.
+// Values the type cannot hold are rejected.
+func coerceFilterValue(name string, t reflect.Type, value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return coerceString(name, t, v)
+	case []string:
+		values := make([]any, len(v))
+		for i, s := range v {
+			var err error
+			if values[i], err = coerceString(name, t, s); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+	return value, nil
+}
+
+func coerceString(name string, t reflect.Type, s string) (any, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
 	}
 
-	return typedID, nil
+	var value any
+	var err error
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, err = strconv.ParseInt(s, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value, err = strconv.ParseUint(s, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		value, err = strconv.ParseFloat(s, 64)
+	case reflect.Bool:
+		value, err = strconv.ParseBool(s)
+	case reflect.Array:
+		if t != reflect.TypeOf(primitive.ObjectID{}) {
+			return s, nil
+		}
+		value, err = primitive.ObjectIDFromHex(s)
+	case reflect.Struct:
+		if t != reflect.TypeOf(time.Time{}) {
+			return s, nil
+		}
+		value, err = time.Parse(time.RFC3339Nano, s)
+	default:
+		return s, nil
+	}
+	if err != nil {
+		return nil, gerrors.BadRequest(fmt.Sprintf("invalid value %v for field %q", s, name))
+	}
+	return value, nil
 }
@@ -1,10 +1,17 @@
 package mongodb
 
 import (
+	"context"
+	"fmt"
 	"github.com/Lumicrate/gompose/db"
+	"github.com/Lumicrate/gompose/db/dbtest"
+	gerrors "github.com/Lumicrate/gompose/errors"
 	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"os"
 	"reflect"
 	"testing"
+	"time"
 )
 
 // Mock DB Adapter
@@ -17,15 +24,32 @@ func NewMockDB() *MockDB {
 	return &MockDB{Entities: make(map[string]any)}
 }
 
-func (m *MockDB) Init() error                        { return nil }
-func (m *MockDB) Migrate(entities []any) error       { return nil }
-func (m *MockDB) Create(entity any) error            { return nil }
-func (m *MockDB) Update(entity any) error            { return nil }
-func (m *MockDB) Delete(id string, entity any) error { return nil }
-func (m *MockDB) FindAll(entity any, filters map[string]any, pagination db.Pagination, sort []db.Sort) (any, error) {
+func (m *MockDB) Init() error                                          { return nil }
+func (m *MockDB) Migrate(entities []any) error                         { return nil }
+func (m *MockDB) Ping() error                                          { return nil }
+func (m *MockDB) Close() error                                         { return nil }
+func (m *MockDB) WithContext(ctx context.Context) db.DBAdapter         { return m }
+func (m *MockDB) WithTransaction(fn func(tx db.DBAdapter) error) error { return fn(m) }
+func (m *MockDB) Create(entity any) error                              { return nil }
+func (m *MockDB) Update(entity any) error                              { return nil }
+func (m *MockDB) IncludeDeleted() db.DBAdapter                         { return m }
+func (m *MockDB) Preload(relations ...string) db.DBAdapter             { return m }
+func (m *MockDB) Select(fields ...string) db.DBAdapter                 { return m }
+func (m *MockDB) Search(query string, fields ...string) db.DBAdapter   { return m }
+func (m *MockDB) Delete(id string, entity any) error                   { return nil }
+func (m *MockDB) Restore(id string, entity any) error                  { return nil }
+func (m *MockDB) Upsert(entity any) (bool, error)                      { return false, nil }
+func (m *MockDB) CreateMany(entities []any) error                      { return nil }
+func (m *MockDB) UpdateMany(entities []any) error                      { return nil }
+func (m *MockDB) DeleteMany(ids []string, entity any) error            { return nil }
+func (m *MockDB) FindAll(entity any, filters []db.Filter, pagination db.Pagination, sort []db.Sort) (any, error) {
 	return []any{}, nil
 }
-func (m *MockDB) FindByID(id string, entity any) (any, error) { return entity, nil }
+func (m *MockDB) FindByID(id string, entity any) (any, error)          { return entity, nil }
+func (m *MockDB) Count(entity any, filters []db.Filter) (int64, error) { return 0, nil }
+func (m *MockDB) Aggregate(entity any, filters []db.Filter, groupBy []string, metrics []db.Metric) ([]db.AggregateRow, error) {
+	return nil, nil
+}
 
 // Test Entities
 
@@ -122,9 +146,179 @@ func TestMockFindAllWithFilters(t *testing.T) {
 	mock.Entities["1"] = &TestEntity{ID: "1", Name: "Alice"}
 	mock.Entities["2"] = &TestEntity{ID: "2", Name: "Bob"}
 
-	filters := map[string]any{"Name": "Alice"}
+	filters := []db.Filter{db.Eq("Name", "Alice")}
 
 	result, err := mock.FindAll(TestEntity{}, filters, db.Pagination{}, nil)
 	require.NoError(t, err)
 	require.IsType(t, []any{}, result)
 }
+
+// Filter Translation Tests
+
+type TestFilterEntity struct {
+	ID    int    `bson:"id"`
+	Name  string `bson:"name"`
+	Age   int    `bson:"age"`
+	Email string `bson:"email_address" json:"email"`
+}
+
+func TestBuildFilter_Operators(t *testing.T) {
+	typ := reflect.TypeOf(TestFilterEntity{})
+	filter, err := buildFilter(typ, []db.Filter{
+		{Field: "age", Operator: db.OpGte, Value: "18"},
+		{Field: "id", Operator: db.OpIn, Value: []string{"1", "2"}},
+		{Field: "name", Operator: db.OpLike, Value: "jo%"},
+		{Field: "email", Operator: db.OpNull, Value: false},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, bson.M{"$and": []bson.M{
+		{"age": bson.M{"$gte": int64(18)}},
+		{"id": bson.M{"$in": []any{int64(1), int64(2)}}},
+		{"name": bson.M{"$regex": "^jo.*$"}},
+		{"email_address": bson.M{"$ne": nil}},
+	}}, filter)
+}
+
+func TestBuildFilter_UnknownField(t *testing.T) {
+	_, err := buildFilter(reflect.TypeOf(TestFilterEntity{}), []db.Filter{db.Eq("$where", "1")})
+	require.EqualError(t, err, `unknown field "$where"`)
+}
+
+func TestBuildFilter_Empty(t *testing.T) {
+	filter, err := buildFilter(reflect.TypeOf(TestFilterEntity{}), nil)
+	require.NoError(t, err)
+	require.Equal(t, bson.M{}, filter)
+}
+
+func TestKeysetCondition(t *testing.T) {
+	typ := reflect.TypeOf(TestFilterEntity{})
+	sort := []db.Sort{{Field: "age", Direction: "desc"}, {Field: "id", Direction: "asc"}}
+
+	cond, err := keysetCondition(typ, sort, []any{"30", "7"})
+	require.NoError(t, err)
+	require.Equal(t, bson.M{"$or": []bson.M{
+		{"age": bson.M{"$lt": int64(30)}},
+		{"age": int64(30), "id": bson.M{"$gt": int64(7)}},
+	}}, cond)
+
+	_, err = keysetCondition(typ, sort, []any{"30"})
+	require.Error(t, err)
+}
+
+type SoftEntity struct {
+	ID        string     `bson:"id"`
+	DeletedAt *time.Time `bson:"deleted_at"`
+}
+
+func TestScoped_SoftDelete(t *testing.T) {
+	elemType := reflect.TypeOf(SoftEntity{})
+	adapter := &MongoAdapter{}
+
+	require.Equal(t, bson.M{"deleted_at": nil}, adapter.scoped(elemType, bson.M{}))
+	require.Equal(t,
+		bson.M{"$and": []bson.M{{"id": "1"}, {"deleted_at": nil}}},
+		adapter.scoped(elemType, bson.M{"id": "1"}))
+
+	withDeleted := adapter.IncludeDeleted().(*MongoAdapter)
+	require.Equal(t, bson.M{"id": "1"}, withDeleted.scoped(elemType, bson.M{"id": "1"}))
+
+	// Entities without DeletedAt are never scoped
+	require.Equal(t, bson.M{}, adapter.scoped(reflect.TypeOf(TestFilterEntity{}), bson.M{}))
+}
+
+func TestWithoutKey(t *testing.T) {
+	doc := bson.D{{Key: "id", Value: "1"}, {Key: "deleted_at", Value: nil}, {Key: "name", Value: "a"}}
+	require.Equal(t, bson.D{{Key: "id", Value: "1"}, {Key: "name", Value: "a"}}, withoutKey(doc, "deleted_at"))
+}
+
+type Customer struct {
+	ID     int     `bson:"id"`
+	Orders []Order `bson:"orders,omitempty"`
+}
+
+type Order struct {
+	ID         int       `bson:"id"`
+	CustomerID int       `bson:"customer_id"`
+	Customer   *Customer `bson:"customer,omitempty"`
+}
+
+type tabled struct{}
+
+func (tabled) TableName() string { return "custom_things" }
+
+func TestCollectionName(t *testing.T) {
+	require.Equal(t, "orders", collectionName(reflect.TypeOf(Order{})))
+	require.Equal(t, "custom_things", collectionName(reflect.TypeOf(tabled{})))
+}
+
+func TestLookupStages(t *testing.T) {
+	stages, err := lookupStages(reflect.TypeOf(Order{}), []string{"Customer"})
+	require.NoError(t, err)
+	require.Equal(t, bson.D{{Key: "$lookup", Value: bson.D{
+		{Key: "from", Value: "customers"},
+		{Key: "localField", Value: "customer_id"},
+		{Key: "foreignField", Value: "id"},
+		{Key: "as", Value: "customer"},
+	}}}, stages[0])
+	require.Len(t, stages, 2, "belongs-to relations are unwrapped from the lookup array")
+
+	stages, err = lookupStages(reflect.TypeOf(Customer{}), []string{"Orders"})
+	require.NoError(t, err)
+	require.Equal(t, bson.D{{Key: "$lookup", Value: bson.D{
+		{Key: "from", Value: "orders"},
+		{Key: "localField", Value: "id"},
+		{Key: "foreignField", Value: "customer_id"},
+		{Key: "as", Value: "orders"},
+	}}}, stages[0])
+	require.Len(t, stages, 1)
+
+	_, err = lookupStages(reflect.TypeOf(Customer{}), []string{"ID"})
+	require.Error(t, err)
+}
+
+// setupLiveAdapter connects to a real server in a fresh database, e.g.
+//
+//	docker run -d -p 27017:27017 mongo:7
+//	GOMPOSE_MONGO_URI="mongodb://localhost:27017" go test ./db/mongodb
+func setupLiveAdapter(t *testing.T) *MongoAdapter {
+	uri := os.Getenv("GOMPOSE_MONGO_URI")
+	if uri == "" {
+		t.Skip("GOMPOSE_MONGO_URI not set")
+	}
+
+	adapter := New(uri, fmt.Sprintf("gompose_test_%d", time.Now().UnixNano()))
+	require.NoError(t, adapter.Init())
+	t.Cleanup(func() {
+		_ = adapter.database.Drop(context.Background())
+		_ = adapter.client.Disconnect(context.Background())
+	})
+	return adapter
+}
+
+func TestMongoAdapter_Conformance(t *testing.T) {
+	setupLiveAdapter(t)
+
+	dbtest.RunConformance(t, func(t *testing.T) db.DBAdapter {
+		return setupLiveAdapter(t)
+	})
+}
+
+func TestMongoAdapter_CreateMany_WithoutTransactions(t *testing.T) {
+	adapter := setupLiveAdapter(t)
+	adapter.transactions = false
+	require.NoError(t, adapter.Migrate([]any{&Order{}}))
+	require.False(t, db.SupportsTransactions(adapter))
+
+	err := adapter.CreateMany([]any{&Order{ID: 1}, &Order{ID: 1}, &Order{ID: 2}})
+	var partial *db.PartialBatchError
+	require.ErrorAs(t, err, &partial)
+	require.Len(t, partial.Errors, 1)
+	require.Equal(t, 1, partial.Errors[0].Index)
+	require.ErrorIs(t, partial.Failed(1), gerrors.ErrConflict)
+	require.NoError(t, partial.Failed(2), "unordered inserts write the items after a failed one")
+
+	count, err := adapter.Count(&Order{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+}
@@ -0,0 +1,194 @@
+package mongodb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// IDStrategy generates the ID of a new document when Create is given an entity whose ID is zero.
+// collection is the entity's collection. The result is converted to the type of the ID field.
+type IDStrategy func(ctx context.Context, database *mongo.Database, collection string) (any, error)
+
+// Built-in ID strategies. An entity picks one with a tag on its ID field, e.g. `gompose:"id:ulid"`;
+// otherwise integer IDs use Increment, primitive.ObjectID IDs use ObjectID and string IDs use UUID.
+const (
+	Increment = "increment" // sequential integers from the counters collection
+	UUID      = "uuid"      // random UUIDv4
+	UUIDv7    = "uuidv7"    // time-ordered UUIDv7
+	ULID      = "ulid"      // time-ordered ULID
+	ObjectID  = "objectid"  // native ObjectID, or its hex form for string IDs
+	ClientID  = "none"      // IDs are always set by the caller
+)
+
+// countersCollection holds one {_id: <collection>, seq: <last id>} document per collection using Increment.
+const countersCollection = "counters"
+
+var (
+	strategiesMu sync.RWMutex
+	strategies   = map[string]IDStrategy{
+		Increment: nextSequence,
+		UUID: func(context.Context, *mongo.Database, string) (any, error) {
+			return uuid.NewString(), nil
+		},
+		UUIDv7: func(context.Context, *mongo.Database, string) (any, error) {
+			id, err := uuid.NewV7()
+			return id.String(), err
+		},
+		ULID: func(context.Context, *mongo.Database, string) (any, error) {
+			return newULID(time.Now())
+		},
+		ObjectID: func(context.Context, *mongo.Database, string) (any, error) {
+			return primitive.NewObjectID(), nil
+		},
+	}
+)
+
+// RegisterIDStrategy makes a custom strategy available to `gompose:"id:<name>"` tags and SetIDStrategy.
+func RegisterIDStrategy(name string, strategy IDStrategy) {
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+	strategies[name] = strategy
+}
+
+// SetIDStrategy selects the ID strategy of entity, overriding the tag of its ID field.
+// It is meant for entities whose definition cannot be tagged, such as auth.UserModel.
+func (m *MongoAdapter) SetIDStrategy(entity any, name string) *MongoAdapter {
+	if m.idStrategies == nil {
+		m.idStrategies = map[reflect.Type]string{}
+	}
+	m.idStrategies[getElemType(entity)] = name
+	return m
+}
+
+// assignID fills in the zero ID of a new entity using the entity's strategy. For Increment,
+// an ID chosen by the caller moves the counter past it so later generated IDs do not collide.
+func (m *MongoAdapter) assignID(entity any) error {
+	v := reflect.ValueOf(entity)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	v = v.Elem()
+	id := v.FieldByName("ID")
+	if !id.IsValid() || !id.CanSet() {
+		return nil
+	}
+
+	collection := collectionName(v.Type())
+	name := m.idStrategyName(v.Type())
+	if !id.IsZero() {
+		if name == Increment && (id.CanInt() || id.CanUint()) {
+			n, _ := strconv.ParseInt(fmt.Sprint(id.Interface()), 10, 64)
+			_, err := m.database.Collection(countersCollection).UpdateOne(m.ctx,
+				bson.M{"_id": collection}, bson.M{"$max": bson.M{"seq": n}}, options.Update().SetUpsert(true))
+			return translateError(err)
+		}
+		return nil
+	}
+	if name == ClientID {
+		return nil
+	}
+
+	strategiesMu.RLock()
+	strategy, ok := strategies[name]
+	strategiesMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown ID strategy %q for %s", name, v.Type().Name())
+	}
+
+	generated, err := strategy(m.ctx, m.database, collection)
+	if err != nil {
+		return translateError(err)
+	}
+	return setID(id, generated)
+}
+
+// idStrategyName returns the strategy of entity type t: SetIDStrategy, then the ID tag, then the ID type.
+func (m *MongoAdapter) idStrategyName(t reflect.Type) string {
+	if name, ok := m.idStrategies[t]; ok {
+		return name
+	}
+
+	field, ok := t.FieldByName("ID")
+	if !ok {
+		return ClientID
+	}
+	for _, setting := range strings.Split(field.Tag.Get("gompose"), ";") {
+		if key, value, _ := strings.Cut(setting, ":"); strings.TrimSpace(key) == "id" {
+			return strings.TrimSpace(value)
+		}
+	}
+
+	switch {
+	case field.Type == reflect.TypeOf(primitive.ObjectID{}):
+		return ObjectID
+	case field.Type.Kind() == reflect.String:
+		return UUID
+	case field.Type.Kind() >= reflect.Int && field.Type.Kind() <= reflect.Uint64:
+		return Increment
+	}
+	return ClientID
+}
+
+func nextSequence(ctx context.Context, database *mongo.Database, collection string) (any, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := database.Collection(countersCollection).
+		FindOneAndUpdate(ctx, bson.M{"_id": collection}, bson.M{"$inc": bson.M{"seq": int64(1)}}, opts).
+		Decode(&counter)
+	return counter.Seq, err
+}
+
+// setID stores a generated value in the ID field, converting between the integer, string and ObjectID forms.
+func setID(id reflect.Value, generated any) error {
+	value := reflect.ValueOf(generated)
+	switch {
+	case value.Type().AssignableTo(id.Type()):
+		id.Set(value)
+	case id.Kind() == reflect.String:
+		if oid, ok := generated.(primitive.ObjectID); ok {
+			id.SetString(oid.Hex())
+		} else {
+			id.SetString(fmt.Sprint(generated))
+		}
+	case value.CanInt() && (id.CanInt() || id.CanUint()):
+		id.Set(value.Convert(id.Type()))
+	default:
+		return fmt.Errorf("cannot store generated ID %v (%T) in a %s ID field", generated, generated, id.Type())
+	}
+	return nil
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID returns a ULID: 48 bits of milliseconds followed by 80 random bits, in Crockford base32.
+func newULID(now time.Time) (string, error) {
+	var raw [16]byte
+	binary.BigEndian.PutUint64(raw[:8], uint64(now.UnixMilli())<<16)
+	if _, err := rand.Read(raw[6:]); err != nil {
+		return "", err
+	}
+
+	hi, lo := binary.BigEndian.Uint64(raw[:8]), binary.BigEndian.Uint64(raw[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:]), nil
+}
@@ -0,0 +1,119 @@
+package mongodb
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	gerrors "github.com/Lumicrate/gompose/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ObjectIDEntity struct {
+	ID   primitive.ObjectID `bson:"id"`
+	Name string
+}
+
+type ULIDEntity struct {
+	ID   string `bson:"id" gompose:"id:ulid"`
+	Name string
+}
+
+type SmallIntEntity struct {
+	ID   uint32 `bson:"id"`
+	Name string
+}
+
+func TestIDStrategyName(t *testing.T) {
+	m := New("mongodb://localhost", "test")
+
+	require.Equal(t, Increment, m.idStrategyName(reflect.TypeOf(TestIntEntity{})))
+	require.Equal(t, UUID, m.idStrategyName(reflect.TypeOf(TestEntity{})))
+	require.Equal(t, ObjectID, m.idStrategyName(reflect.TypeOf(ObjectIDEntity{})))
+	require.Equal(t, ULID, m.idStrategyName(reflect.TypeOf(ULIDEntity{})))
+
+	m.SetIDStrategy(&TestEntity{}, UUIDv7)
+	require.Equal(t, UUIDv7, m.idStrategyName(reflect.TypeOf(TestEntity{})))
+}
+
+func TestAssignID_GeneratedStrings(t *testing.T) {
+	m := New("mongodb://localhost", "test")
+
+	entity := &TestEntity{}
+	require.NoError(t, m.assignID(entity))
+	require.Len(t, entity.ID, 36)
+
+	kept := &TestEntity{ID: "mine"}
+	require.NoError(t, m.assignID(kept))
+	require.Equal(t, "mine", kept.ID)
+
+	oid := &ObjectIDEntity{}
+	require.NoError(t, m.assignID(oid))
+	require.False(t, oid.ID.IsZero())
+
+	ulid := &ULIDEntity{}
+	require.NoError(t, m.assignID(ulid))
+	require.Regexp(t, regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`), ulid.ID)
+
+	m.SetIDStrategy(&TestEntity{}, ObjectID)
+	hex := &TestEntity{}
+	require.NoError(t, m.assignID(hex))
+	require.Len(t, hex.ID, 24)
+
+	m.SetIDStrategy(&TestEntity{}, "snowflake")
+	require.EqualError(t, m.assignID(&TestEntity{}), `unknown ID strategy "snowflake" for TestEntity`)
+}
+
+func TestSetID_ConvertsIntegers(t *testing.T) {
+	entity := &SmallIntEntity{}
+	require.NoError(t, setID(reflect.ValueOf(entity).Elem().FieldByName("ID"), int64(7)))
+	require.Equal(t, uint32(7), entity.ID)
+
+	require.Error(t, setID(reflect.ValueOf(entity).Elem().FieldByName("ID"), "abc"))
+}
+
+func TestNewULID_IsTimeOrdered(t *testing.T) {
+	earlier, err := newULID(time.UnixMilli(1_700_000_000_000))
+	require.NoError(t, err)
+	later, err := newULID(time.UnixMilli(1_700_000_000_001))
+	require.NoError(t, err)
+
+	require.Len(t, earlier, 26)
+	require.Less(t, earlier, later)
+	require.Equal(t, "01HF7YAT00", earlier[:10])
+}
+
+func TestGetTypedId_Types(t *testing.T) {
+	id, err := getTypedId("7", reflect.TypeOf(SmallIntEntity{}))
+	require.NoError(t, err)
+	require.Equal(t, uint32(7), id)
+
+	oid := primitive.NewObjectID()
+	id, err = getTypedId(oid.Hex(), reflect.TypeOf(ObjectIDEntity{}))
+	require.NoError(t, err)
+	require.Equal(t, oid, id)
+
+	_, err = getTypedId("nope", reflect.TypeOf(ObjectIDEntity{}))
+	require.ErrorIs(t, err, gerrors.ErrBadRequest)
+
+	hex, err := getEntityID(&ObjectIDEntity{ID: oid})
+	require.NoError(t, err)
+	require.Equal(t, oid.Hex(), hex)
+}
+
+func TestMongoAdapter_IncrementIDs(t *testing.T) {
+	adapter := setupLiveAdapter(t)
+
+	first, second := &TestIntEntity{Name: "a"}, &TestIntEntity{Name: "b"}
+	require.NoError(t, adapter.Create(first))
+	require.NoError(t, adapter.Create(second))
+	require.Equal(t, 1, first.ID)
+	require.Equal(t, 2, second.ID)
+
+	require.NoError(t, adapter.Create(&TestIntEntity{ID: 10, Name: "explicit"}))
+	next := &TestIntEntity{Name: "c"}
+	require.NoError(t, adapter.Create(next))
+	require.Equal(t, 11, next.ID)
+}
@@ -0,0 +1,286 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// indexSpec is an index declared by the struct tags of an entity:
+//
+//	Email     string    `gompose:"unique"`             // unique index on email
+//	Tenant    string    `gompose:"index:tenant_slug"`  // fields sharing a name form a compound index,
+//	Slug      string    `gompose:"unique:tenant_slug"` // unique if any of its fields says so
+//	ExpiresAt time.Time `gompose:"ttl:24h"`            // documents expire 24h after ExpiresAt
+//	Title     string    `gompose:"text"`               // all text fields share one text index
+//
+// GORM's `index`, `uniqueIndex` and `unique` settings are honoured as well, so entities
+// shared with the SQL adapters need no extra tags. Every entity with an ID field also gets
+// a unique index on id, which all lookups by ID use.
+type indexSpec struct {
+	name   string
+	keys   bson.D
+	unique bool
+	ttl    *int32
+}
+
+// IndexDrift is a difference between the indexes declared by an entity and the ones in its collection.
+type IndexDrift struct {
+	Collection string
+	Index      string
+	Problem    string
+}
+
+func (d IndexDrift) String() string {
+	return fmt.Sprintf("%s.%s: %s", d.Collection, d.Index, d.Problem)
+}
+
+const (
+	driftMissing    = "missing"
+	driftChanged    = "differs from the declared index"
+	driftUndeclared = "not declared by the entity"
+)
+
+// IndexDrift compares the declared indexes of entities with the existing ones without changing anything.
+// Migrate creates missing indexes but never drops or rebuilds one, so changed and undeclared
+// indexes need a migration.
+func (m *MongoAdapter) IndexDrift(entities []any) ([]IndexDrift, error) {
+	var drifts []IndexDrift
+	for _, entity := range entities {
+		collection := m.collectionFor(entity)
+		specs, err := indexSpecs(getElemType(entity))
+		if err != nil {
+			return nil, err
+		}
+		existing, err := listIndexes(m.ctx, collection)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		drifts = append(drifts, indexDrift(collection.Name(), specs, existing)...)
+	}
+	return drifts, nil
+}
+
+// ensureIndexes creates the declared indexes that do not exist yet and returns the remaining drift.
+func (m *MongoAdapter) ensureIndexes(entity any) ([]IndexDrift, error) {
+	collection := m.collectionFor(entity)
+	specs, err := indexSpecs(getElemType(entity))
+	if err != nil {
+		return nil, err
+	}
+	existing, err := listIndexes(m.ctx, collection)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	var models []mongo.IndexModel
+	var drifts []IndexDrift
+	for _, drift := range indexDrift(collection.Name(), specs, existing) {
+		if drift.Problem != driftMissing {
+			drifts = append(drifts, drift)
+			continue
+		}
+		for _, spec := range specs {
+			if spec.name == drift.Index {
+				models = append(models, spec.model())
+			}
+		}
+	}
+
+	if len(models) > 0 {
+		if _, err := collection.Indexes().CreateMany(m.ctx, models); err != nil {
+			return nil, translateError(err)
+		}
+	}
+	return drifts, nil
+}
+
+func (s indexSpec) model() mongo.IndexModel {
+	opts := options.Index().SetName(s.name)
+	if s.unique {
+		opts.SetUnique(true)
+	}
+	if s.ttl != nil {
+		opts.SetExpireAfterSeconds(*s.ttl)
+	}
+	return mongo.IndexModel{Keys: s.keys, Options: opts}
+}
+
+// indexSpecs collects the indexes declared by the fields of elemType, in declaration order.
+func indexSpecs(elemType reflect.Type) ([]indexSpec, error) {
+	var specs []indexSpec
+	byName := map[string]int{}
+	add := func(name, key string, value any, unique bool) {
+		if i, ok := byName[name]; ok {
+			specs[i].keys = append(specs[i].keys, bson.E{Key: key, Value: value})
+			specs[i].unique = specs[i].unique || unique
+			return
+		}
+		byName[name] = len(specs)
+		specs = append(specs, indexSpec{name: name, keys: bson.D{{Key: key, Value: value}}, unique: unique})
+	}
+
+	if _, ok := elemType.FieldByName("ID"); ok {
+		add("id_1", "id", 1, true)
+	}
+
+	for _, f := range reflect.VisibleFields(elemType) {
+		if f.Anonymous || !f.IsExported() {
+			continue
+		}
+		key, _, err := fieldByName(elemType, f.Name)
+		if err != nil {
+			continue
+		}
+
+		for _, setting := range indexSettings(f) {
+			name := setting.name
+			switch setting.kind {
+			case "index", "unique":
+				if name == "" {
+					name = key + "_1"
+				}
+				add(name, key, 1, setting.kind == "unique")
+			case "text":
+				add("text", key, "text", false)
+			case "ttl":
+				expireAfter, err := time.ParseDuration(setting.name)
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: invalid ttl %q", elemType.Name(), f.Name, setting.name)
+				}
+				seconds := int32(expireAfter / time.Second)
+				specs = append(specs, indexSpec{name: key + "_ttl", keys: bson.D{{Key: key, Value: 1}}, ttl: &seconds})
+			}
+		}
+	}
+	return specs, nil
+}
+
+type indexSetting struct {
+	kind string // index, unique, text or ttl
+	name string // index name, or the duration for ttl
+}
+
+// indexSettings reads the index settings of a field from its gompose tag, or from its gorm tag.
+func indexSettings(f reflect.StructField) []indexSetting {
+	var settings []indexSetting
+	for _, part := range strings.Split(f.Tag.Get("gompose"), ";") {
+		key, value, _ := strings.Cut(part, ":")
+		switch kind := strings.ToLower(strings.TrimSpace(key)); kind {
+		case "index", "unique", "text", "ttl":
+			settings = append(settings, indexSetting{kind: kind, name: strings.TrimSpace(value)})
+		}
+	}
+	if len(settings) > 0 {
+		return settings
+	}
+
+	for _, part := range strings.Split(f.Tag.Get("gorm"), ";") {
+		key, value, _ := strings.Cut(part, ":")
+		name, options, _ := strings.Cut(strings.TrimSpace(value), ",")
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "unique", "uniqueindex":
+			settings = append(settings, indexSetting{kind: "unique", name: name})
+		case "index":
+			kind := "index"
+			if strings.Contains(strings.ToLower(options), "unique") {
+				kind = "unique"
+			}
+			settings = append(settings, indexSetting{kind: kind, name: name})
+		}
+	}
+	return settings
+}
+
+type existingIndex struct {
+	Name               string `bson:"name"`
+	Key                bson.D `bson:"key"`
+	Unique             bool   `bson:"unique"`
+	ExpireAfterSeconds *int32 `bson:"expireAfterSeconds"`
+	Weights            bson.M `bson:"weights"`
+}
+
+func listIndexes(ctx context.Context, collection *mongo.Collection) (map[string]existingIndex, error) {
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var indexes []existingIndex
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]existingIndex, len(indexes))
+	for _, index := range indexes {
+		byName[index.Name] = index
+	}
+	return byName, nil
+}
+
+func indexDrift(collection string, specs []indexSpec, existing map[string]existingIndex) []IndexDrift {
+	var drifts []IndexDrift
+	declared := map[string]bool{"_id_": true}
+	for _, spec := range specs {
+		declared[spec.name] = true
+		index, ok := existing[spec.name]
+		switch {
+		case !ok:
+			drifts = append(drifts, IndexDrift{Collection: collection, Index: spec.name, Problem: driftMissing})
+		case !spec.matches(index):
+			drifts = append(drifts, IndexDrift{Collection: collection, Index: spec.name, Problem: driftChanged})
+		}
+	}
+
+	var undeclared []string
+	for name := range existing {
+		if !declared[name] {
+			undeclared = append(undeclared, name)
+		}
+	}
+	sort.Strings(undeclared)
+	for _, name := range undeclared {
+		drifts = append(drifts, IndexDrift{Collection: collection, Index: name, Problem: driftUndeclared})
+	}
+	return drifts
+}
+
+func (s indexSpec) matches(index existingIndex) bool {
+	if s.unique != index.Unique || (s.ttl == nil) != (index.ExpireAfterSeconds == nil) {
+		return false
+	}
+	if s.ttl != nil && *s.ttl != *index.ExpireAfterSeconds {
+		return false
+	}
+
+	// Text indexes are stored as {_fts: "text", _ftsx: 1} with the fields in weights.
+	if len(s.keys) > 0 && s.keys[0].Value == "text" {
+		if len(index.Weights) != len(s.keys) {
+			return false
+		}
+		for _, key := range s.keys {
+			if _, ok := index.Weights[key.Key]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	if len(s.keys) != len(index.Key) {
+		return false
+	}
+	for i, key := range s.keys {
+		if key.Key != index.Key[i].Key || fmt.Sprint(key.Value) != fmt.Sprint(index.Key[i].Value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+package mongodb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Lumicrate/gompose/db"
+	gerrors "github.com/Lumicrate/gompose/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type IndexedEntity struct {
+	ID        int       `bson:"id"`
+	Email     string    `bson:"email" gompose:"unique"`
+	Tenant    string    `bson:"tenant" gompose:"index:tenant_slug"`
+	Slug      string    `bson:"slug" gompose:"unique:tenant_slug"`
+	ExpiresAt time.Time `bson:"expires_at" gompose:"ttl:1h"`
+	Title     string    `bson:"title" gompose:"text"`
+	Body      string    `bson:"body" gompose:"text"`
+	Code      string    `bson:"code" gorm:"uniqueIndex"`
+	Country   string    `bson:"country" gorm:"index:idx_country,unique"`
+	Plain     string    `bson:"plain"`
+}
+
+func TestIndexSpecs(t *testing.T) {
+	specs, err := indexSpecs(reflect.TypeOf(IndexedEntity{}))
+	require.NoError(t, err)
+
+	hour := int32(3600)
+	require.Equal(t, []indexSpec{
+		{name: "id_1", keys: bson.D{{Key: "id", Value: 1}}, unique: true},
+		{name: "email_1", keys: bson.D{{Key: "email", Value: 1}}, unique: true},
+		{name: "tenant_slug", keys: bson.D{{Key: "tenant", Value: 1}, {Key: "slug", Value: 1}}, unique: true},
+		{name: "expires_at_ttl", keys: bson.D{{Key: "expires_at", Value: 1}}, ttl: &hour},
+		{name: "text", keys: bson.D{{Key: "title", Value: "text"}, {Key: "body", Value: "text"}}},
+		{name: "code_1", keys: bson.D{{Key: "code", Value: 1}}, unique: true},
+		{name: "idx_country", keys: bson.D{{Key: "country", Value: 1}}, unique: true},
+	}, specs)
+}
+
+func TestIndexSpecs_InvalidTTL(t *testing.T) {
+	type Broken struct {
+		At time.Time `gompose:"ttl:soon"`
+	}
+	_, err := indexSpecs(reflect.TypeOf(Broken{}))
+	require.EqualError(t, err, `Broken.At: invalid ttl "soon"`)
+}
+
+func TestIndexDrift(t *testing.T) {
+	specs, err := indexSpecs(reflect.TypeOf(IndexedEntity{}))
+	require.NoError(t, err)
+
+	hour := int32(3600)
+	existing := map[string]existingIndex{
+		"_id_":           {Name: "_id_", Key: bson.D{{Key: "_id", Value: int32(1)}}},
+		"id_1":           {Name: "id_1", Key: bson.D{{Key: "id", Value: int32(1)}}, Unique: true},
+		"email_1":        {Name: "email_1", Key: bson.D{{Key: "email", Value: int32(1)}}},
+		"tenant_slug":    {Name: "tenant_slug", Key: bson.D{{Key: "tenant", Value: 1.0}, {Key: "slug", Value: 1.0}}, Unique: true},
+		"expires_at_ttl": {Name: "expires_at_ttl", Key: bson.D{{Key: "expires_at", Value: int32(1)}}, ExpireAfterSeconds: &hour},
+		"text": {Name: "text", Key: bson.D{{Key: "_fts", Value: "text"}, {Key: "_ftsx", Value: int32(1)}},
+			Weights: bson.M{"title": int32(1), "body": int32(1)}},
+		"legacy_1": {Name: "legacy_1", Key: bson.D{{Key: "legacy", Value: int32(1)}}},
+	}
+
+	require.Equal(t, []IndexDrift{
+		{Collection: "things", Index: "email_1", Problem: driftChanged},
+		{Collection: "things", Index: "code_1", Problem: driftMissing},
+		{Collection: "things", Index: "idx_country", Problem: driftMissing},
+		{Collection: "things", Index: "legacy_1", Problem: driftUndeclared},
+	}, indexDrift("things", specs, existing))
+}
+
+func TestMongoAdapter_MigrateCreatesIndexes(t *testing.T) {
+	adapter := setupLiveAdapter(t)
+
+	require.NoError(t, adapter.Migrate([]any{&IndexedEntity{}}))
+	drifts, err := adapter.IndexDrift([]any{&IndexedEntity{}})
+	require.NoError(t, err)
+	require.Empty(t, drifts)
+
+	require.NoError(t, adapter.Create(&IndexedEntity{ID: 1, Email: "a@example.com", Code: "a", Country: "a", Slug: "a"}))
+	err = adapter.Create(&IndexedEntity{ID: 2, Email: "a@example.com", Code: "b", Country: "b", Slug: "b"})
+	require.ErrorIs(t, err, gerrors.ErrConflict)
+}
+
+func TestMongoAdapter_EnsureIndexes(t *testing.T) {
+	var adapter db.DBAdapter = setupLiveAdapter(t)
+	ensurer, ok := adapter.(db.IndexEnsurer)
+	require.True(t, ok, "App.Run creates MongoDB indexes through db.IndexEnsurer")
+
+	require.NoError(t, ensurer.EnsureIndexes([]any{&IndexedEntity{}}))
+	drifts, err := adapter.(*MongoAdapter).IndexDrift([]any{&IndexedEntity{}})
+	require.NoError(t, err)
+	require.Empty(t, drifts)
+}
@@ -0,0 +1,55 @@
+package mongodb
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// Options tunes the client of a MongoAdapter. Zero values keep the driver defaults, and
+// settings given in the URI apply when the matching option is zero.
+type Options struct {
+	MaxPoolSize            uint64        `yaml:"max_pool_size"`
+	MinPoolSize            uint64        `yaml:"min_pool_size"`
+	MaxConnIdleTime        time.Duration `yaml:"max_conn_idle_time"`
+	ConnectTimeout         time.Duration `yaml:"connect_timeout"`
+	ServerSelectionTimeout time.Duration `yaml:"server_selection_timeout"`
+
+	// ReadPreference is one of primary, primaryPreferred, secondary, secondaryPreferred
+	// or nearest.
+	ReadPreference string `yaml:"read_preference"`
+}
+
+// clientOptions returns the client options connecting to uri.
+func clientOptions(uri string, o Options) (*options.ClientOptions, error) {
+	opts := options.Client().ApplyURI(uri)
+	if o.MaxPoolSize > 0 {
+		opts.SetMaxPoolSize(o.MaxPoolSize)
+	}
+	if o.MinPoolSize > 0 {
+		opts.SetMinPoolSize(o.MinPoolSize)
+	}
+	if o.MaxConnIdleTime > 0 {
+		opts.SetMaxConnIdleTime(o.MaxConnIdleTime)
+	}
+	if o.ConnectTimeout > 0 {
+		opts.SetConnectTimeout(o.ConnectTimeout)
+	}
+	if o.ServerSelectionTimeout > 0 {
+		opts.SetServerSelectionTimeout(o.ServerSelectionTimeout)
+	}
+	if o.ReadPreference != "" {
+		mode, err := readpref.ModeFromString(o.ReadPreference)
+		if err != nil {
+			return nil, fmt.Errorf("invalid read preference %q: %w", o.ReadPreference, err)
+		}
+		pref, err := readpref.New(mode)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetReadPreference(pref)
+	}
+	return opts, nil
+}
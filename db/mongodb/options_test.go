@@ -0,0 +1,36 @@
+package mongodb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+func TestClientOptions(t *testing.T) {
+	opts, err := clientOptions("mongodb://localhost:27017/?maxPoolSize=10", Options{
+		MinPoolSize:            2,
+		ServerSelectionTimeout: 5 * time.Second,
+		ReadPreference:         "secondaryPreferred",
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), *opts.MaxPoolSize, "URI settings apply when the option is zero")
+	require.Equal(t, uint64(2), *opts.MinPoolSize)
+	require.Equal(t, 5*time.Second, *opts.ServerSelectionTimeout)
+	require.Equal(t, readpref.SecondaryPreferredMode, opts.ReadPreference.Mode())
+
+	opts, err = clientOptions("mongodb://localhost:27017", Options{MaxPoolSize: 50})
+	require.NoError(t, err)
+	require.Equal(t, uint64(50), *opts.MaxPoolSize)
+	require.Nil(t, opts.ReadPreference)
+
+	_, err = clientOptions("mongodb://localhost:27017", Options{ReadPreference: "anywhere"})
+	require.ErrorContains(t, err, `invalid read preference "anywhere"`)
+}
+
+func TestMongoAdapter_PingAndClose(t *testing.T) {
+	adapter := New("mongodb://localhost:27017", "gompose_test")
+	require.Error(t, adapter.Ping(), "Ping before Init")
+	require.NoError(t, adapter.Close())
+}
@@ -0,0 +1,65 @@
+package mongodb
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/Lumicrate/gompose/db"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// textScoreSort orders the results of a $text search by relevance.
+var textScoreSort = bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}}
+
+// searchFilter builds the condition of a search. Entities with a text index (`gompose:"text"`)
+// are searched through it, which matches whole words of the indexed fields and reports
+// ranked as true. Other entities fall back to a case-insensitive regex per word and field.
+// The condition is nil when the query holds no words.
+func searchFilter(elemType reflect.Type, query string, fields []string) (condition bson.M, ranked bool, err error) {
+	terms := db.SearchTerms(query)
+	if len(terms) == 0 {
+		return nil, false, nil
+	}
+	if len(fields) == 0 {
+		return nil, false, fmt.Errorf("search needs at least one field")
+	}
+
+	keys := make([]string, len(fields))
+	for i, name := range fields {
+		if keys[i], _, err = fieldByName(elemType, name); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if hasTextIndex(elemType) {
+		// Quoted words must all match; unquoted ones would be OR'ed.
+		phrases := make([]string, len(terms))
+		for i, term := range terms {
+			phrases[i] = `"` + term + `"`
+		}
+		return bson.M{"$text": bson.M{"$search": strings.Join(phrases, " ")}}, true, nil
+	}
+
+	conditions := make([]bson.M, len(terms))
+	for i, term := range terms {
+		matches := make([]bson.M, len(keys))
+		for j, key := range keys {
+			matches[j] = bson.M{key: bson.M{"$regex": regexp.QuoteMeta(term), "$options": "i"}}
+		}
+		conditions[i] = bson.M{"$or": matches}
+	}
+	return bson.M{"$and": conditions}, false, nil
+}
+
+func hasTextIndex(elemType reflect.Type) bool {
+	for _, f := range reflect.VisibleFields(elemType) {
+		for _, setting := range indexSettings(f) {
+			if setting.kind == "text" {
+				return true
+			}
+		}
+	}
+	return false
+}
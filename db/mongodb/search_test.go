@@ -0,0 +1,46 @@
+package mongodb
+
+import (
+	"reflect"
+	"testing"
+
+	gerrors "github.com/Lumicrate/gompose/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSearchFilter_TextIndex(t *testing.T) {
+	condition, ranked, err := searchFilter(reflect.TypeOf(IndexedEntity{}), "Go, modules!", []string{"Title", "Body"})
+	require.NoError(t, err)
+	require.True(t, ranked)
+	require.Equal(t, bson.M{"$text": bson.M{"$search": `"go" "modules"`}}, condition)
+
+	// The text index decides which fields are searched.
+	condition, _, err = searchFilter(reflect.TypeOf(IndexedEntity{}), "go", []string{"Plain"})
+	require.NoError(t, err)
+	require.Equal(t, bson.M{"$text": bson.M{"$search": `"go"`}}, condition)
+}
+
+func TestSearchFilter_RegexFallback(t *testing.T) {
+	type Note struct {
+		ID    int    `bson:"id"`
+		Title string `bson:"title"`
+		Body  string `bson:"body"`
+	}
+	condition, ranked, err := searchFilter(reflect.TypeOf(Note{}), "go vet", []string{"Title", "Body"})
+	require.NoError(t, err)
+	require.False(t, ranked)
+	require.Equal(t, bson.M{"$and": []bson.M{
+		{"$or": []bson.M{{"title": bson.M{"$regex": "go", "$options": "i"}}, {"body": bson.M{"$regex": "go", "$options": "i"}}}},
+		{"$or": []bson.M{{"title": bson.M{"$regex": "vet", "$options": "i"}}, {"body": bson.M{"$regex": "vet", "$options": "i"}}}},
+	}}, condition)
+}
+
+func TestSearchFilter_EmptyAndUnknown(t *testing.T) {
+	condition, _, err := searchFilter(reflect.TypeOf(IndexedEntity{}), " ?! ", []string{"Title"})
+	require.NoError(t, err)
+	require.Nil(t, condition)
+
+	_, _, err = searchFilter(reflect.TypeOf(IndexedEntity{}), "go", []string{"Missing"})
+	require.ErrorIs(t, err, gerrors.ErrBadRequest)
+}
@@ -0,0 +1,61 @@
+package mysql
+
+import (
+	"errors"
+	"github.com/Lumicrate/gompose/db/gormadapter"
+	gerrors "github.com/Lumicrate/gompose/errors"
+	gomysql "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+)
+
+// MySQL error numbers, see https://dev.mysql.com/doc/mysql-errors/8.0/en/server-error-reference.html
+const (
+	errDuplicateEntry     = 1062
+	errRowIsReferenced    = 1451
+	errNoReferencedRow    = 1452
+	errRowIsReferencedOld = 1217
+	errNoReferencedRowOld = 1216
+)
+
+// MySQLAdapter stores entities in MySQL or MariaDB. The DSN uses the go-sql-driver format,
+// e.g. "user:password@tcp(localhost:3306)/mydb?charset=utf8mb4&parseTime=True&loc=Local".
+type MySQLAdapter struct {
+	*gormadapter.Adapter
+}
+
+func New(dsn string) *MySQLAdapter {
+	return NewWithOptions(dsn, gormadapter.Options{})
+}
+
+// NewWithOptions is New with a tuned connection pool and logger.
+func NewWithOptions(dsn string, opts gormadapter.Options) *MySQLAdapter {
+	return &MySQLAdapter{gormadapter.NewWithOptions(mysql.Open(withFoundRows(dsn)), translateError, opts)}
+}
+
+// withFoundRows makes UPDATE report matched rather than changed rows, like Postgres and
+// SQLite do, so an update that changes nothing is not mistaken for a missing row.
+func withFoundRows(dsn string) string {
+	cfg, err := gomysql.ParseDSN(dsn)
+	if err != nil {
+		// Leave it to Init to report the malformed DSN.
+		return dsn
+	}
+	cfg.ClientFoundRows = true
+	return cfg.FormatDSN()
+}
+
+// translateError maps MySQL errors GORM does not translate onto gompose errors.
+func translateError(err error) error {
+	var myErr *gomysql.MySQLError
+	if !errors.As(err, &myErr) {
+		return err
+	}
+
+	switch myErr.Number {
+	case errDuplicateEntry:
+		return gerrors.Conflict("entity already exists").Wrap(err)
+	case errRowIsReferenced, errNoReferencedRow, errRowIsReferencedOld, errNoReferencedRowOld:
+		return gerrors.Conflict("entity is referenced by or references a missing entity").Wrap(err)
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+package mysql
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/Lumicrate/gompose/db"
+	"github.com/Lumicrate/gompose/db/dbtest"
+	gerrors "github.com/Lumicrate/gompose/errors"
+	gomysql "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslateError(t *testing.T) {
+	duplicate := fmt.Errorf("insert: %w", &gomysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a' for key 'sku'"})
+	require.ErrorIs(t, translateError(duplicate), gerrors.ErrConflict)
+
+	foreignKey := &gomysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row"}
+	require.ErrorIs(t, translateError(foreignKey), gerrors.ErrConflict)
+
+	other := &gomysql.MySQLError{Number: 1045, Message: "Access denied"}
+	require.Equal(t, other, translateError(other))
+}
+
+func TestWithFoundRows(t *testing.T) {
+	dsn := withFoundRows("user:secret@tcp(localhost:3306)/shop?parseTime=true")
+
+	cfg, err := gomysql.ParseDSN(dsn)
+	require.NoError(t, err)
+	require.True(t, cfg.ClientFoundRows)
+	require.True(t, cfg.ParseTime)
+	require.Equal(t, "shop", cfg.DBName)
+
+	require.Equal(t, "not a dsn", withFoundRows("not a dsn"))
+}
+
+type Product struct {
+	ID    int    `gorm:"primaryKey;autoIncrement"`
+	SKU   string `gorm:"size:64;uniqueIndex"`
+	Price float64
+}
+
+// TestMySQLAdapter_Integration runs against a real server, e.g.
+//
+//	docker run -d -p 3306:3306 -e MYSQL_ROOT_PASSWORD=secret -e MYSQL_DATABASE=gompose mysql:8
+//	GOMPOSE_MYSQL_DSN="root:secret@tcp(localhost:3306)/gompose?parseTime=true" go test ./db/mysql
+func TestMySQLAdapter_Integration(t *testing.T) {
+	dsn := os.Getenv("GOMPOSE_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("GOMPOSE_MYSQL_DSN not set")
+	}
+
+	adapter := New(dsn)
+	require.NoError(t, adapter.Init())
+	require.NoError(t, adapter.DB().Migrator().DropTable(&Product{}))
+	require.NoError(t, adapter.Migrate([]any{&Product{}}))
+
+	product := &Product{SKU: "apple", Price: 1}
+	require.NoError(t, adapter.Create(product))
+	require.ErrorIs(t, adapter.Create(&Product{SKU: "apple"}), gerrors.ErrConflict)
+
+	// Saving identical values must not be reported as a missing row
+	require.NoError(t, adapter.Update(product))
+
+	result, err := adapter.FindAll(&Product{}, []db.Filter{{Field: "Price", Operator: db.OpGte, Value: "1"}}, db.Pagination{Limit: 10}, nil)
+	require.NoError(t, err)
+	require.Len(t, result.([]Product), 1)
+}
+
+func TestMySQLAdapter_Conformance(t *testing.T) {
+	dsn := os.Getenv("GOMPOSE_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("GOMPOSE_MYSQL_DSN not set")
+	}
+
+	dbtest.RunConformance(t, func(t *testing.T) db.DBAdapter {
+		adapter := New(dsn)
+		require.NoError(t, adapter.Init())
+		require.NoError(t, adapter.DB().Migrator().DropTable(&dbtest.Widget{}, &dbtest.Tag{}))
+		return adapter
+	})
+}
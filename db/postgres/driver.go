@@ -1,90 +1,93 @@
 package postgres
 
 import (
+	"errors"
 	"fmt"
-	"github.com/Lumicrate/gompose/db"
+	"github.com/Lumicrate/gompose/db/gormadapter"
+	gerrors "github.com/Lumicrate/gompose/errors"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
-	"reflect"
+	"net/url"
+	"strings"
+	"time"
 )
 
 type PostgresAdapter struct {
-	dsn string
-	db  *gorm.DB
+	*gormadapter.Adapter
 }
 
-func New(dsn string) *PostgresAdapter {
-	return &PostgresAdapter{dsn: dsn}
-}
+// Options tunes the connection pool, logging and server settings of the adapter.
+type Options struct {
+	gormadapter.Options `yaml:",inline"`
 
-func (p *PostgresAdapter) Init() error {
-	var err error
-	p.db, err = gorm.Open(postgres.Open(p.dsn), &gorm.Config{})
-	return err
-}
+	// StatementTimeout makes the server abort statements that run longer.
+	StatementTimeout time.Duration `yaml:"statement_timeout"`
 
-func (p *PostgresAdapter) Migrate(entities []any) error {
-	for _, entity := range entities {
-		if err := p.db.AutoMigrate(entity); err != nil {
-			return fmt.Errorf("migration failed: %w", err)
-		}
-	}
-	return nil
+	Replicas ReplicaOptions `yaml:"replicas"`
 }
 
-func (p *PostgresAdapter) Create(entity any) error {
-	return p.db.Create(entity).Error
-}
+// ReplicaOptions lists read replicas serving FindAll, FindByID, Count and Aggregate; see
+// gormadapter.SetReplicas for the routing.
+type ReplicaOptions struct {
+	DSNs []string `yaml:"dsns"`
 
-func (p *PostgresAdapter) Update(entity any) error {
-	return p.db.Save(entity).Error
+	// Policy is round_robin (the default) or random.
+	Policy              string        `yaml:"policy"`
+	HealthCheckInterval time.Duration `yaml:"health_check_interval"`
 }
 
-func (p *PostgresAdapter) Delete(id string, entity any) error {
-	return p.db.Delete(entity, "id = ?", id).Error
+func New(dsn string) *PostgresAdapter {
+	return NewWithOptions(dsn, Options{})
 }
 
-func (p *PostgresAdapter) FindAll(entity any, filters map[string]any, pagination db.Pagination, sort []db.Sort) (any, error) {
-	entityType := reflect.TypeOf(entity)
-	if entityType.Kind() == reflect.Ptr {
-		entityType = entityType.Elem()
-	}
-
-	sliceType := reflect.SliceOf(entityType)
-
-	resultValue := reflect.New(sliceType) // *([]Entity)
-
-	tx := p.db.Model(entity)
-
-	for key, val := range filters {
-		tx = tx.Where(fmt.Sprintf("%s = ?", key), val)
-	}
-
-	for _, s := range sort {
-		tx = tx.Order(fmt.Sprintf("%s %s", s.Field, s.Direction))
-	}
-
-	if pagination.Limit > 0 {
-		tx = tx.Limit(pagination.Limit)
+func NewWithOptions(dsn string, opts Options) *PostgresAdapter {
+	adapter := gormadapter.NewWithOptions(postgres.Open(withOptions(dsn, opts)), translateError, opts.Options)
+	if len(opts.Replicas.DSNs) > 0 {
+		replicas := gormadapter.Replicas{
+			Policy:              gormadapter.ReplicaPolicy(opts.Replicas.Policy),
+			HealthCheckInterval: opts.Replicas.HealthCheckInterval,
+		}
+		for _, replica := range opts.Replicas.DSNs {
+			replicas.Dialectors = append(replicas.Dialectors, postgres.Open(withOptions(replica, opts)))
+		}
+		adapter.SetReplicas(replicas)
 	}
+	return &PostgresAdapter{adapter}
+}
 
-	if pagination.Offset > 0 {
-		tx = tx.Offset(pagination.Offset)
+// withOptions adds the server settings of the options to dsn.
+func withOptions(dsn string, opts Options) string {
+	if opts.StatementTimeout > 0 {
+		dsn = withRuntimeParam(dsn, "statement_timeout", fmt.Sprint(opts.StatementTimeout.Milliseconds()))
 	}
+	return dsn
+}
 
-	if err := tx.Find(resultValue.Interface()).Error; err != nil {
-		return nil, err
+// withRuntimeParam adds a server setting to a URL or keyword/value DSN; pgx sends the
+// settings it does not know itself to the server when connecting.
+func withRuntimeParam(dsn, key, value string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			// Leave it to Init to report the malformed DSN.
+			return dsn
+		}
+		query := u.Query()
+		query.Set(key, value)
+		u.RawQuery = query.Encode()
+		return u.String()
 	}
-
-	result := resultValue.Elem().Interface()
-
-	return result, nil
+	return strings.TrimSpace(dsn + " " + key + "=" + value)
 }
 
-func (p *PostgresAdapter) FindByID(id string, entity any) (any, error) {
-	err := p.db.First(entity, "id = ?", id).Error
-	if err != nil {
-		return nil, err
+// translateError maps Postgres errors GORM does not translate onto gompose errors.
+func translateError(err error) error {
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.As(err, &pgErr) && pgErr.Code == "23505": // unique_violation
+		return gerrors.Conflict("entity already exists").Wrap(err)
+	case errors.As(err, &pgErr) && pgErr.Code == "23503": // foreign_key_violation
+		return gerrors.Conflict("entity is referenced by or references a missing entity").Wrap(err)
 	}
-	return entity, nil
+	return err
 }
@@ -1,11 +1,19 @@
 package postgres
 
 import (
+	"context"
+	"errors"
 	"github.com/Lumicrate/gompose/db"
+	"github.com/Lumicrate/gompose/db/dbtest"
+	"github.com/Lumicrate/gompose/db/gormadapter"
+	gerrors "github.com/Lumicrate/gompose/errors"
 	"github.com/glebarez/sqlite"
 	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"reflect"
 	"testing"
+	"time"
 )
 
 // Test Entity
@@ -19,10 +27,10 @@ type TestEntity struct {
 
 // setupTestAdapter creates an in-memory SQLite database that mimics Postgres behavior
 func setupTestAdapter(t *testing.T) *PostgresAdapter {
-	dbConn, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	dbConn, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{TranslateError: true})
 	require.NoError(t, err)
 
-	adapter := &PostgresAdapter{db: dbConn}
+	adapter := &PostgresAdapter{gormadapter.FromDB(dbConn, translateError)}
 	err = adapter.Migrate([]any{&TestEntity{}})
 	require.NoError(t, err)
 
@@ -38,6 +46,40 @@ func TestPostgresAdapter_Init(t *testing.T) {
 	require.Error(t, err, "Init should fail with invalid DSN")
 }
 
+func TestPostgresAdapter_Options(t *testing.T) {
+	adapter := &PostgresAdapter{gormadapter.NewWithOptions(sqlite.Open(":memory:"), translateError, gormadapter.Options{
+		MaxOpenConns:    3,
+		ConnMaxLifetime: time.Minute,
+		LogLevel:        "silent",
+	})}
+	require.NoError(t, adapter.Init())
+
+	sqlDB, err := adapter.DB().DB()
+	require.NoError(t, err)
+	require.Equal(t, 3, sqlDB.Stats().MaxOpenConnections)
+
+	bad := NewWithOptions("host=localhost", Options{Options: gormadapter.Options{LogLevel: "loud"}})
+	require.ErrorContains(t, bad.Init(), `unknown log level "loud"`)
+}
+
+func TestPostgresAdapter_PingAndClose(t *testing.T) {
+	require.Error(t, New("host=localhost").Ping(), "Ping before Init")
+
+	adapter := setupTestAdapter(t)
+	require.NoError(t, adapter.Ping())
+	require.NoError(t, adapter.Close())
+	require.Error(t, adapter.Ping())
+}
+
+func TestWithRuntimeParam(t *testing.T) {
+	require.Equal(t,
+		"host=localhost dbname=app statement_timeout=30000",
+		withRuntimeParam("host=localhost dbname=app", "statement_timeout", "30000"))
+	require.Equal(t,
+		"postgres://user@localhost/app?sslmode=disable&statement_timeout=30000",
+		withRuntimeParam("postgres://user@localhost/app?sslmode=disable", "statement_timeout", "30000"))
+}
+
 func TestPostgresAdapter_Migrate(t *testing.T) {
 	adapter := setupTestAdapter(t)
 	err := adapter.Migrate([]any{&TestEntity{}})
@@ -95,7 +137,7 @@ func TestPostgresAdapter_FindAll(t *testing.T) {
 	require.NoError(t, adapter.Create(&TestEntity{ID: "2", Name: "Bob"}))
 	require.NoError(t, adapter.Create(&TestEntity{ID: "3", Name: "Charlie"}))
 
-	filters := map[string]any{"Name": "Alice"}
+	filters := []db.Filter{db.Eq("Name", "Alice")}
 	sort := []db.Sort{{Field: "Name", Direction: "asc"}}
 	pagination := db.Pagination{Limit: 2, Offset: 0}
 
@@ -108,6 +150,38 @@ func TestPostgresAdapter_FindAll(t *testing.T) {
 	require.Equal(t, "Alice", slice[0].Name)
 }
 
+func TestPostgresAdapter_FindAll_FilterOperators(t *testing.T) {
+	adapter := setupTestAdapter(t)
+
+	require.NoError(t, adapter.Create(&TestEntity{ID: "1", Name: "Alice"}))
+	require.NoError(t, adapter.Create(&TestEntity{ID: "2", Name: "Bob"}))
+	require.NoError(t, adapter.Create(&TestEntity{ID: "3", Name: "Charlie"}))
+
+	cases := []struct {
+		filter   db.Filter
+		expected []string
+	}{
+		{db.Filter{Field: "name", Operator: db.OpNe, Value: "Bob"}, []string{"1", "3"}},
+		{db.Filter{Field: "id", Operator: db.OpGt, Value: "1"}, []string{"2", "3"}},
+		{db.Filter{Field: "id", Operator: db.OpLte, Value: "2"}, []string{"1", "2"}},
+		{db.Filter{Field: "name", Operator: db.OpIn, Value: []string{"Alice", "Charlie"}}, []string{"1", "3"}},
+		{db.Filter{Field: "name", Operator: db.OpLike, Value: "%li%"}, []string{"1", "3"}},
+		{db.Filter{Field: "name", Operator: db.OpNull, Value: false}, []string{"1", "2", "3"}},
+		{db.Filter{Field: "name", Operator: db.OpNull, Value: true}, []string{}},
+	}
+
+	for _, c := range cases {
+		results, err := adapter.FindAll(TestEntity{}, []db.Filter{c.filter}, db.Pagination{}, []db.Sort{{Field: "id", Direction: "asc"}})
+		require.NoError(t, err)
+
+		ids := []string{}
+		for _, e := range results.([]TestEntity) {
+			ids = append(ids, e.ID)
+		}
+		require.Equal(t, c.expected, ids, "filter %+v", c.filter)
+	}
+}
+
 func TestPostgresAdapter_FindAll_NoFilters(t *testing.T) {
 	adapter := setupTestAdapter(t)
 
@@ -133,3 +207,312 @@ func TestPostgresAdapter_MigrateMultipleEntities(t *testing.T) {
 	err := adapter.Migrate([]any{&TestEntity{}, &AnotherEntity{}})
 	require.NoError(t, err)
 }
+
+func TestPostgresAdapter_WithContext_Canceled(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	require.NoError(t, adapter.Create(&TestEntity{ID: "1", Name: "Alice"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := adapter.WithContext(ctx).FindAll(TestEntity{}, nil, db.Pagination{}, nil)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// the original adapter is not bound to the canceled context
+	_, err = adapter.FindAll(TestEntity{}, nil, db.Pagination{}, nil)
+	require.NoError(t, err)
+}
+
+func TestPostgresAdapter_WithTransaction_Commit(t *testing.T) {
+	adapter := setupTestAdapter(t)
+
+	err := adapter.WithTransaction(func(tx db.DBAdapter) error {
+		if err := tx.Create(&TestEntity{ID: "1", Name: "Alice"}); err != nil {
+			return err
+		}
+		return tx.Create(&TestEntity{ID: "2", Name: "Bob"})
+	})
+	require.NoError(t, err)
+
+	results, err := adapter.FindAll(TestEntity{}, nil, db.Pagination{}, nil)
+	require.NoError(t, err)
+	require.Len(t, results.([]TestEntity), 2)
+}
+
+func TestPostgresAdapter_WithTransaction_Rollback(t *testing.T) {
+	adapter := setupTestAdapter(t)
+
+	err := adapter.WithTransaction(func(tx db.DBAdapter) error {
+		if err := tx.Create(&TestEntity{ID: "1", Name: "Alice"}); err != nil {
+			return err
+		}
+		return errors.New("after hook failed")
+	})
+	require.EqualError(t, err, "after hook failed")
+
+	var result TestEntity
+	_, err = adapter.FindByID("1", &result)
+	require.Error(t, err, "insert should have been rolled back")
+}
+
+func TestPostgresAdapter_Count(t *testing.T) {
+	adapter := setupTestAdapter(t)
+
+	require.NoError(t, adapter.Create(&TestEntity{ID: "1", Name: "Alice"}))
+	require.NoError(t, adapter.Create(&TestEntity{ID: "2", Name: "Bob"}))
+	require.NoError(t, adapter.Create(&TestEntity{ID: "3", Name: "Charlie"}))
+
+	total, err := adapter.Count(TestEntity{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), total)
+
+	total, err = adapter.Count(TestEntity{}, []db.Filter{{Field: "name", Operator: db.OpNe, Value: "Bob"}})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+}
+
+func TestPostgresAdapter_FindAll_Keyset(t *testing.T) {
+	adapter := setupTestAdapter(t)
+
+	require.NoError(t, adapter.Create(&TestEntity{ID: "1", Name: "Bob"}))
+	require.NoError(t, adapter.Create(&TestEntity{ID: "2", Name: "Alice"}))
+	require.NoError(t, adapter.Create(&TestEntity{ID: "3", Name: "Bob"}))
+	require.NoError(t, adapter.Create(&TestEntity{ID: "4", Name: "Charlie"}))
+
+	sort := []db.Sort{{Field: "name", Direction: "desc"}, {Field: "id", Direction: "asc"}}
+
+	// Offset is ignored in keyset mode
+	results, err := adapter.FindAll(TestEntity{}, nil, db.Pagination{Limit: 2, Offset: 5, After: []any{"Bob", "1"}}, sort)
+	require.NoError(t, err)
+
+	slice := results.([]TestEntity)
+	require.Len(t, slice, 2)
+	require.Equal(t, "3", slice[0].ID)
+	require.Equal(t, "2", slice[1].ID)
+
+	_, err = adapter.FindAll(TestEntity{}, nil, db.Pagination{After: []any{"Bob"}}, sort)
+	require.Error(t, err)
+}
+
+func TestPostgresAdapter_FindAll_RejectsUnknownFields(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	require.NoError(t, adapter.Create(&TestEntity{ID: "1", Name: "Alice"}))
+
+	_, err := adapter.FindAll(TestEntity{}, []db.Filter{db.Eq("1=1 OR name", "x")}, db.Pagination{}, nil)
+	require.EqualError(t, err, `unknown field "1=1 OR name"`)
+
+	_, err = adapter.FindAll(TestEntity{}, nil, db.Pagination{}, []db.Sort{{Field: "(SELECT 1)", Direction: "asc"}})
+	require.Error(t, err)
+
+	// Go field names and column names are both accepted
+	results, err := adapter.FindAll(TestEntity{}, []db.Filter{db.Eq("Name", "Alice")}, db.Pagination{}, []db.Sort{{Field: "id"}})
+	require.NoError(t, err)
+	require.Len(t, results.([]TestEntity), 1)
+}
+
+func TestPostgresAdapter_TranslatesErrors(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	require.NoError(t, adapter.Create(&TestEntity{ID: "1", Name: "Alice"}))
+
+	err := adapter.Create(&TestEntity{ID: "1", Name: "Bob"})
+	require.ErrorIs(t, err, gerrors.ErrConflict)
+
+	_, err = adapter.FindByID("missing", &TestEntity{})
+	require.ErrorIs(t, err, gerrors.ErrNotFound)
+}
+
+type SoftEntity struct {
+	ID        string `gorm:"primaryKey"`
+	Name      string
+	DeletedAt *time.Time
+}
+
+type GormSoftEntity struct {
+	ID        string `gorm:"primaryKey"`
+	Name      string
+	DeletedAt gorm.DeletedAt
+}
+
+func TestPostgresAdapter_SoftDelete(t *testing.T) {
+	for _, entity := range []any{&SoftEntity{}, &GormSoftEntity{}} {
+		adapter := setupTestAdapter(t)
+		require.NoError(t, adapter.Migrate([]any{entity}))
+
+		switch entity.(type) {
+		case *SoftEntity:
+			require.NoError(t, adapter.Create(&SoftEntity{ID: "1", Name: "Alice"}))
+			require.NoError(t, adapter.Create(&SoftEntity{ID: "2", Name: "Bob"}))
+		case *GormSoftEntity:
+			require.NoError(t, adapter.Create(&GormSoftEntity{ID: "1", Name: "Alice"}))
+			require.NoError(t, adapter.Create(&GormSoftEntity{ID: "2", Name: "Bob"}))
+		}
+
+		require.NoError(t, adapter.Delete("1", entity))
+		require.ErrorIs(t, adapter.Delete("1", entity), gerrors.ErrNotFound)
+
+		_, err := adapter.FindByID("1", entity)
+		require.ErrorIs(t, err, gerrors.ErrNotFound)
+
+		count, err := adapter.Count(entity, nil)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), count)
+
+		count, err = adapter.IncludeDeleted().Count(entity, nil)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), count)
+
+		_, err = adapter.IncludeDeleted().FindByID("1", entity)
+		require.NoError(t, err)
+
+		require.NoError(t, adapter.Restore("1", entity))
+		require.ErrorIs(t, adapter.Restore("1", entity), gerrors.ErrNotFound)
+
+		results, err := adapter.FindAll(entity, nil, db.Pagination{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, 2, reflect.ValueOf(results).Len())
+	}
+}
+
+func TestPostgresAdapter_SoftDelete_UpdateKeepsMark(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	require.NoError(t, adapter.Migrate([]any{&SoftEntity{}}))
+	require.NoError(t, adapter.Create(&SoftEntity{ID: "1", Name: "Bob"}))
+	require.NoError(t, adapter.Update(&SoftEntity{ID: "1", Name: "Alice"}))
+	require.NoError(t, adapter.Delete("1", &SoftEntity{}))
+
+	err := adapter.Update(&SoftEntity{ID: "1", Name: "Mallory"})
+	require.ErrorIs(t, err, gerrors.ErrNotFound)
+
+	found, err := adapter.IncludeDeleted().FindByID("1", &SoftEntity{})
+	require.NoError(t, err)
+	require.Equal(t, "Alice", found.(*SoftEntity).Name)
+	require.NotNil(t, found.(*SoftEntity).DeletedAt)
+}
+
+func TestPostgresAdapter_Restore_NotSoftDeletable(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	require.ErrorIs(t, adapter.Restore("1", &TestEntity{}), gerrors.ErrBadRequest)
+}
+
+type VersionedEntity struct {
+	ID        string `gorm:"primaryKey"`
+	Name      string
+	Version   int
+	DeletedAt *time.Time
+}
+
+func TestPostgresAdapter_OptimisticLocking(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	require.NoError(t, adapter.Migrate([]any{&VersionedEntity{}}))
+
+	entity := &VersionedEntity{ID: "1", Name: "Alice", Version: 9}
+	require.NoError(t, adapter.Create(entity))
+	require.Equal(t, 1, entity.Version)
+
+	require.NoError(t, adapter.Update(&VersionedEntity{ID: "1", Name: "Bob", Version: 1}))
+
+	stale := &VersionedEntity{ID: "1", Name: "Carol", Version: 1}
+	require.ErrorIs(t, adapter.Update(stale), gerrors.ErrPreconditionFailed)
+	require.Equal(t, 1, stale.Version, "a rejected update leaves the version alone")
+
+	// Without a version the update is unconditional but still bumps the version
+	unconditional := &VersionedEntity{ID: "1", Name: "Dave"}
+	require.NoError(t, adapter.Update(unconditional))
+	require.Equal(t, 3, unconditional.Version)
+
+	found, err := adapter.FindByID("1", &VersionedEntity{})
+	require.NoError(t, err)
+	require.Equal(t, "Dave", found.(*VersionedEntity).Name)
+	require.Equal(t, 3, found.(*VersionedEntity).Version)
+
+	require.ErrorIs(t, adapter.Update(&VersionedEntity{ID: "2", Version: 1}), gerrors.ErrNotFound)
+
+	require.ErrorIs(t, adapter.Delete("1", &VersionedEntity{Version: 2}), gerrors.ErrPreconditionFailed)
+	require.NoError(t, adapter.Delete("1", &VersionedEntity{Version: 3}))
+}
+
+type Customer struct {
+	ID     int `gorm:"primaryKey"`
+	Name   string
+	Orders []Order
+}
+
+type Order struct {
+	ID         int `gorm:"primaryKey"`
+	CustomerID int
+	Customer   *Customer
+}
+
+func TestPostgresAdapter_Preload(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	require.NoError(t, adapter.Migrate([]any{&Customer{}, &Order{}}))
+	require.NoError(t, adapter.Create(&Customer{ID: 1, Name: "Alice"}))
+	require.NoError(t, adapter.Create(&Order{ID: 10, CustomerID: 1}))
+	require.NoError(t, adapter.Create(&Order{ID: 11, CustomerID: 1}))
+
+	found, err := adapter.Preload("Orders").FindByID("1", &Customer{})
+	require.NoError(t, err)
+	require.Len(t, found.(*Customer).Orders, 2)
+
+	orders, err := adapter.Preload("Customer").FindAll(&Order{}, nil, db.Pagination{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "Alice", orders.([]Order)[0].Customer.Name)
+
+	// Without Preload relations stay empty
+	found, err = adapter.FindByID("1", &Customer{})
+	require.NoError(t, err)
+	require.Empty(t, found.(*Customer).Orders)
+
+	_, err = adapter.Preload("Name").FindByID("1", &Customer{})
+	require.ErrorIs(t, err, gerrors.ErrBadRequest)
+}
+
+func TestPostgresAdapter_Conformance(t *testing.T) {
+	dbtest.RunConformance(t, func(t *testing.T) db.DBAdapter {
+		dbConn, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{TranslateError: true})
+		require.NoError(t, err)
+		return &PostgresAdapter{gormadapter.FromDB(dbConn, translateError)}
+	})
+}
+
+type Article struct {
+	ID    int `gorm:"primaryKey"`
+	Title string
+	Body  string
+}
+
+func TestPostgresAdapter_Search(t *testing.T) {
+	adapter := setupTestAdapter(t)
+	require.NoError(t, adapter.Migrate([]any{&Article{}}))
+	require.NoError(t, adapter.CreateMany([]any{
+		&Article{ID: 1, Title: "Cooking", Body: "Go to the market"},
+		&Article{ID: 2, Title: "Go tips", Body: "Go modules and go vet"},
+		&Article{ID: 3, Title: "Gardening", Body: "Nothing to see"},
+	}))
+
+	// SQLite has no tsvector: the LIKE fallback ranks rows by the fields they match in.
+	result, err := adapter.Search("go", "Title", "Body").FindAll(&Article{}, nil, db.Pagination{}, nil)
+	require.NoError(t, err)
+	articles := result.([]Article)
+	require.Len(t, articles, 2)
+	require.Equal(t, 2, articles[0].ID)
+	require.Equal(t, 1, articles[1].ID)
+}
+
+func TestPostgresAdapter_SearchUsesTsvector(t *testing.T) {
+	conn, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	require.NoError(t, err)
+
+	var query string
+	require.NoError(t, conn.Callback().Query().After("gorm:query").Register("capture", func(tx *gorm.DB) {
+		query = tx.Dialector.Explain(tx.Statement.SQL.String(), tx.Statement.Vars...)
+	}))
+
+	adapter := &PostgresAdapter{gormadapter.FromDB(conn, translateError)}
+	_, err = adapter.Search("Go modules", "Title", "Body").FindAll(&Article{}, nil, db.Pagination{}, nil)
+	require.NoError(t, err)
+
+	document := `to_tsvector('simple', coalesce("title"::text, '') || ' ' || coalesce("body"::text, ''))`
+	require.Contains(t, query, `WHERE `+document+` @@ to_tsquery('simple', 'go:* & modules:*')`)
+	require.Contains(t, query, `ORDER BY ts_rank(`+document+`, to_tsquery('simple', 'go:* & modules:*')) DESC`)
+}
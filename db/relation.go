@@ -0,0 +1,127 @@
+package db
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+type RelationKind int
+
+const (
+	BelongsTo RelationKind = iota
+	HasOne
+	HasMany
+)
+
+// Relation is an association declared by a struct field of the owning entity.
+//
+// For BelongsTo the ForeignKey field lives on the owner and References on Target;
+// for HasOne and HasMany ForeignKey lives on Target and References on the owner.
+type Relation struct {
+	Field      string
+	Kind       RelationKind
+	Target     reflect.Type
+	ForeignKey string
+	References string
+}
+
+// Relations lists the associations of entity type t. They follow GORM's conventions:
+// a `Customer *Customer` field next to `CustomerID` belongs to a customer, and an
+// `Orders []Order` field on Customer has many orders holding `CustomerID`. The keys
+// can be overridden with `gompose:"foreignKey:OwnerID;references:ID"`, and GORM's
+// `gorm:"foreignKey:...;references:..."` tag is honoured as well.
+func Relations(t reflect.Type) []Relation {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var relations []Relation
+	for _, f := range reflect.VisibleFields(t) {
+		if f.Anonymous || !f.IsExported() {
+			continue
+		}
+		if rel, ok := relationFor(t, f); ok {
+			relations = append(relations, rel)
+		}
+	}
+	return relations
+}
+
+// RelationByField returns the relation declared by the Go field name of t.
+func RelationByField(t reflect.Type, field string) (Relation, bool) {
+	for _, rel := range Relations(t) {
+		if rel.Field == field {
+			return rel, true
+		}
+	}
+	return Relation{}, false
+}
+
+func relationFor(owner reflect.Type, f reflect.StructField) (Relation, bool) {
+	target, many := f.Type, false
+	if target.Kind() == reflect.Slice {
+		target, many = target.Elem(), true
+	}
+	if target.Kind() == reflect.Ptr {
+		target = target.Elem()
+	}
+	if target.Kind() != reflect.Struct || target == reflect.TypeOf(time.Time{}) {
+		return Relation{}, false
+	}
+
+	foreignKey, references := relationTag(f)
+	rel := Relation{Field: f.Name, Target: target, ForeignKey: foreignKey, References: references}
+	if rel.References == "" {
+		rel.References = "ID"
+	}
+
+	if many {
+		rel.Kind = HasMany
+		if rel.ForeignKey == "" {
+			rel.ForeignKey = owner.Name() + "ID"
+		}
+		return rel, hasField(target, rel.ForeignKey) && hasField(owner, rel.References)
+	}
+
+	belongsTo := rel
+	belongsTo.Kind = BelongsTo
+	if belongsTo.ForeignKey == "" {
+		belongsTo.ForeignKey = f.Name + "ID"
+	}
+	if hasField(owner, belongsTo.ForeignKey) && hasField(target, belongsTo.References) {
+		return belongsTo, true
+	}
+
+	rel.Kind = HasOne
+	if rel.ForeignKey == "" {
+		rel.ForeignKey = owner.Name() + "ID"
+	}
+	return rel, hasField(target, rel.ForeignKey) && hasField(owner, rel.References)
+}
+
+func relationTag(f reflect.StructField) (foreignKey, references string) {
+	for _, tag := range []string{f.Tag.Get("gompose"), f.Tag.Get("gorm")} {
+		for _, setting := range strings.Split(tag, ";") {
+			key, value, _ := strings.Cut(setting, ":")
+			switch strings.ToLower(strings.TrimSpace(key)) {
+			case "foreignkey":
+				foreignKey = strings.TrimSpace(value)
+			case "references":
+				references = strings.TrimSpace(value)
+			}
+		}
+		if foreignKey != "" || references != "" {
+			return foreignKey, references
+		}
+	}
+	return "", ""
+}
+
+func hasField(t reflect.Type, name string) bool {
+	_, ok := t.FieldByName(name)
+	return ok
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type Customer struct {
+	ID      int
+	Name    string
+	Orders  []Order
+	Profile *Profile
+}
+
+type Profile struct {
+	ID         int
+	CustomerID int
+}
+
+type Order struct {
+	ID         int
+	CustomerID int
+	Customer   *Customer
+	Items      []LineItem `gompose:"foreignKey:OrderRef"`
+	CreatedAt  time.Time
+}
+
+type LineItem struct {
+	ID       int
+	OrderRef int
+}
+
+func TestRelations(t *testing.T) {
+	require.Equal(t, []Relation{
+		{Field: "Orders", Kind: HasMany, Target: reflect.TypeOf(Order{}), ForeignKey: "CustomerID", References: "ID"},
+		{Field: "Profile", Kind: HasOne, Target: reflect.TypeOf(Profile{}), ForeignKey: "CustomerID", References: "ID"},
+	}, Relations(reflect.TypeOf(&Customer{})))
+
+	require.Equal(t, []Relation{
+		{Field: "Customer", Kind: BelongsTo, Target: reflect.TypeOf(Customer{}), ForeignKey: "CustomerID", References: "ID"},
+		{Field: "Items", Kind: HasMany, Target: reflect.TypeOf(LineItem{}), ForeignKey: "OrderRef", References: "ID"},
+	}, Relations(reflect.TypeOf(Order{})))
+}
+
+func TestRelationByField(t *testing.T) {
+	rel, ok := RelationByField(reflect.TypeOf(Order{}), "Customer")
+	require.True(t, ok)
+	require.Equal(t, BelongsTo, rel.Kind)
+
+	_, ok = RelationByField(reflect.TypeOf(Order{}), "CreatedAt")
+	require.False(t, ok)
+}
@@ -0,0 +1,17 @@
+package db
+
+import "context"
+
+type primaryKey struct{}
+
+// WithPrimary marks ctx so that adapters with read replicas serve its reads from the
+// primary, e.g. to read back an entity right after writing it.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// ReadsPrimary reports whether ctx was marked by WithPrimary.
+func ReadsPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
@@ -0,0 +1,23 @@
+package db
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SearchTerms splits a search query into its lower-cased words, dropping punctuation and
+// repeated words. Terms only hold letters and digits, so adapters can embed them in
+// patterns without escaping.
+func SearchTerms(query string) []string {
+	var terms []string
+	seen := map[string]bool{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !seen[word] {
+			seen[word] = true
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
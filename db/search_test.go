@@ -0,0 +1,12 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchTerms(t *testing.T) {
+	require.Equal(t, []string{"go", "modules", "v2", "çay"}, SearchTerms("  Go modules; go-v2? Çay "))
+	require.Empty(t, SearchTerms(" %_!' "))
+}
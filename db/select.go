@@ -0,0 +1,40 @@
+package db
+
+import "reflect"
+
+// SelectedFields expands the fields of a restricted read (see DBAdapter.Select) with the
+// fields adapters have to load anyway: the ID, and the keys on entity type t that the
+// preloaded relations are joined on. Duplicates are dropped and the order is kept.
+func SelectedFields(t reflect.Type, fields, preloads []string) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var selected []string
+	seen := map[string]bool{}
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			selected = append(selected, name)
+		}
+	}
+
+	if _, ok := t.FieldByName("ID"); ok {
+		add("ID")
+	}
+	for _, name := range fields {
+		add(name)
+	}
+	for _, name := range preloads {
+		rel, ok := RelationByField(t, name)
+		if !ok {
+			continue
+		}
+		if rel.Kind == BelongsTo {
+			add(rel.ForeignKey)
+		} else {
+			add(rel.References)
+		}
+	}
+	return selected
+}
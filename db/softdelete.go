@@ -0,0 +1,21 @@
+package db
+
+import "reflect"
+
+// DeletedAtField names the field that makes an entity soft-deletable: adapters set it on
+// Delete instead of removing the record, and hide records where it is set from reads.
+// It should be a *time.Time or gorm.DeletedAt so that "not deleted" is stored as NULL.
+const DeletedAtField = "DeletedAt"
+
+// IsSoftDeletable reports whether entity has a DeletedAt field.
+func IsSoftDeletable(entity any) bool {
+	t := reflect.TypeOf(entity)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	_, ok := t.FieldByName(DeletedAtField)
+	return ok
+}
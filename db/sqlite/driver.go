@@ -0,0 +1,37 @@
+package sqlite
+
+import (
+	"strings"
+
+	"github.com/Lumicrate/gompose/db/gormadapter"
+	"github.com/glebarez/sqlite"
+)
+
+// SQLiteAdapter stores entities in a SQLite database through a pure Go driver, so it
+// needs neither cgo nor a database server. The DSN is a file path such as "data.db",
+// optionally with pragmas ("data.db?_pragma=foreign_keys(1)"), or ":memory:".
+type SQLiteAdapter struct {
+	*gormadapter.Adapter
+	dsn string
+}
+
+func New(dsn string) *SQLiteAdapter {
+	return &SQLiteAdapter{Adapter: gormadapter.New(sqlite.Open(dsn), nil), dsn: dsn}
+}
+
+func (s *SQLiteAdapter) Init() error {
+	if err := s.Adapter.Init(); err != nil {
+		return err
+	}
+
+	// Every connection to ":memory:" opens a separate empty database, so the pool
+	// is limited to a single connection to keep one database for the adapter.
+	if strings.Contains(s.dsn, ":memory:") {
+		sqlDB, err := s.DB().DB()
+		if err != nil {
+			return err
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Lumicrate/gompose/db"
+	"github.com/Lumicrate/gompose/db/dbtest"
+	gerrors "github.com/Lumicrate/gompose/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type Product struct {
+	ID    int `gorm:"primaryKey;autoIncrement"`
+	Name  string
+	SKU   string `gorm:"uniqueIndex"`
+	Price float64
+}
+
+func setupTestAdapter(t *testing.T) *SQLiteAdapter {
+	adapter := New(":memory:")
+	require.NoError(t, adapter.Init())
+	require.NoError(t, adapter.Migrate([]any{&Product{}}))
+
+	for i, name := range []string{"apple", "banana", "cherry", "date"} {
+		require.NoError(t, adapter.Create(&Product{Name: name, SKU: name, Price: float64(i + 1)}))
+	}
+	return adapter
+}
+
+func TestSQLiteAdapter_CRUD(t *testing.T) {
+	adapter := setupTestAdapter(t)
+
+	product := &Product{Name: "elderberry", SKU: "elderberry", Price: 9}
+	require.NoError(t, adapter.Create(product))
+	require.NotZero(t, product.ID)
+
+	product.Price = 10
+	require.NoError(t, adapter.Update(product))
+
+	found, err := adapter.FindByID("5", &Product{})
+	require.NoError(t, err)
+	require.Equal(t, 10.0, found.(*Product).Price)
+
+	require.NoError(t, adapter.Delete("5", &Product{}))
+	_, err = adapter.FindByID("5", &Product{})
+	require.ErrorIs(t, err, gerrors.ErrNotFound)
+}
+
+func TestSQLiteAdapter_FindAll(t *testing.T) {
+	adapter := setupTestAdapter(t)
+
+	filters := []db.Filter{
+		{Field: "Price", Operator: db.OpGte, Value: "2"},
+		{Field: "Name", Operator: db.OpLike, Value: "%a%"},
+	}
+	sort := []db.Sort{{Field: "Price", Direction: "desc"}}
+
+	result, err := adapter.FindAll(&Product{}, filters, db.Pagination{Limit: 2}, sort)
+	require.NoError(t, err)
+	products := result.([]Product)
+	require.Len(t, products, 2)
+	require.Equal(t, "date", products[0].Name)
+	require.Equal(t, "banana", products[1].Name)
+
+	count, err := adapter.Count(&Product{}, filters)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	result, err = adapter.FindAll(&Product{}, nil, db.Pagination{Limit: 2, After: []any{"2"}}, []db.Sort{{Field: "ID", Direction: "asc"}})
+	require.NoError(t, err)
+	require.Equal(t, "cherry", result.([]Product)[0].Name)
+}
+
+func TestSQLiteAdapter_UniqueViolationIsConflict(t *testing.T) {
+	adapter := setupTestAdapter(t)
+
+	err := adapter.Create(&Product{Name: "apple again", SKU: "apple"})
+	require.ErrorIs(t, err, gerrors.ErrConflict)
+}
+
+func TestSQLiteAdapter_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gompose.db")
+
+	adapter := New(path)
+	require.NoError(t, adapter.Init())
+	require.NoError(t, adapter.Migrate([]any{&Product{}}))
+	require.NoError(t, adapter.Create(&Product{Name: "apple", SKU: "apple"}))
+
+	reopened := New(path)
+	require.NoError(t, reopened.Init())
+	count, err := reopened.Count(&Product{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+}
+
+func TestSQLiteAdapter_Conformance(t *testing.T) {
+	dbtest.RunConformance(t, func(t *testing.T) db.DBAdapter {
+		adapter := New(":memory:")
+		require.NoError(t, adapter.Init())
+		return adapter
+	})
+}
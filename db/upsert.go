@@ -0,0 +1,29 @@
+package db
+
+import (
+	"errors"
+
+	gerrors "github.com/Lumicrate/gompose/errors"
+)
+
+// UpsertWith implements Upsert with Update and Create, for adapters without a native
+// upsert that honours versions and soft deletes. Call it inside a transaction so that no
+// other write can slip in between the two steps.
+func UpsertWith(adapter DBAdapter, entity any) (bool, error) {
+	expected := EntityVersion(entity)
+
+	err := adapter.Update(entity)
+	if !errors.Is(err, gerrors.ErrNotFound) {
+		return false, err
+	}
+	if expected > 0 {
+		// A version can only match an existing entity.
+		return false, gerrors.PreconditionFailed("entity does not exist")
+	}
+
+	// A soft-deleted entity still holds its ID, so creating it again fails with a conflict.
+	if err := adapter.Create(entity); err != nil {
+		return false, err
+	}
+	return true, nil
+}
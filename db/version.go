@@ -0,0 +1,59 @@
+package db
+
+import "reflect"
+
+// VersionField names the integer field used for optimistic concurrency control. Adapters
+// start it at 1 on Create and increment it on every Update. An Update or Delete whose
+// entity carries a non-zero version only applies if the stored version still matches;
+// otherwise it fails with a precondition error. A zero version skips the check.
+const VersionField = "Version"
+
+// IsVersioned reports whether entity has an integer Version field.
+func IsVersioned(entity any) bool {
+	_, ok := versionValue(entity)
+	return ok
+}
+
+// EntityVersion returns the Version field of entity.
+func EntityVersion(entity any) int64 {
+	v, ok := versionValue(entity)
+	if !ok {
+		return 0
+	}
+	if v.CanInt() {
+		return v.Int()
+	}
+	return int64(v.Uint())
+}
+
+// SetEntityVersion sets the Version field of entity, which must be a pointer.
+func SetEntityVersion(entity any, version int64) {
+	v, ok := versionValue(entity)
+	if !ok || !v.CanSet() {
+		return
+	}
+	if v.CanInt() {
+		v.SetInt(version)
+	} else {
+		v.SetUint(uint64(version))
+	}
+}
+
+func versionValue(entity any) (reflect.Value, bool) {
+	v := reflect.ValueOf(entity)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	field := v.FieldByName(VersionField)
+	if !field.IsValid() || !(field.CanInt() || field.CanUint()) {
+		return reflect.Value{}, false
+	}
+	return field, true
+}
@@ -0,0 +1,31 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type versioned struct {
+	ID      string
+	Version uint
+}
+
+func TestEntityVersion(t *testing.T) {
+	entity := &versioned{ID: "1", Version: 3}
+
+	require.True(t, IsVersioned(entity))
+	require.True(t, IsVersioned(versioned{}))
+	require.Equal(t, int64(3), EntityVersion(entity))
+
+	SetEntityVersion(entity, 4)
+	require.Equal(t, uint(4), entity.Version)
+}
+
+func TestIsVersioned_RequiresIntegerField(t *testing.T) {
+	type stringVersion struct{ Version string }
+
+	require.False(t, IsVersioned(stringVersion{}))
+	require.False(t, IsVersioned(struct{ ID string }{}))
+	require.False(t, IsVersioned(nil))
+}
@@ -2,9 +2,11 @@ package swagger
 
 import (
 	"fmt"
+	"github.com/Lumicrate/gompose/db"
 	"github.com/Lumicrate/gompose/http"
 	"github.com/getkin/kin-openapi/openapi3"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -77,26 +79,110 @@ func (s *SwaggerProvider) Generate(engine http.HTTPEngine) *openapi3.T {
 			Parameters:  pathParams,
 		}
 
+		// Bulk routes take and return one item per entity
+		bulk := strings.HasSuffix(path, "/bulk")
+		bodySchema, responseSchema := schemaRef, schemaRef
+		if bulk {
+			bodySchema = arraySchema(schemaRef)
+			responseSchema = bulkResponseSchema(schemaRef)
+			operation.Parameters = append(operation.Parameters, &openapi3.ParameterRef{Value: &openapi3.Parameter{
+				Name:        "atomic",
+				In:          "query",
+				Description: "Write nothing unless every item succeeds",
+				Required:    false,
+				Schema:      &openapi3.SchemaRef{Value: &openapi3.Schema{Type: &openapi3.Types{"boolean"}}},
+			}})
+			if r.Method == "DELETE" {
+				operation.Parameters = append(operation.Parameters, &openapi3.ParameterRef{Value: &openapi3.Parameter{
+					Name:        "ids",
+					In:          "query",
+					Description: "Comma-separated IDs of the entities to delete",
+					Required:    true,
+					Schema:      &openapi3.SchemaRef{Value: &openapi3.Schema{Type: &openapi3.Types{"string"}}},
+				}})
+			}
+		}
+
+		// Aggregate routes return one object per group instead of entities
+		aggregate := strings.HasSuffix(path, "/aggregate")
+		if aggregate {
+			responseSchema = arraySchema(&openapi3.SchemaRef{Value: &openapi3.Schema{
+				Type:                 &openapi3.Types{"object"},
+				AdditionalProperties: openapi3.AdditionalProperties{Has: ptrBool(true)},
+			}})
+			operation.Parameters = append(operation.Parameters,
+				&openapi3.ParameterRef{Value: &openapi3.Parameter{
+					Name:        "group_by",
+					In:          "query",
+					Description: "Comma-separated fields to group by",
+					Required:    false,
+					Schema:      &openapi3.SchemaRef{Value: &openapi3.Schema{Type: &openapi3.Types{"string"}}},
+				}},
+				&openapi3.ParameterRef{Value: &openapi3.Parameter{
+					Name:        "metrics",
+					In:          "query",
+					Description: "Comma-separated metrics: count, sum(field), avg(field), min(field), max(field). Defaults to count",
+					Required:    false,
+					Schema:      &openapi3.SchemaRef{Value: &openapi3.Schema{Type: &openapi3.Types{"string"}}},
+				}},
+				&openapi3.ParameterRef{Value: &openapi3.Parameter{
+					Name:        "sort",
+					In:          "query",
+					Description: "Comma-separated group-by fields or metrics to order the groups by (prefix with - for descending)",
+					Required:    false,
+					Schema:      &openapi3.SchemaRef{Value: &openapi3.Schema{Type: &openapi3.Types{"string"}}},
+				}},
+				&openapi3.ParameterRef{Value: &openapi3.Parameter{
+					Name:        "limit",
+					In:          "query",
+					Description: "Maximum number of groups to return",
+					Required:    false,
+					Schema:      &openapi3.SchemaRef{Value: &openapi3.Schema{Type: &openapi3.Types{"integer"}}},
+				}},
+				&openapi3.ParameterRef{Value: &openapi3.Parameter{
+					Name:        "offset",
+					In:          "query",
+					Description: "Number of groups to skip",
+					Required:    false,
+					Schema:      &openapi3.SchemaRef{Value: &openapi3.Schema{Type: &openapi3.Types{"integer"}}},
+				}},
+			)
+			if r.Entity != nil && db.IsSoftDeletable(r.Entity) {
+				operation.Parameters = append(operation.Parameters, includeDeletedParameter())
+			}
+			if r.Entity != nil {
+				operation.Parameters = append(operation.Parameters, filterParameters(reflect.TypeOf(r.Entity))...)
+			}
+		}
+
 		// Default responses
 		operation.Responses.Set("200", &openapi3.ResponseRef{
 			Value: &openapi3.Response{
 				Description: ptrString("Successful response"),
-				Content:     NewContentWithJSONSchema(schemaRef),
+				Content:     NewContentWithJSONSchema(responseSchema),
 			},
 		})
+		if bulk {
+			operation.Responses.Set("207", &openapi3.ResponseRef{
+				Value: &openapi3.Response{
+					Description: ptrString("Some items failed; see the status of each result"),
+					Content:     NewContentWithJSONSchema(responseSchema),
+				},
+			})
+		}
 
 		// Request body for write methods
-		if r.Method == "POST" || r.Method == "PUT" || r.Method == "PATCH" {
+		if (r.Method == "POST" && !strings.HasSuffix(path, "/restore")) || r.Method == "PUT" || r.Method == "PATCH" {
 			operation.RequestBody = &openapi3.RequestBodyRef{
 				Value: &openapi3.RequestBody{
 					Description: "Request body for " + r.Method,
 					Required:    true,
-					Content:     NewContentWithJSONSchema(schemaRef),
+					Content:     NewContentWithJSONSchema(bodySchema),
 				},
 			}
 		}
 
-		if r.Method == "GET" && !strings.Contains(path, "{id}") {
+		if r.Method == "GET" && !strings.Contains(path, "{id}") && !aggregate {
 			// Add pagination query params
 			operation.Parameters = append(operation.Parameters,
 				&openapi3.ParameterRef{Value: &openapi3.Parameter{
@@ -122,19 +208,13 @@ func (s *SwaggerProvider) Generate(engine http.HTTPEngine) *openapi3.T {
 				}},
 			)
 
+			if r.Entity != nil && db.IsSoftDeletable(r.Entity) {
+				operation.Parameters = append(operation.Parameters, includeDeletedParameter())
+			}
+
 			// Add filter params based on entity fields (if available)
-			if schemaRef != nil && schemaRef.Value != nil {
-				for fieldName, fieldSchema := range schemaRef.Value.Properties {
-					operation.Parameters = append(operation.Parameters,
-						&openapi3.ParameterRef{Value: &openapi3.Parameter{
-							Name:        fieldName,
-							In:          "query",
-							Description: "Filter by " + fieldName,
-							Required:    false,
-							Schema:      fieldSchema,
-						}},
-					)
-				}
+			if r.Entity != nil {
+				operation.Parameters = append(operation.Parameters, filterParameters(reflect.TypeOf(r.Entity))...)
 			}
 		}
 
@@ -152,6 +232,27 @@ func (s *SwaggerProvider) Generate(engine http.HTTPEngine) *openapi3.T {
 			pathItem.Delete = operation
 		}
 
+		if r.Method == "GET" && strings.HasSuffix(path, "{id}") && r.Entity != nil && db.IsSoftDeletable(r.Entity) {
+			operation.Parameters = append(operation.Parameters, includeDeletedParameter())
+		}
+
+		if r.Method == "GET" && r.Entity != nil && !aggregate {
+			if param := includeParameter(reflect.TypeOf(r.Entity)); param != nil {
+				operation.Parameters = append(operation.Parameters, param)
+			}
+			operation.Parameters = append(operation.Parameters, fieldsParameter(reflect.TypeOf(r.Entity)))
+		}
+
+		if (r.Method == "PUT" || r.Method == "PATCH" || r.Method == "DELETE") && r.Entity != nil && db.IsVersioned(r.Entity) {
+			operation.Parameters = append(operation.Parameters, &openapi3.ParameterRef{Value: &openapi3.Parameter{
+				Name:        "If-Match",
+				In:          "header",
+				Description: "ETag of the version being modified; the request fails with 412 if it is stale",
+				Required:    false,
+				Schema:      &openapi3.SchemaRef{Value: &openapi3.Schema{Type: &openapi3.Types{"string"}}},
+			}})
+		}
+
 		if r.Protected {
 			operation.Security = &openapi3.SecurityRequirements{
 				{"BearerAuth": {}},
@@ -175,6 +276,162 @@ func (s *SwaggerProvider) Generate(engine http.HTTPEngine) *openapi3.T {
 	return doc
 }
 
+// includeDeletedParameter documents ?include_deleted= on soft-deletable entities.
+func includeDeletedParameter() *openapi3.ParameterRef {
+	return &openapi3.ParameterRef{Value: &openapi3.Parameter{
+		Name:        "include_deleted",
+		In:          "query",
+		Description: "Include soft-deleted records (authenticated callers only)",
+		Required:    false,
+		Schema:      &openapi3.SchemaRef{Value: &openapi3.Schema{Type: &openapi3.Types{"boolean"}}},
+	}}
+}
+
+// includeParameter documents ?include= with the relations of t, or returns nil when it has none.
+func includeParameter(t reflect.Type) *openapi3.ParameterRef {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var names []string
+	for _, rel := range db.Relations(t) {
+		f, _ := t.FieldByName(rel.Field)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	return &openapi3.ParameterRef{Value: &openapi3.Parameter{
+		Name:        "include",
+		In:          "query",
+		Description: "Comma-separated relations to load: " + strings.Join(names, ", "),
+		Required:    false,
+		Schema:      &openapi3.SchemaRef{Value: &openapi3.Schema{Type: &openapi3.Types{"string"}}},
+	}}
+}
+
+// fieldsParameter documents ?fields= with the json names of the non-relation fields of t.
+func fieldsParameter(t reflect.Type) *openapi3.ParameterRef {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var names []string
+	for _, f := range reflect.VisibleFields(t) {
+		if f.Anonymous || !f.IsExported() {
+			continue
+		}
+		if _, isRelation := db.RelationByField(t, f.Name); isRelation {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names = append(names, name)
+	}
+
+	return &openapi3.ParameterRef{Value: &openapi3.Parameter{
+		Name:        "fields",
+		In:          "query",
+		Description: "Comma-separated fields to return: " + strings.Join(names, ", "),
+		Required:    false,
+		Schema:      &openapi3.SchemaRef{Value: &openapi3.Schema{Type: &openapi3.Types{"string"}}},
+	}}
+}
+
+// filterParameters documents `field` (equality) and `field[op]` query parameters
+// for every operator supported by the field's type.
+func filterParameters(t reflect.Type) openapi3.Parameters {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	properties := NewSchemaRefForValue(t).Value.Properties
+	var params openapi3.Parameters
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		jsonTag := strings.Split(f.Tag.Get("json"), ",")[0]
+		if jsonTag == "-" || jsonTag == "" {
+			continue
+		}
+
+		fieldSchema := properties[jsonTag]
+		for _, op := range db.OperatorsFor(f.Type) {
+			name := fmt.Sprintf("%s[%s]", jsonTag, op)
+			description := fmt.Sprintf("Filter by %s (%s)", jsonTag, op)
+			schema := fieldSchema
+			switch op {
+			case db.OpEq:
+				name = jsonTag
+				description = "Filter by " + jsonTag
+			case db.OpIn:
+				description = fmt.Sprintf("Filter by %s matching any of the comma-separated values", jsonTag)
+				schema = &openapi3.SchemaRef{Value: &openapi3.Schema{Type: &openapi3.Types{"string"}}}
+			case db.OpLike:
+				description = fmt.Sprintf("Filter by %s matching a pattern (%% matches any sequence, _ a single character)", jsonTag)
+			case db.OpNull:
+				description = fmt.Sprintf("Filter by whether %s is null", jsonTag)
+				schema = &openapi3.SchemaRef{Value: &openapi3.Schema{Type: &openapi3.Types{"boolean"}}}
+			}
+
+			params = append(params, &openapi3.ParameterRef{Value: &openapi3.Parameter{
+				Name:        name,
+				In:          "query",
+				Description: description,
+				Required:    false,
+				Schema:      schema,
+			}})
+		}
+	}
+
+	return params
+}
+
+func arraySchema(items *openapi3.SchemaRef) *openapi3.SchemaRef {
+	return &openapi3.SchemaRef{Value: &openapi3.Schema{Type: &openapi3.Types{"array"}, Items: items}}
+}
+
+// bulkResponseSchema describes crud.BulkResponse, whose results carry entities of the given schema.
+func bulkResponseSchema(entity *openapi3.SchemaRef) *openapi3.SchemaRef {
+	integer := func() *openapi3.SchemaRef {
+		return &openapi3.SchemaRef{Value: &openapi3.Schema{Type: &openapi3.Types{"integer"}}}
+	}
+	result := &openapi3.Schema{
+		Type: &openapi3.Types{"object"},
+		Properties: openapi3.Schemas{
+			"index":  integer(),
+			"status": integer(),
+			"data":   entity,
+			"error":  {Value: &openapi3.Schema{Type: &openapi3.Types{"object"}}},
+		},
+	}
+	return &openapi3.SchemaRef{Value: &openapi3.Schema{
+		Type: &openapi3.Types{"object"},
+		Properties: openapi3.Schemas{
+			"results":   arraySchema(&openapi3.SchemaRef{Value: result}),
+			"succeeded": integer(),
+			"failed":    integer(),
+		},
+	}}
+}
+
+// helper to create bool pointer
+func ptrBool(b bool) *bool {
+	return &b
+}
+
 // helper to create string pointer
 func ptrString(s string) *string {
 	return &s
@@ -226,12 +483,83 @@ func NewSchemaRefForValue(t reflect.Type) *openapi3.SchemaRef {
 			propSchema.Type = &openapi3.Types{"object"} // fallback
 		}
 
+		if rules := f.Tag.Get("validate"); rules != "" {
+			if applyValidationRules(propSchema, rules) {
+				schema.Required = append(schema.Required, jsonTag)
+			}
+		}
+
 		schema.Properties[jsonTag] = &openapi3.SchemaRef{Value: propSchema}
 	}
 
 	return &openapi3.SchemaRef{Value: schema}
 }
 
+// applyValidationRules maps go-playground/validator rules onto the property schema.
+// It reports whether the field is required.
+func applyValidationRules(propSchema *openapi3.Schema, rules string) bool {
+	required := false
+	isString := propSchema.Type.Is("string")
+	isArray := propSchema.Type.Is("array")
+
+	for _, rule := range strings.Split(rules, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		number, numErr := strconv.ParseFloat(param, 64)
+		count, countErr := strconv.ParseUint(param, 10, 64)
+
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			propSchema.Format = "email"
+		case "url", "uri":
+			propSchema.Format = "uri"
+		case "uuid", "uuid4":
+			propSchema.Format = "uuid"
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				propSchema.Enum = append(propSchema.Enum, v)
+			}
+		case "min", "max", "len":
+			switch {
+			case isString && countErr == nil:
+				if name != "max" {
+					propSchema.MinLength = count
+				}
+				if name != "min" {
+					propSchema.MaxLength = &count
+				}
+			case isArray && countErr == nil:
+				if name != "max" {
+					propSchema.MinItems = count
+				}
+				if name != "min" {
+					propSchema.MaxItems = &count
+				}
+			case numErr == nil:
+				if name != "max" {
+					propSchema.Min = &number
+				}
+				if name != "min" {
+					propSchema.Max = &number
+				}
+			}
+		case "gt", "gte":
+			if numErr == nil && !isString && !isArray {
+				propSchema.Min = &number
+				propSchema.ExclusiveMin = name == "gt"
+			}
+		case "lt", "lte":
+			if numErr == nil && !isString && !isArray {
+				propSchema.Max = &number
+				propSchema.ExclusiveMax = name == "lt"
+			}
+		}
+	}
+
+	return required
+}
+
 func swaggerUIHTML(jsonURL string) string {
 	return `<!DOCTYPE html>
 <html lang="en">
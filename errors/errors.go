@@ -0,0 +1,108 @@
+package errors
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Error is an error with an HTTP meaning. Adapters translate driver errors into it and
+// Write renders it as an RFC 7807 problem.
+type Error struct {
+	Status int
+	Detail string
+	Fields []FieldError
+	Err    error
+}
+
+// FieldError describes one invalid field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+var (
+	ErrBadRequest         = &Error{Status: http.StatusBadRequest}
+	ErrUnauthorized       = &Error{Status: http.StatusUnauthorized}
+	ErrForbidden          = &Error{Status: http.StatusForbidden}
+	ErrNotFound           = &Error{Status: http.StatusNotFound}
+	ErrConflict           = &Error{Status: http.StatusConflict}
+	ErrValidation         = &Error{Status: http.StatusUnprocessableEntity}
+	ErrPreconditionFailed = &Error{Status: http.StatusPreconditionFailed}
+)
+
+func New(status int, detail string) *Error {
+	return &Error{Status: status, Detail: detail}
+}
+
+func Newf(status int, format string, args ...any) *Error {
+	return New(status, fmt.Sprintf(format, args...))
+}
+
+func BadRequest(detail string) *Error {
+	return New(http.StatusBadRequest, detail)
+}
+
+func Unauthorized(detail string) *Error {
+	return New(http.StatusUnauthorized, detail)
+}
+
+func Forbidden(detail string) *Error {
+	return New(http.StatusForbidden, detail)
+}
+
+func NotFound(detail string) *Error {
+	return New(http.StatusNotFound, detail)
+}
+
+func Conflict(detail string) *Error {
+	return New(http.StatusConflict, detail)
+}
+
+func PreconditionFailed(detail string) *Error {
+	return New(http.StatusPreconditionFailed, detail)
+}
+
+func PreconditionRequired(detail string) *Error {
+	return New(http.StatusPreconditionRequired, detail)
+}
+
+func Validation(fields []FieldError) *Error {
+	return &Error{Status: http.StatusUnprocessableEntity, Detail: "validation failed", Fields: fields}
+}
+
+// Wrap records the underlying cause, which is kept out of responses.
+func (e *Error) Wrap(err error) *Error {
+	clone := *e
+	clone.Err = err
+	return &clone
+}
+
+func (e *Error) Title() string {
+	if e.Status == StatusClientClosedRequest {
+		return "Client Closed Request"
+	}
+	return http.StatusText(e.Status)
+}
+
+func (e *Error) Error() string {
+	msg := e.Detail
+	if msg == "" {
+		msg = e.Title()
+	}
+	if e.Err != nil {
+		return msg + ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches errors of the same status, so errors.Is(err, ErrNotFound) works for any not-found error.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Status == e.Status && t.Detail == ""
+}
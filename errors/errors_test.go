@@ -0,0 +1,63 @@
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIs_MatchesByStatus(t *testing.T) {
+	err := fmt.Errorf("lookup: %w", NotFound("user 7 not found"))
+
+	require.True(t, stderrors.Is(err, ErrNotFound))
+	require.False(t, stderrors.Is(err, ErrConflict))
+	require.False(t, stderrors.Is(err, NotFound("other")))
+}
+
+func TestFrom(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    error
+		status int
+		detail string
+	}{
+		{"typed", Conflict("email taken"), 409, "email taken"},
+		{"wrapped typed", fmt.Errorf("tx: %w", Forbidden("nope")), 403, "nope"},
+		{"deadline", context.DeadlineExceeded, 504, "the request timed out"},
+		{"canceled", context.Canceled, StatusClientClosedRequest, "the request was canceled"},
+		{"unknown", stderrors.New("pq: password authentication failed"), 500, "internal server error"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := From(c.err)
+			require.Equal(t, c.status, e.Status)
+			require.Equal(t, c.detail, e.Detail)
+		})
+	}
+}
+
+func TestToProblem(t *testing.T) {
+	fields := []FieldError{{Field: "name", Rule: "required", Message: "is required"}}
+
+	problem := ToProblem(Validation(fields))
+
+	require.Equal(t, Problem{
+		Type:   "about:blank",
+		Title:  "Unprocessable Entity",
+		Status: 422,
+		Detail: "validation failed",
+		Errors: fields,
+	}, problem)
+}
+
+func TestWrap_KeepsCauseOutOfDetail(t *testing.T) {
+	cause := stderrors.New("duplicate key value violates unique constraint")
+	err := Conflict("entity already exists").Wrap(cause)
+
+	require.ErrorIs(t, err, cause)
+	require.Equal(t, "entity already exists", ToProblem(err).Detail)
+}
@@ -0,0 +1,64 @@
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"log"
+
+	"github.com/Lumicrate/gompose/http"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// StatusClientClosedRequest is the non-standard status used when the client went away.
+const StatusClientClosedRequest = 499
+
+// Problem is an RFC 7807 problem details body.
+type Problem struct {
+	Type   string       `json:"type"`
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Detail string       `json:"detail,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// From converts any error into an *Error. Errors without an HTTP meaning become a 500
+// whose detail does not leak the underlying message.
+func From(err error) *Error {
+	var e *Error
+	if stderrors.As(err, &e) {
+		return e
+	}
+
+	switch {
+	case stderrors.Is(err, context.DeadlineExceeded):
+		return New(504, "the request timed out").Wrap(err)
+	case stderrors.Is(err, context.Canceled):
+		return New(StatusClientClosedRequest, "the request was canceled").Wrap(err)
+	default:
+		return New(500, "internal server error").Wrap(err)
+	}
+}
+
+// ToProblem builds the response body for err.
+func ToProblem(err error) Problem {
+	e := From(err)
+	return Problem{
+		Type:   "about:blank",
+		Title:  e.Title(),
+		Status: e.Status,
+		Detail: e.Detail,
+		Errors: e.Fields,
+	}
+}
+
+// Write renders err as application/problem+json. Server errors are logged with their cause.
+func Write(ctx http.Context, err error) {
+	problem := ToProblem(err)
+	if problem.Status >= 500 {
+		log.Printf("gompose: %d %s: %v", problem.Status, problem.Title, err)
+	}
+
+	ctx.SetHeader("Content-Type", ProblemContentType)
+	ctx.JSON(problem.Status, problem)
+}
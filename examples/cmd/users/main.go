@@ -26,6 +26,7 @@ func main() {
 		AddEntity(User{}, crud.Protect("POST", "PUT", "DELETE")).
 		AddEntity(entities.Rocket{}, crud.Protect("POST", "PUT", "DELETE")).
 		UseDB(dbAdapter).
+		AutoMigrate().
 		UseHTTP(httpEngine).
 		UseAuth(authProvider).
 		UseSwagger()
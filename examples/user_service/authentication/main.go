@@ -51,6 +51,7 @@ func main() {
 	app := core.NewApp().
 		AddEntity(Office{}, crud.Protect("POST", "PUT", "DELETE")).
 		UseDB(dbAdapter).
+		AutoMigrate().
 		UseHTTP(httpEngine).
 		UseAuth(authProvider).
 		UseSwagger()
@@ -47,6 +47,7 @@ func main() {
 	app := core.NewApp().
 		AddEntity(User{}).
 		UseDB(dbAdapter).
+		AutoMigrate().
 		RegisterMiddleware(middlewares.LoggingMiddleware()).
 		RegisterMiddleware(middlewares.RateLimitMiddleware(1 * time.Second)).
 		UseHTTP(httpEngine)
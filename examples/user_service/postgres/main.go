@@ -68,6 +68,7 @@ func main() {
 		AddEntity(User{}).
 		AddEntity(Office{}).
 		UseDB(dbAdapter).
+		AutoMigrate().
 		UseHTTP(httpEngine).
 		UseSwagger()
 
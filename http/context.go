@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"net/http"
 )
 
@@ -27,4 +28,5 @@ type Context interface {
 	Get(key string) any
 
 	Request() *http.Request
+	Context() context.Context
 }
@@ -1,6 +1,7 @@
 package ginadapter
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -84,3 +85,7 @@ func (g *GinContext) Body(content string) {
 func (g *GinContext) Request() *http.Request {
 	return g.ctx.Request
 }
+
+func (g *GinContext) Context() context.Context {
+	return g.ctx.Request.Context()
+}
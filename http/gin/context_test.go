@@ -105,3 +105,9 @@ func TestGinContext_RequestReturnsSame(t *testing.T) {
 	g := &GinContext{ctx: c}
 	require.Equal(t, c.Request, g.Request())
 }
+
+func TestGinContext_ContextIsRequestContext(t *testing.T) {
+	c, _ := setupGinContext("GET", "/", nil)
+	g := &GinContext{ctx: c}
+	require.Equal(t, c.Request.Context(), g.Context())
+}
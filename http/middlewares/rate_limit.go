@@ -1,6 +1,7 @@
 package middlewares
 
 import (
+	gerrors "github.com/Lumicrate/gompose/errors"
 	"github.com/Lumicrate/gompose/http"
 	"sync"
 	"time"
@@ -17,7 +18,7 @@ func RateLimitMiddleware(limit time.Duration) http.MiddlewareFunc {
 			lastRequest, exists := visitors[ip]
 			if exists && time.Since(lastRequest) < limit {
 				mu.Unlock()
-				ctx.JSON(429, map[string]string{"error": "rate limit exceeded"})
+				gerrors.Write(ctx, gerrors.New(429, "rate limit exceeded"))
 				ctx.Abort()
 				return
 			}
@@ -1,6 +1,7 @@
 package i18n_test
 
 import (
+	"context"
 	"github.com/Lumicrate/gompose/i18n"
 	Net "net/http"
 	"net/http/httptest"
@@ -47,6 +48,7 @@ func (m *mockContext) Next()                            {}
 func (m *mockContext) Set(k string, v any)              { m.store[k] = v }
 func (m *mockContext) Get(k string) any                 { return m.store[k] }
 func (m *mockContext) Request() *Net.Request            { return m.req }
+func (m *mockContext) Context() context.Context         { return m.req.Context() }
 
 // Tests
 
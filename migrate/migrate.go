@@ -0,0 +1,195 @@
+// Package migrate applies ordered, versioned schema migrations and records them in a
+// schema_migrations table (or collection) through the application's db.DBAdapter.
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Lumicrate/gompose/db"
+)
+
+// Func changes the schema. It receives the adapter bound to the migration's transaction;
+// adapters expose their driver handle for anything the DBAdapter interface does not cover,
+// e.g. adapter.(*gormadapter.Adapter).DB() or adapter.(*mongodb.MongoAdapter).Database().
+type Func func(ctx context.Context, adapter db.DBAdapter) error
+
+// Migration is one versioned schema change. Versions are usually timestamps such as
+// 20261018120000 and are applied in ascending order. Down may be nil for irreversible changes.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      Func
+	Down    Func
+}
+
+// SchemaMigration records an applied migration.
+type SchemaMigration struct {
+	ID        int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// TableName names the table, and the MongoDB collection, of the records.
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status describes a known or applied migration.
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+var registry []Migration
+
+// Register adds a Go migration to the ones returned by Registered. It is meant to be
+// called from init functions of a migrations package.
+func Register(version int64, name string, up, down Func) {
+	registry = append(registry, Migration{Version: version, Name: name, Up: up, Down: down})
+}
+
+// Registered returns the migrations added with Register.
+func Registered() []Migration {
+	return append([]Migration{}, registry...)
+}
+
+type Migrator struct {
+	adapter    db.DBAdapter
+	migrations []Migration
+}
+
+func New(adapter db.DBAdapter, migrations ...Migration) *Migrator {
+	sorted := append([]Migration{}, migrations...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Migrator{adapter: adapter, migrations: sorted}
+}
+
+// Up applies every migration that has not been applied yet, in version order, each in its
+// own transaction. It returns the migrations it applied, also when a later one fails.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if migration.Up == nil {
+			return done, fmt.Errorf("migration %d has no up step", migration.Version)
+		}
+
+		err := m.adapter.WithContext(ctx).WithTransaction(func(tx db.DBAdapter) error {
+			if err := migration.Up(ctx, tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{ID: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()})
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down reverts the last steps applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+	known := make(map[int64]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	var done []Migration
+	for _, version := range versions[:min(steps, len(versions))] {
+		migration, ok := known[version]
+		if !ok {
+			return done, fmt.Errorf("migration %d is applied but unknown", version)
+		}
+		if migration.Down == nil {
+			return done, fmt.Errorf("migration %d_%s cannot be reverted", version, migration.Name)
+		}
+
+		err := m.adapter.WithContext(ctx).WithTransaction(func(tx db.DBAdapter) error {
+			if err := migration.Down(ctx, tx); err != nil {
+				return err
+			}
+			return tx.Delete(strconv.FormatInt(version, 10), &SchemaMigration{})
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s: %w", version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Status lists the known migrations and the applied ones that are no longer known, by version.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied, status.AppliedAt = true, record.AppliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, record := range applied {
+		statuses = append(statuses, Status{Version: record.ID, Name: record.Name, Applied: true, AppliedAt: record.AppliedAt})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// applied creates the schema_migrations table if needed and returns its records by version,
+// read from the primary.
+func (m *Migrator) applied(ctx context.Context) (map[int64]SchemaMigration, error) {
+	for i := 1; i < len(m.migrations); i++ {
+		if m.migrations[i].Version == m.migrations[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", m.migrations[i].Version)
+		}
+	}
+
+	// A lagging read replica would report applied migrations as pending.
+	adapter := m.adapter.WithContext(db.WithPrimary(ctx))
+	if err := adapter.Migrate([]any{&SchemaMigration{}}); err != nil {
+		return nil, err
+	}
+
+	result, err := adapter.FindAll(&SchemaMigration{}, nil, db.Pagination{}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[int64]SchemaMigration{}
+	for _, record := range result.([]SchemaMigration) {
+		applied[record.ID] = record
+	}
+	return applied, nil
+}
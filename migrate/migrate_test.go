@@ -0,0 +1,161 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Lumicrate/gompose/db"
+	"github.com/Lumicrate/gompose/db/gormadapter"
+	"github.com/Lumicrate/gompose/db/memory"
+	"github.com/Lumicrate/gompose/db/sqlite"
+	glebarez "github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestMigrator_UpDownStatus(t *testing.T) {
+	adapter := memory.New()
+	var log []string
+	step := func(name string) Func {
+		return func(ctx context.Context, adapter db.DBAdapter) error {
+			log = append(log, name)
+			return nil
+		}
+	}
+
+	migrator := New(adapter,
+		Migration{Version: 2, Name: "second", Up: step("up 2"), Down: step("down 2")},
+		Migration{Version: 1, Name: "first", Up: step("up 1"), Down: step("down 1")},
+	)
+
+	applied, err := migrator.Up(context.Background())
+	require.NoError(t, err)
+	require.Len(t, applied, 2)
+	require.Equal(t, []string{"up 1", "up 2"}, log)
+
+	// Nothing is pending anymore.
+	applied, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, applied)
+
+	reverted, err := migrator.Down(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), reverted[0].Version)
+	require.Equal(t, []string{"up 1", "up 2", "down 2"}, log)
+
+	statuses, err := migrator.Status(context.Background())
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	require.True(t, statuses[0].Applied)
+	require.False(t, statuses[1].Applied)
+}
+
+func TestMigrator_FailedMigrationIsNotRecorded(t *testing.T) {
+	adapter := memory.New()
+	migrator := New(adapter, Migration{Version: 1, Name: "broken", Up: func(ctx context.Context, tx db.DBAdapter) error {
+		return errors.New("boom")
+	}})
+
+	_, err := migrator.Up(context.Background())
+	require.ErrorContains(t, err, "boom")
+
+	statuses, err := migrator.Status(context.Background())
+	require.NoError(t, err)
+	require.False(t, statuses[0].Applied)
+}
+
+func TestMigrator_DuplicateVersion(t *testing.T) {
+	noop := func(ctx context.Context, adapter db.DBAdapter) error { return nil }
+	migrator := New(memory.New(), Migration{Version: 1, Up: noop}, Migration{Version: 1, Up: noop})
+
+	_, err := migrator.Up(context.Background())
+	require.EqualError(t, err, "duplicate migration version 1")
+}
+
+func TestMigrator_DownWithoutDownStep(t *testing.T) {
+	noop := func(ctx context.Context, adapter db.DBAdapter) error { return nil }
+	migrator := New(memory.New(), Migration{Version: 1, Name: "irreversible", Up: noop})
+
+	_, err := migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	_, err = migrator.Down(context.Background(), 1)
+	require.EqualError(t, err, "migration 1_irreversible cannot be reverted")
+}
+
+func TestLoadSQL(t *testing.T) {
+	fsys := fstest.MapFS{
+		"20260101000000_create_books.up.sql":   {Data: []byte("CREATE TABLE books (id INTEGER PRIMARY KEY, title TEXT);")},
+		"20260101000000_create_books.down.sql": {Data: []byte("DROP TABLE books;")},
+		"20260102000000_add_author.up.sql":     {Data: []byte("ALTER TABLE books ADD COLUMN author TEXT;")},
+		"README.md":                            {Data: []byte("ignored")},
+	}
+
+	migrations, err := LoadSQL(fsys)
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	require.Equal(t, "create_books", migrations[0].Name)
+	require.NotNil(t, migrations[0].Down)
+	require.Nil(t, migrations[1].Down)
+
+	adapter := sqlite.New(":memory:")
+	require.NoError(t, adapter.Init())
+
+	migrator := New(adapter, migrations...)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+	require.True(t, adapter.DB().Migrator().HasColumn("books", "author"))
+
+	_, err = migrator.Down(context.Background(), 1)
+	require.EqualError(t, err, "migration 20260102000000_add_author cannot be reverted")
+}
+
+func TestLoadSQL_DownWithoutUp(t *testing.T) {
+	_, err := LoadSQL(fstest.MapFS{"1_orphan.down.sql": {Data: []byte("SELECT 1;")}})
+	require.EqualError(t, err, "migration 1 has a down file but no up file")
+}
+
+func TestSQLMigration_RequiresSQLAdapter(t *testing.T) {
+	migrations, err := LoadSQL(fstest.MapFS{"1_init.up.sql": {Data: []byte("CREATE TABLE t (id INT);")}})
+	require.NoError(t, err)
+
+	_, err = New(memory.New(), migrations...).Up(context.Background())
+	require.ErrorContains(t, err, "cannot run SQL migrations")
+}
+
+func TestMigrator_ReadsAppliedFromPrimary(t *testing.T) {
+	dir := t.TempDir()
+	// The replica lags behind: it has no schema_migrations table at all.
+	replica := gormadapter.New(glebarez.Open(filepath.Join(dir, "replica.db")), nil)
+	require.NoError(t, replica.Init())
+	require.NoError(t, replica.Close())
+
+	adapter := gormadapter.New(glebarez.Open(filepath.Join(dir, "primary.db")), nil).
+		SetReplicas(gormadapter.Replicas{Dialectors: []gorm.Dialector{glebarez.Open(filepath.Join(dir, "replica.db"))}})
+	require.NoError(t, adapter.Init())
+	t.Cleanup(func() { adapter.Close() })
+
+	runs := 0
+	migrator := New(adapter, Migration{Version: 1, Name: "once", Up: func(context.Context, db.DBAdapter) error {
+		runs++
+		return nil
+	}})
+
+	_, err := migrator.Up(context.Background())
+	require.NoError(t, err)
+	_, err = adapter.FindAll(&SchemaMigration{}, nil, db.Pagination{}, nil)
+	require.Error(t, err, "plain reads go to the replica")
+
+	done, err := migrator.Up(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, done)
+	require.Equal(t, 1, runs)
+
+	statuses, err := migrator.Status(context.Background())
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	require.True(t, statuses[0].Applied)
+}
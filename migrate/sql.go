@@ -0,0 +1,85 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Lumicrate/gompose/db"
+)
+
+// SQLExecer is implemented by adapters that can run raw SQL, which SQL-file migrations need.
+type SQLExecer interface {
+	ExecSQL(ctx context.Context, query string) error
+}
+
+var sqlFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// LoadSQL reads migrations from the files at the root of fsys named
+// <version>_<name>.up.sql and <version>_<name>.down.sql. The down file is optional.
+// Every file is run as a single Exec, so drivers that need an option for multiple
+// statements per call (such as MySQL's multiStatements=true) must have it enabled.
+func LoadSQL(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	var order []int64
+	for _, entry := range entries {
+		match := sqlFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid version: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+			order = append(order, version)
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("%s: version %d is already used by %q", entry.Name(), version, migration.Name)
+		}
+
+		if match[3] == "up" {
+			migration.Up = execSQL(string(content))
+		} else {
+			migration.Down = execSQL(string(content))
+		}
+	}
+
+	migrations := make([]Migration, 0, len(order))
+	for _, version := range order {
+		if byVersion[version].Up == nil {
+			return nil, fmt.Errorf("migration %d has a down file but no up file", version)
+		}
+		migrations = append(migrations, *byVersion[version])
+	}
+	return migrations, nil
+}
+
+func execSQL(query string) Func {
+	return func(ctx context.Context, adapter db.DBAdapter) error {
+		if strings.TrimSpace(query) == "" {
+			return nil
+		}
+		execer, ok := adapter.(SQLExecer)
+		if !ok {
+			return fmt.Errorf("%T cannot run SQL migrations", adapter)
+		}
+		return execer.ExecSQL(ctx, query)
+	}
+}